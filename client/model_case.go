@@ -0,0 +1,40 @@
+/*
+ * OFAC API
+ *
+ * OFAC (Office of Foreign Assets Control) API is designed to facilitate the enforcement of US government economic sanctions programs required by federal law. This project implements a modern REST HTTP API for companies and organizations to obey federal law and use OFAC data in their applications.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+import (
+	"time"
+)
+
+// Sanctions list record which matched a subject when it was screened
+type Case struct {
+	CaseID    string `json:"caseID,omitempty"`
+	SubjectID string `json:"subjectID,omitempty"`
+	// Sanctions list of the matched record
+	List string `json:"list,omitempty"`
+	// ID of the matched record. Denied persons and BIS entities are identified by the entityID of their search results.
+	EntityID string `json:"entityID,omitempty"`
+	// Name of the matched record
+	Name string `json:"name,omitempty"`
+	// Match percentage of the subject's name and the record
+	Match float32 `json:"match,omitempty"`
+	// Review status of a case
+	Status string `json:"status,omitempty"`
+	// User the case is assigned to
+	Assignee string `json:"assignee,omitempty"`
+	// Analyst's decision on whether the subject is the matched record
+	Disposition string `json:"disposition,omitempty"`
+	// Comments on the case, oldest first. Only returned for a single case.
+	Comments  []CaseComment `json:"comments,omitempty"`
+	CreatedAt time.Time     `json:"createdAt,omitempty"`
+	UpdatedAt time.Time     `json:"updatedAt,omitempty"`
+	// When the case was cleared
+	ClosedAt time.Time `json:"closedAt,omitempty"`
+}
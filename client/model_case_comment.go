@@ -0,0 +1,22 @@
+/*
+ * OFAC API
+ *
+ * OFAC (Office of Foreign Assets Control) API is designed to facilitate the enforcement of US government economic sanctions programs required by federal law. This project implements a modern REST HTTP API for companies and organizations to obey federal law and use OFAC data in their applications.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+import (
+	"time"
+)
+
+// Note left on a case. Comments without a userID were left when the case's record changed.
+type CaseComment struct {
+	CommentID string    `json:"commentID,omitempty"`
+	UserID    string    `json:"userID,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+}
@@ -0,0 +1,22 @@
+/*
+ * OFAC API
+ *
+ * OFAC (Office of Foreign Assets Control) API is designed to facilitate the enforcement of US government economic sanctions programs required by federal law. This project implements a modern REST HTTP API for companies and organizations to obey federal law and use OFAC data in their applications.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+// One of our own customer records to screen
+type CreateSubject struct {
+	// Our own identifier of the record
+	SubjectID string `json:"subjectID"`
+	// Name screened against the sanctions lists
+	Name string `json:"name"`
+	// Date of birth (YYYY-MM-DD). It's informational for analysts reviewing cases, only the name is screened.
+	Dob string `json:"dob,omitempty"`
+	// Informational for analysts reviewing cases, only the name is screened.
+	Addresses []SubjectAddress `json:"addresses,omitempty"`
+}
@@ -19,4 +19,8 @@ type Download struct {
 	AltNames  int32     `json:"altNames,omitempty"`
 	Addresses int32     `json:"addresses,omitempty"`
 	Timestamp time.Time `json:"timestamp,omitempty"`
+	// refreshed when the lists were parsed again, unchanged when no list had changed since the previous download, or rejected when the lists failed the refresh guardrails and the previous data was kept
+	Status string `json:"status,omitempty"`
+	// Why a rejected download failed the refresh guardrails
+	Reasons []string `json:"reasons,omitempty"`
 }
@@ -11,6 +11,8 @@ package openapi
 
 // BIS Denied Persons List item
 type Dpl struct {
+	// Identifies the Denied Person, who has no ID on the list, by a hash of their name, address and effective date
+	EntityID string `json:"entityID,omitempty"`
 	// Name of the Denied Person
 	Name string `json:"name,omitempty"`
 	// Denied Person's street address
@@ -11,6 +11,8 @@ package openapi
 
 // Entity List (EL) - Bureau of Industry and Security
 type El struct {
+	// Identifies the entity, which has no ID on the list, by a hash of its name, addresses and start date
+	EntityID string `json:"entityID,omitempty"`
 	// The name of the entity
 	Name string `json:"name,omitempty"`
 	// Addresses associated with the entity
@@ -22,4 +22,12 @@ type OfacCustomerStatus struct {
 	// Manually applied status for OFAC Customer
 	Status    string    `json:"status,omitempty"`
 	CreatedAt time.Time `json:"createdAt,omitempty"`
+	// ID of the status override, used to approve it
+	OverrideID string `json:"overrideID,omitempty"`
+	// Approval state of the override. Exceptions are pending until approved by another user.
+	State string `json:"state,omitempty"`
+	// User ID who approved the override
+	ApprovedBy string `json:"approvedBy,omitempty"`
+	// When the override is reverted
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
 }
@@ -17,4 +17,6 @@ type Search struct {
 	DeniedPersons     []Dpl     `json:"deniedPersons,omitempty"`
 	SectoralSanctions []Ssi     `json:"sectoralSanctions,omitempty"`
 	BisEntities       []El      `json:"bisEntities,omitempty"`
+	// Whitelist entries which removed records from the results
+	Whitelisted []WhitelistEntry `json:"whitelisted,omitempty"`
 }
@@ -0,0 +1,37 @@
+/*
+ * OFAC API
+ *
+ * OFAC (Office of Foreign Assets Control) API is designed to facilitate the enforcement of US government economic sanctions programs required by federal law. This project implements a modern REST HTTP API for companies and organizations to obey federal law and use OFAC data in their applications.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+import (
+	"time"
+)
+
+// Change of a company or customer status. Each change is hash chained to the change before it.
+type StatusChange struct {
+	// User ID provided when updating status
+	UserID string `json:"userID,omitempty"`
+	// Optional note from updating status
+	Note string `json:"note,omitempty"`
+	// Manually applied status
+	Status    string    `json:"status,omitempty"`
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	// Hash of the previous change, empty for the first change
+	PrevHash string `json:"prevHash,omitempty"`
+	// SHA-256 (hex encoded) of this change and prevHash. Empty for changes made before statuses were hash chained.
+	Hash string `json:"hash,omitempty"`
+	// ID of the status override, used to approve it
+	OverrideID string `json:"overrideID,omitempty"`
+	// Approval state of the override. Exceptions are pending until approved by another user.
+	State string `json:"state,omitempty"`
+	// User ID who approved the override
+	ApprovedBy string `json:"approvedBy,omitempty"`
+	// When the override is reverted
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+}
@@ -0,0 +1,17 @@
+/*
+ * OFAC API
+ *
+ * OFAC (Office of Foreign Assets Control) API is designed to facilitate the enforcement of US government economic sanctions programs required by federal law. This project implements a modern REST HTTP API for companies and organizations to obey federal law and use OFAC data in their applications.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+// Every status change of a company or customer, oldest first
+type StatusHistory struct {
+	Changes []StatusChange `json:"changes,omitempty"`
+	// True when every change's hash matches the change and links to the change before it
+	Verified bool `json:"verified,omitempty"`
+}
@@ -0,0 +1,30 @@
+/*
+ * OFAC API
+ *
+ * OFAC (Office of Foreign Assets Control) API is designed to facilitate the enforcement of US government economic sanctions programs required by federal law. This project implements a modern REST HTTP API for companies and organizations to obey federal law and use OFAC data in their applications.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+import (
+	"time"
+)
+
+// One of our own customer records along with every case opened from screening it
+type Subject struct {
+	// Our own identifier of the record
+	SubjectID string `json:"subjectID,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Date of birth (YYYY-MM-DD). It's informational for analysts reviewing cases, only the name is screened.
+	Dob string `json:"dob,omitempty"`
+	// Informational for analysts reviewing cases, only the name is screened.
+	Addresses []SubjectAddress `json:"addresses,omitempty"`
+	CreatedAt time.Time        `json:"createdAt,omitempty"`
+	UpdatedAt time.Time        `json:"updatedAt,omitempty"`
+	// When the subject was last screened, which happens when it's saved and after every data refresh
+	ScreenedAt time.Time `json:"screenedAt,omitempty"`
+	Cases      []Case    `json:"cases,omitempty"`
+}
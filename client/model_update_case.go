@@ -0,0 +1,20 @@
+/*
+ * OFAC API
+ *
+ * OFAC (Office of Foreign Assets Control) API is designed to facilitate the enforcement of US government economic sanctions programs required by federal law. This project implements a modern REST HTTP API for companies and organizations to obey federal law and use OFAC data in their applications.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+// Request body to update a case, fields which are left out are unchanged
+type UpdateCase struct {
+	// Review status of a case
+	Status string `json:"status,omitempty"`
+	// User the case is assigned to
+	Assignee string `json:"assignee,omitempty"`
+	// Analyst's decision on whether the subject is the matched record
+	Disposition string `json:"disposition,omitempty"`
+}
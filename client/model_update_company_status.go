@@ -9,10 +9,16 @@
 
 package openapi
 
+import (
+	"time"
+)
+
 // Request body to update a company status.
 type UpdateCompanyStatus struct {
 	// manual override of company/SDN sanction status
 	Status string `json:"status"`
 	// Free form notes about manually changing the Company status
 	Notes string `json:"notes,omitempty"`
+	// When the override is reverted. Exceptions always expire, by default after 30 days.
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
 }
@@ -9,10 +9,16 @@
 
 package openapi
 
+import (
+	"time"
+)
+
 // Request body to update a customers status.
 type UpdateCustomerStatus struct {
 	// manual override of customer/SDN sanction status
 	Status string `json:"status"`
 	// Free form notes about manually changing the Customer status
 	Notes string `json:"notes,omitempty"`
+	// When the override is reverted. Exceptions always expire, by default after 30 days.
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
 }
@@ -0,0 +1,26 @@
+/*
+ * OFAC API
+ *
+ * OFAC (Office of Foreign Assets Control) API is designed to facilitate the enforcement of US government economic sanctions programs required by federal law. This project implements a modern REST HTTP API for companies and organizations to obey federal law and use OFAC data in their applications.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+// Changes to a watch, fields which are left out are unchanged
+type UpdateWatch struct {
+	// HTTPS url for webhook on search match
+	Webhook string `json:"webhook,omitempty"`
+	// Private token supplied by clients to be used for authenticating webhooks.
+	AuthToken string `json:"authToken,omitempty"`
+	// Secret (at least 16 characters) used to sign webhook deliveries.
+	SigningSecret string `json:"signingSecret,omitempty"`
+	// How notifications are sent.
+	Notifier string `json:"notifier,omitempty"`
+	// Name watches only. Minimum match percentage (between 0 and 1) a record must reach to be sent.
+	MinMatch float32 `json:"minMatch,omitempty"`
+	// Name watches only. Sanctions lists to search, all lists are searched if empty.
+	Lists []string `json:"lists,omitempty"`
+}
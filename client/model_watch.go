@@ -13,4 +13,6 @@ package openapi
 type Watch struct {
 	// Object representing a customer or company watch
 	WatchId string `json:"watchId,omitempty"`
+	// Secret used to sign each webhook delivery with HMAC-SHA256. Store this to verify the X-Signature header.
+	SigningSecret string `json:"signingSecret,omitempty"`
 }
@@ -0,0 +1,23 @@
+/*
+ * OFAC API
+ *
+ * OFAC (Office of Foreign Assets Control) API is designed to facilitate the enforcement of US government economic sanctions programs required by federal law. This project implements a modern REST HTTP API for companies and organizations to obey federal law and use OFAC data in their applications.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+import (
+	"time"
+)
+
+// Attempt at calling a watch's webhook
+type WatchDelivery struct {
+	AttemptedAt time.Time `json:"attemptedAt,omitempty"`
+	// HTTP status code returned by the webhook, 0 if no response was received
+	Status int32 `json:"status,omitempty"`
+	// True if the webhook responded with a 2xx status
+	Delivered bool `json:"delivered,omitempty"`
+}
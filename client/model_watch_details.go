@@ -0,0 +1,34 @@
+/*
+ * OFAC API
+ *
+ * OFAC (Office of Foreign Assets Control) API is designed to facilitate the enforcement of US government economic sanctions programs required by federal law. This project implements a modern REST HTTP API for companies and organizations to obey federal law and use OFAC data in their applications.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+import (
+	"time"
+)
+
+// Company or customer watch. The authToken and signingSecret are never returned.
+type WatchDetails struct {
+	WatchID string `json:"watchID,omitempty"`
+	Type    string `json:"type,omitempty"`
+	// Company being watched, only set on company watches
+	CompanyID string `json:"companyID,omitempty"`
+	// Customer being watched, only set on customer watches
+	CustomerID string `json:"customerID,omitempty"`
+	// Name being watched, only set on name watches
+	Name    string `json:"name,omitempty"`
+	Webhook string `json:"webhook,omitempty"`
+	// Notifier picked by the watch, the server's default notifier is used if empty
+	Notifier string `json:"notifier,omitempty"`
+	// Name watches only. Minimum match percentage a record must reach to be sent.
+	MinMatch float32 `json:"minMatch,omitempty"`
+	// Name watches only. Sanctions lists searched, all lists are searched if empty.
+	Lists     []string  `json:"lists,omitempty"`
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+}
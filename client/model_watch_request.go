@@ -12,7 +12,15 @@ package openapi
 // Webhook or other means of notification on search criteria. OFAC will make a POST request with a body of the customer or company (SDN, AltNames, and Address).
 type WatchRequest struct {
 	// Private token supplied by clients to be used for authenticating webhooks.
-	AuthToken string `json:"authToken"`
+	AuthToken string `json:"authToken,omitempty"`
 	// HTTPS url for webhook on search match
-	Webhook string `json:"webhook"`
+	Webhook string `json:"webhook,omitempty"`
+	// Secret (at least 16 characters) used to sign webhook deliveries. A random secret is generated if empty.
+	SigningSecret string `json:"signingSecret,omitempty"`
+	// How notifications are sent. The server's default notifier (webhook unless WATCH_NOTIFIER is set) is used if empty. webhook and authToken are only required for webhook notifications.
+	Notifier string `json:"notifier,omitempty"`
+	// Name watches only. Minimum match percentage (between 0 and 1) a record must reach to be sent. Defaults to 0.90
+	MinMatch float32 `json:"minMatch,omitempty"`
+	// Name watches only. Sanctions lists to search, all lists are searched if empty.
+	Lists []string `json:"lists,omitempty"`
 }
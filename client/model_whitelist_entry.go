@@ -0,0 +1,33 @@
+/*
+ * OFAC API
+ *
+ * OFAC (Office of Foreign Assets Control) API is designed to facilitate the enforcement of US government economic sanctions programs required by federal law. This project implements a modern REST HTTP API for companies and organizations to obey federal law and use OFAC data in their applications.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+import (
+	"time"
+)
+
+// Record whitelisted as a false positive for a subject or a name. Entries are invalidated when their record changes.
+type WhitelistEntry struct {
+	WhitelistID string `json:"whitelistID,omitempty"`
+	// Subject the record is whitelisted for
+	SubjectID string `json:"subjectID,omitempty"`
+	// Normalized name the record is whitelisted for
+	Name string `json:"name,omitempty"`
+	// Sanctions list of the record
+	List string `json:"list,omitempty"`
+	// ID of the record. Denied persons and BIS entities are identified by the entityID of their search results.
+	EntityID string `json:"entityID,omitempty"`
+	// User who whitelisted the record
+	Reviewer  string    `json:"reviewer,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	// When the record changed in a later download
+	InvalidatedAt time.Time `json:"invalidatedAt,omitempty"`
+}
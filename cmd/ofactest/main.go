@@ -69,12 +69,14 @@ func main() {
 
 	log.Printf("[INFO] using %s for address", conf.BasePath)
 
-	// Read OAuth token and set on conf
+	// Read OAuth token (or an API key) and set on conf
 	if v := os.Getenv("OAUTH_TOKEN"); v != "" {
 		conf.AddDefaultHeader("Authorization", fmt.Sprintf("Bearer %s", v))
+	} else if v := os.Getenv("OFAC_API_KEY"); v != "" {
+		conf.AddDefaultHeader("X-Api-Key", v)
 	} else {
 		if local := *flagLocal; !local {
-			log.Fatal("[FAILURE] no OAuth token provided")
+			log.Fatal("[FAILURE] no OAuth token or API key provided")
 		}
 	}
 
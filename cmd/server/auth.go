@@ -0,0 +1,520 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+// role grants access to a group of routes on the business HTTP API
+type role string
+
+const (
+	// roleScreening reads sanctions data, search results, companies, customers, subjects, cases and the
+	// whitelist. Every other role can also screen.
+	roleScreening role = "screening"
+
+	// roleOverrides sets and approves company and customer status overrides, and reviews screening
+	// results: saving subjects, updating and commenting on cases, and editing the whitelist.
+	roleOverrides role = "overrides"
+
+	// roleWatches creates, reads, updates and removes watches
+	roleWatches role = "watches"
+
+	// roleAdmin can call every route
+	roleAdmin role = "admin"
+)
+
+func (r role) validate() error {
+	switch r {
+	case roleScreening, roleOverrides, roleWatches, roleAdmin:
+		return nil
+	}
+	return fmt.Errorf("unknown role %q, expected one of: %s, %s, %s, %s", r, roleScreening, roleOverrides, roleWatches, roleAdmin)
+}
+
+// routeRoles are the roles needed for routes which don't only read data. Any other GET route needs
+// roleScreening and any other route needs roleAdmin.
+var routeRoles = map[string]role{
+	"PUT /companies/{companyID}":                               roleOverrides,
+	"POST /companies/{companyID}/status/{overrideID}/approve":  roleOverrides,
+	"PUT /customers/{customerID}":                              roleOverrides,
+	"POST /customers/{customerID}/status/{overrideID}/approve": roleOverrides,
+	"POST /subjects":                                 roleOverrides,
+	"PUT /cases/{caseID}":                            roleOverrides,
+	"POST /cases/{caseID}/comments":                  roleOverrides,
+	"POST /whitelist":                                roleOverrides,
+	"DELETE /whitelist/{whitelistID}":                roleOverrides,
+	"POST /companies/{companyID}/watch":              roleWatches,
+	"DELETE /companies/{companyID}/watch/{watchID}":  roleWatches,
+	"POST /companies/watch":                          roleWatches,
+	"DELETE /companies/watch/{watchID}":              roleWatches,
+	"POST /customers/{customerID}/watch":             roleWatches,
+	"DELETE /customers/{customerID}/watch/{watchID}": roleWatches,
+	"POST /customers/watch":                          roleWatches,
+	"DELETE /customers/watch/{watchID}":              roleWatches,
+	"GET /watches":                                   roleWatches,
+	"GET /watches/{watchID}":                         roleWatches,
+	"PATCH /watches/{watchID}":                       roleWatches,
+	"GET /watches/{watchID}/deliveries":              roleWatches,
+}
+
+// requiredRole returns the role needed to call the route matching r
+func requiredRole(r *http.Request) role {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			if role, exists := routeRoles[r.Method+" "+tpl]; exists {
+				return role
+			}
+		}
+	}
+	if r.Method == "GET" {
+		return roleScreening
+	}
+	return roleAdmin
+}
+
+// authIdentity is who made a request and the roles they were granted. Files of API keys and mTLS
+// client identities hold a JSON array of them.
+type authIdentity struct {
+	// Key is the API key sent in the X-Api-Key header
+	Key string `json:"key,omitempty"`
+
+	// CommonName is the subject common name of a verified TLS client certificate
+	CommonName string `json:"commonName,omitempty"`
+
+	UserID string `json:"userID"`
+	Roles  []role `json:"roles"`
+}
+
+func (id *authIdentity) allowed(required role) bool {
+	for _, r := range id.Roles {
+		if r == required || r == roleAdmin || (required == roleScreening && r.validate() == nil) {
+			return true
+		}
+	}
+	return false
+}
+
+func readAuthIdentities(path string) ([]*authIdentity, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var identities []*authIdentity
+	if err := json.Unmarshal(bs, &identities); err != nil {
+		return nil, fmt.Errorf("problem reading %s: %v", path, err)
+	}
+	for _, id := range identities {
+		for _, r := range id.Roles {
+			if err := r.validate(); err != nil {
+				return nil, fmt.Errorf("%s: %v", path, err)
+			}
+		}
+	}
+	return identities, nil
+}
+
+var (
+	errNoCredentials      = errors.New("no credentials provided")
+	errInvalidCredentials = errors.New("invalid credentials")
+)
+
+// authenticator finds who made a request. It returns a nil identity when the request doesn't carry
+// credentials it reads, and an error when it does but they're invalid.
+type authenticator interface {
+	authenticate(r *http.Request) (*authIdentity, error)
+}
+
+// apiKeyAuthenticator accepts static API keys in the X-Api-Key header
+type apiKeyAuthenticator struct {
+	// keys holds each identity by the SHA-256 hash of its key
+	keys map[string]*authIdentity
+}
+
+func newAPIKeyAuthenticator(identities []*authIdentity) (*apiKeyAuthenticator, error) {
+	a := &apiKeyAuthenticator{keys: make(map[string]*authIdentity)}
+	for _, id := range identities {
+		if id.Key == "" || id.UserID == "" {
+			return nil, errors.New("API keys need a key and userID")
+		}
+		a.keys[hashAPIKey(id.Key)] = id
+	}
+	return a, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (a *apiKeyAuthenticator) authenticate(r *http.Request) (*authIdentity, error) {
+	key := r.Header.Get("X-Api-Key")
+	if key == "" {
+		return nil, nil
+	}
+	if id, exists := a.keys[hashAPIKey(key)]; exists {
+		return id, nil
+	}
+	return nil, errInvalidCredentials
+}
+
+// mtlsAuthenticator accepts verified TLS client certificates whose common name is listed
+type mtlsAuthenticator struct {
+	commonNames map[string]*authIdentity
+}
+
+func newMTLSAuthenticator(identities []*authIdentity) (*mtlsAuthenticator, error) {
+	a := &mtlsAuthenticator{commonNames: make(map[string]*authIdentity)}
+	for _, id := range identities {
+		if id.CommonName == "" {
+			return nil, errors.New("mTLS identities need a commonName")
+		}
+		if id.UserID == "" {
+			id.UserID = id.CommonName
+		}
+		a.commonNames[id.CommonName] = id
+	}
+	return a, nil
+}
+
+func (a *mtlsAuthenticator) authenticate(r *http.Request) (*authIdentity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+	if id, exists := a.commonNames[r.TLS.VerifiedChains[0][0].Subject.CommonName]; exists {
+		return id, nil
+	}
+	return nil, errInvalidCredentials
+}
+
+// jwtAuthenticator accepts HS256 or RS256 signed JWTs as bearer tokens in the Authorization header.
+// The token's subject is the user and its roles are read from rolesClaim.
+type jwtAuthenticator struct {
+	secret []byte                    // HS256 shared secret
+	keys   map[string]*rsa.PublicKey // RS256 keys from a JWKS file, by key ID
+
+	issuer, audience string
+	rolesClaim       string
+
+	now func() time.Time
+}
+
+// jwtLeeway is how far clocks can drift when checking a token's exp and nbf claims
+const jwtLeeway = time.Minute
+
+// readJWKS reads the RSA keys of a JSON Web Key Set file
+func readJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(bs, &jwks); err != nil {
+		return nil, fmt.Errorf("problem reading %s: %v", path, err)
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %q: %v", path, k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %q: %v", path, k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s has no RSA keys", path)
+	}
+	return keys, nil
+}
+
+func (a *jwtAuthenticator) authenticate(r *http.Request) (*authIdentity, error) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, nil
+	}
+	claims, err := a.verify(strings.TrimSpace(strings.TrimPrefix(auth, "Bearer ")))
+	if err != nil {
+		return nil, err
+	}
+	return a.identity(claims)
+}
+
+// verify checks a token's signature and returns its claims
+func (a *jwtAuthenticator) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed JWT")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed JWT signature")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch header.Alg {
+	case "HS256":
+		if len(a.secret) == 0 {
+			return nil, errors.New("HS256 JWTs aren't accepted")
+		}
+		mac := hmac.New(sha256.New, a.secret)
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return nil, errInvalidCredentials
+		}
+	case "RS256":
+		key := a.keys[header.Kid]
+		if key == nil && header.Kid == "" && len(a.keys) == 1 {
+			for _, k := range a.keys {
+				key = k
+			}
+		}
+		if key == nil {
+			return nil, fmt.Errorf("unknown JWT key %q", header.Kid)
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return nil, errInvalidCredentials
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", header.Alg)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	bs, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return errors.New("malformed JWT")
+	}
+	if err := json.Unmarshal(bs, v); err != nil {
+		return errors.New("malformed JWT")
+	}
+	return nil
+}
+
+// identity checks a verified token's claims and returns who it was issued to
+func (a *jwtAuthenticator) identity(claims map[string]interface{}) (*authIdentity, error) {
+	now := time.Now()
+	if a.now != nil {
+		now = a.now()
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(jwtLeeway)) {
+		return nil, errors.New("JWT has expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(jwtLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("JWT isn't valid yet")
+	}
+	if a.issuer != "" && claims["iss"] != a.issuer {
+		return nil, errors.New("JWT has the wrong issuer")
+	}
+	if a.audience != "" && !containsClaim(claims["aud"], a.audience) {
+		return nil, errors.New("JWT has the wrong audience")
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, errors.New("JWT has no subject")
+	}
+
+	id := &authIdentity{UserID: sub}
+	var roles []string
+	switch v := claims[a.rolesClaim].(type) {
+	case string:
+		roles = strings.Fields(v)
+	case []interface{}:
+		for i := range v {
+			if s, ok := v[i].(string); ok {
+				roles = append(roles, s)
+			}
+		}
+	}
+	for i := range roles {
+		// ignore roles meant for other services
+		if r := role(roles[i]); r.validate() == nil {
+			id.Roles = append(id.Roles, r)
+		}
+	}
+	return id, nil
+}
+
+// containsClaim returns true if claim (a string or array of strings) holds value
+func containsClaim(claim interface{}, value string) bool {
+	switch v := claim.(type) {
+	case string:
+		return v == value
+	case []interface{}:
+		for i := range v {
+			if v[i] == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setupAuthenticators reads the API key, JWT and mTLS settings from our environment. Requests to the
+// business HTTP API aren't authenticated when none are set.
+func setupAuthenticators(logger log.Logger) ([]authenticator, error) {
+	var out []authenticator
+
+	if path := os.Getenv("AUTH_API_KEYS_PATH"); path != "" {
+		identities, err := readAuthIdentities(path)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_API_KEYS_PATH: %v", err)
+		}
+		a, err := newAPIKeyAuthenticator(identities)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_API_KEYS_PATH: %v", err)
+		}
+		out = append(out, a)
+		logger.Log("auth", fmt.Sprintf("accepting %d API keys", len(a.keys)))
+	}
+
+	secret, jwksPath := os.Getenv("AUTH_JWT_HS256_SECRET"), os.Getenv("AUTH_JWT_JWKS_PATH")
+	if secret != "" || jwksPath != "" {
+		a := &jwtAuthenticator{
+			issuer:     os.Getenv("AUTH_JWT_ISSUER"),
+			audience:   os.Getenv("AUTH_JWT_AUDIENCE"),
+			rolesClaim: os.Getenv("AUTH_JWT_ROLES_CLAIM"),
+		}
+		if a.rolesClaim == "" {
+			a.rolesClaim = "roles"
+		}
+		if secret != "" {
+			a.secret = []byte(secret)
+		}
+		if jwksPath != "" {
+			keys, err := readJWKS(jwksPath)
+			if err != nil {
+				return nil, fmt.Errorf("AUTH_JWT_JWKS_PATH: %v", err)
+			}
+			a.keys = keys
+		}
+		out = append(out, a)
+		logger.Log("auth", fmt.Sprintf("accepting JWTs (HS256=%v RS256 keys=%d)", secret != "", len(a.keys)))
+	}
+
+	if path := os.Getenv("AUTH_MTLS_IDENTITIES_PATH"); path != "" {
+		identities, err := readAuthIdentities(path)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_MTLS_IDENTITIES_PATH: %v", err)
+		}
+		a, err := newMTLSAuthenticator(identities)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_MTLS_IDENTITIES_PATH: %v", err)
+		}
+		out = append(out, a)
+		logger.Log("auth", fmt.Sprintf("accepting %d mTLS client identities", len(a.commonNames)))
+	}
+
+	if len(out) == 0 {
+		logger.Log("auth", "WARNING: no authentication is configured, every request to the HTTP API is allowed")
+	}
+	return out, nil
+}
+
+// authIdentityKey holds the *authIdentity of an authenticated request in its context
+type authIdentityKey struct{}
+
+// requestIdentity returns who authMiddleware authenticated the request as, or nil when it wasn't
+func requestIdentity(r *http.Request) *authIdentity {
+	id, _ := r.Context().Value(authIdentityKey{}).(*authIdentity)
+	return id
+}
+
+// authMiddleware rejects requests without valid credentials from one of authenticators, or whose
+// identity lacks the role needed for the route. The X-User-Id header of allowed requests is replaced
+// with the authenticated user, so handlers never trust one sent by the caller, and the identity is
+// added to the request's context (see requestIdentity).
+//
+// CORS preflight requests and /ping are always allowed.
+func authMiddleware(logger log.Logger, authenticators []authenticator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "OPTIONS" || r.URL.Path == "/ping" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var id *authIdentity
+			var err error
+			for i := range authenticators {
+				if id, err = authenticators[i].authenticate(r); id != nil || err != nil {
+					break
+				}
+			}
+			if id == nil {
+				if err == nil {
+					err = errNoCredentials
+				}
+				if logger != nil {
+					logger.Log("auth", fmt.Sprintf("%s %s: %v", r.Method, r.URL.Path, err), "requestId", r.Header.Get("X-Request-Id"))
+				}
+				writeProblem(w, http.StatusUnauthorized, err)
+				return
+			}
+			if required := requiredRole(r); !id.allowed(required) {
+				writeProblem(w, http.StatusForbidden, fmt.Errorf("%s role required", required))
+				return
+			}
+
+			r.Header.Set("X-User-Id", id.UserID)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authIdentityKey{}, id)))
+		})
+	}
+}
+
+// writeProblem responds with status and err in the same body as moovhttp.Problem
+func writeProblem(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,274 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// signTestJWT returns a token of claims signed with an HS256 secret ([]byte) or RS256 key (*rsa.PrivateKey)
+func signTestJWT(t *testing.T, key interface{}, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header := map[string]string{"alg": "HS256", "typ": "JWT"}
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		header["alg"], header["kid"] = "RS256", kid
+	}
+	encode := func(v interface{}) string {
+		bs, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(bs)
+	}
+	signed := encode(header) + "." + encode(claims)
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		s, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = s
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func writeTestJWKS(t *testing.T, dir string, kid string, key *rsa.PublicKey) string {
+	t.Helper()
+
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	bs, _ := json.Marshal(jwks)
+	path := filepath.Join(dir, "jwks.json")
+	if err := ioutil.WriteFile(path, bs, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// authRouter returns a router with a read-only, an override and a watch route which echo the X-User-Id header
+func authRouter(authenticators ...authenticator) *mux.Router {
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(r.Header.Get("X-User-Id")))
+	}
+	router := mux.NewRouter()
+	addPingRoute(router)
+	router.Methods("GET").Path("/search").HandlerFunc(echo)
+	router.Methods("PUT").Path("/companies/{companyID}").HandlerFunc(echo)
+	router.Methods("POST").Path("/companies/watch").HandlerFunc(echo)
+	router.Methods("POST").Path("/unlisted").HandlerFunc(echo)
+	router.Use(authMiddleware(nil, authenticators))
+	return router
+}
+
+func serveAuth(router *mux.Router, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuth__apiKeys(t *testing.T) {
+	a, err := newAPIKeyAuthenticator([]*authIdentity{
+		{Key: "screen-key", UserID: "screener", Roles: []role{roleScreening}},
+		{Key: "override-key", UserID: "jane", Roles: []role{roleOverrides}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := authRouter(a)
+
+	if w := serveAuth(router, "GET", "/ping", nil); w.Code != http.StatusOK {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+	if w := serveAuth(router, "GET", "/search", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+	if w := serveAuth(router, "GET", "/search", map[string]string{"X-Api-Key": "wrong"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+
+	// the caller's X-User-Id header is replaced
+	w := serveAuth(router, "GET", "/search", map[string]string{"X-Api-Key": "screen-key", "X-User-Id": "admin"})
+	if w.Code != http.StatusOK || w.Body.String() != "screener" {
+		t.Errorf("bogus response: %d: %s", w.Code, w.Body.String())
+	}
+
+	// screening is read-only
+	if w := serveAuth(router, "PUT", "/companies/foo", map[string]string{"X-Api-Key": "screen-key"}); w.Code != http.StatusForbidden {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+	if w := serveAuth(router, "PUT", "/companies/foo", map[string]string{"X-Api-Key": "override-key"}); w.Code != http.StatusOK || w.Body.String() != "jane" {
+		t.Errorf("bogus response: %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveAuth(router, "GET", "/search", map[string]string{"X-Api-Key": "override-key"}); w.Code != http.StatusOK {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+	if w := serveAuth(router, "POST", "/companies/watch", map[string]string{"X-Api-Key": "override-key"}); w.Code != http.StatusForbidden {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+	if w := serveAuth(router, "POST", "/unlisted", map[string]string{"X-Api-Key": "override-key"}); w.Code != http.StatusForbidden {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+
+	if _, err := newAPIKeyAuthenticator([]*authIdentity{{Key: "key"}}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestAuth__JWT(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ofac-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := readJWKS(writeTestJWKS(t, dir, "key-1", &key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("secret")
+	a := &jwtAuthenticator{secret: secret, keys: keys, issuer: "idp", audience: "ofac", rolesClaim: "roles"}
+	router := authRouter(a)
+
+	claims := func(roles interface{}, exp time.Time) map[string]interface{} {
+		return map[string]interface{}{"sub": "jane", "iss": "idp", "aud": []string{"ofac"}, "exp": exp.Unix(), "roles": roles}
+	}
+	bearer := func(token string) map[string]string {
+		return map[string]string{"Authorization": "Bearer " + token}
+	}
+	later := time.Now().Add(time.Hour)
+
+	// HS256 and RS256 tokens
+	hs := signTestJWT(t, secret, "", claims([]string{"watches", "other-service"}, later))
+	if w := serveAuth(router, "POST", "/companies/watch", bearer(hs)); w.Code != http.StatusOK || w.Body.String() != "jane" {
+		t.Errorf("bogus response: %d: %s", w.Code, w.Body.String())
+	}
+	rs := signTestJWT(t, key, "key-1", claims("screening overrides", later))
+	if w := serveAuth(router, "PUT", "/companies/foo", bearer(rs)); w.Code != http.StatusOK {
+		t.Errorf("bogus response: %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveAuth(router, "POST", "/companies/watch", bearer(rs)); w.Code != http.StatusForbidden {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+
+	// rejected tokens
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	wrongAudience := claims("admin", later)
+	wrongAudience["aud"] = "billing"
+	rejected := map[string]string{
+		"expired":         signTestJWT(t, secret, "", claims("admin", time.Now().Add(-time.Hour))),
+		"wrong secret":    signTestJWT(t, []byte("other"), "", claims("admin", later)),
+		"wrong key":       signTestJWT(t, other, "key-1", claims("admin", later)),
+		"unknown key":     signTestJWT(t, key, "key-2", claims("admin", later)),
+		"wrong audience":  signTestJWT(t, secret, "", wrongAudience),
+		"malformed":       "abc.def",
+		"unsigned (none)": base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"jane"}`)) + ".",
+	}
+	for name, token := range rejected {
+		if w := serveAuth(router, "GET", "/search", bearer(token)); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: bogus status code: %d", name, w.Code)
+		}
+	}
+
+	// a token without any of our roles can't screen
+	if w := serveAuth(router, "GET", "/search", bearer(signTestJWT(t, secret, "", claims(nil, later)))); w.Code != http.StatusForbidden {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+}
+
+func TestAuth__mTLS(t *testing.T) {
+	a, err := newMTLSAuthenticator([]*authIdentity{{CommonName: "batch.example.com", Roles: []role{roleAdmin}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := authRouter(a)
+
+	serve := func(cn string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/unlisted", nil)
+		req.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}},
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	if w := serve("batch.example.com"); w.Code != http.StatusOK || w.Body.String() != "batch.example.com" {
+		t.Errorf("bogus response: %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve("other.example.com"); w.Code != http.StatusUnauthorized {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+	if w := serveAuth(router, "POST", "/unlisted", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+}
+
+func TestAuth__readAuthIdentities(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ofac-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(body string) string {
+		path := filepath.Join(dir, fmt.Sprintf("identities-%d.json", time.Now().UnixNano()))
+		if err := ioutil.WriteFile(path, []byte(body), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	identities, err := readAuthIdentities(write(`[{"key": "abc", "userID": "ci", "roles": ["screening", "watches"]}]`))
+	if err != nil || len(identities) != 1 || len(identities[0].Roles) != 2 {
+		t.Errorf("identities=%#v err=%v", identities, err)
+	}
+	if _, err := readAuthIdentities(write(`[{"key": "abc", "userID": "ci", "roles": ["root"]}]`)); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := readAuthIdentities(write(`{`)); err == nil {
+		t.Error("expected error")
+	}
+}
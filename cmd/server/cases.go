@@ -0,0 +1,601 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base"
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+var (
+	errNoCaseID    = errors.New("no caseID found")
+	errNoComment   = errors.New("no comment provided")
+	errCaseMissing = errors.New("case not found")
+)
+
+// CaseStatus is where a case is in its review
+type CaseStatus string
+
+const (
+	// CaseOpen cases are waiting to be reviewed
+	CaseOpen CaseStatus = "open"
+	// CaseCleared cases were reviewed and closed
+	CaseCleared CaseStatus = "cleared"
+	// CaseEscalated cases were reviewed and need a second look
+	CaseEscalated CaseStatus = "escalated"
+)
+
+func (s CaseStatus) validate() error {
+	switch s {
+	case CaseOpen, CaseCleared, CaseEscalated:
+		return nil
+	}
+	return fmt.Errorf("unknown case status %q", s)
+}
+
+// CaseDisposition is an analyst's decision on whether a case's subject is the record it matched
+type CaseDisposition string
+
+const (
+	CaseTrueMatch     CaseDisposition = "true_match"
+	CaseFalsePositive CaseDisposition = "false_positive"
+)
+
+func (d CaseDisposition) validate() error {
+	switch d {
+	case "", CaseTrueMatch, CaseFalsePositive:
+		return nil
+	}
+	return fmt.Errorf("unknown case disposition %q", d)
+}
+
+// Case is a record on one of the sanctions lists which matched a Subject when it was screened.
+//
+// List is one of the name watch lists (sdn, alt, dpl, ssi or el) and EntityID identifies the record
+// on that list. Denied persons and BIS entities have no ID, so they're identified by a hash of their
+// name, address and start date (see deniedPersonID and bisEntityID).
+type Case struct {
+	ID        string     `json:"caseID"`
+	SubjectID string     `json:"subjectID"`
+	List      string     `json:"list"`
+	EntityID  string     `json:"entityID"`
+	Name      string     `json:"name"`
+	Match     float64    `json:"match"`
+	Status    CaseStatus `json:"status"`
+
+	Assignee    string          `json:"assignee,omitempty"`
+	Disposition CaseDisposition `json:"disposition,omitempty"`
+	Comments    []*CaseComment  `json:"comments,omitempty"`
+
+	// RecordHash is the hash of the record when it was last screened, a cleared case is
+	// reopened if the record changes in a later download.
+	RecordHash string `json:"-"`
+
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	ClosedAt  *time.Time `json:"closedAt,omitempty"`
+}
+
+func (c *Case) key() string {
+	return c.List + ":" + c.EntityID
+}
+
+// CaseComment is a note left on a case by an analyst. Comments without a UserID were left when
+// the case was reopened.
+type CaseComment struct {
+	ID        string    `json:"commentID"`
+	UserID    string    `json:"userID,omitempty"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// recordHash returns the SHA-256 (hex encoded) of a list record's JSON.
+func recordHash(record interface{}) string {
+	bs, err := json.Marshal(record)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:])
+}
+
+// createCaseIndexes are the indexes of our cases table. A subject has one case per record it matches.
+func createCaseIndexes() []string {
+	return []string{
+		`create unique index idx_cases_hit on cases (subject_id, list, entity_id);`,
+		`create index idx_cases_status on cases (status);`,
+	}
+}
+
+// addCaseWorkflow is the migration which adds assignees, dispositions, comments and record hashes to cases
+func addCaseWorkflow(databaseType string) []string {
+	timestamp := map[string]string{sqliteDatabase: "datetime", postgresDatabase: "timestamptz", mysqlDatabase: "datetime(6)"}[databaseType]
+
+	return []string{
+		`alter table cases add column assignee text;`,
+		`alter table cases add column disposition varchar(32);`,
+		`alter table cases add column record_hash varchar(64);`,
+		fmt.Sprintf(`alter table cases add column closed_at %s;`, timestamp),
+		fmt.Sprintf(`create table if not exists case_comments(comment_id varchar(64) primary key, case_id varchar(64), user_id text, body text, created_at %s);`, timestamp),
+		`create index idx_case_comments_case_id on case_comments (case_id);`,
+		`create index idx_cases_created_at on cases (created_at);`,
+	}
+}
+
+// dropCaseWorkflow reverts addCaseWorkflow
+func dropCaseWorkflow(databaseType string) []string {
+	out := []string{`drop table if exists case_comments;`}
+	switch databaseType {
+	case sqliteDatabase:
+		out = append(out,
+			`create table cases_copy(case_id primary key, subject_id, list, entity_id, name, match_score, status, created_at datetime, updated_at datetime);`,
+			`insert into cases_copy select case_id, subject_id, list, entity_id, name, match_score, status, created_at, updated_at from cases;`,
+			`drop table cases;`,
+			`alter table cases_copy rename to cases;`,
+		)
+		return append(out, createCaseIndexes()...)
+	case mysqlDatabase:
+		out = append(out, `drop index idx_cases_created_at on cases;`)
+	default:
+		out = append(out, `drop index if exists idx_cases_created_at;`)
+	}
+	return append(out, `alter table cases drop column assignee, drop column disposition, drop column record_hash, drop column closed_at;`)
+}
+
+// caseFilter holds the query parameters for listing cases. Ages are measured from when a case was opened.
+type caseFilter struct {
+	statuses  []CaseStatus
+	subjectID string
+	assignee  string
+	olderThan time.Duration
+	newerThan time.Duration
+
+	limit, offset int
+}
+
+// where returns the SQL where clause and arguments for the filter.
+func (f caseFilter) where(now time.Time) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	if len(f.statuses) > 0 {
+		clauses = append(clauses, "status in ("+strings.TrimSuffix(strings.Repeat("?, ", len(f.statuses)), ", ")+")")
+		for i := range f.statuses {
+			args = append(args, f.statuses[i])
+		}
+	}
+	if f.subjectID != "" {
+		clauses = append(clauses, "subject_id = ?")
+		args = append(args, f.subjectID)
+	}
+	if f.assignee != "" {
+		clauses = append(clauses, "assignee = ?")
+		args = append(args, f.assignee)
+	}
+	// sqlite compares times as strings, so keep them in one time zone
+	if f.olderThan > 0 {
+		clauses = append(clauses, "created_at <= ?")
+		args = append(args, now.Add(-1*f.olderThan).Local())
+	}
+	if f.newerThan > 0 {
+		clauses = append(clauses, "created_at >= ?")
+		args = append(args, now.Add(-1*f.newerThan).Local())
+	}
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return " where " + strings.Join(clauses, " and "), args
+}
+
+func readCaseFilter(r *http.Request) (caseFilter, error) {
+	q := r.URL.Query()
+	filter := caseFilter{
+		subjectID: q.Get("subjectID"),
+		assignee:  q.Get("assignee"),
+		limit:     extractSearchLimit(r),
+		offset:    extractOffset(r),
+	}
+	if v := q.Get("status"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			status := CaseStatus(strings.TrimSpace(s))
+			if err := status.validate(); err != nil {
+				return filter, err
+			}
+			filter.statuses = append(filter.statuses, status)
+		}
+	}
+	for _, age := range []struct {
+		param string
+		dur   *time.Duration
+	}{{"olderThan", &filter.olderThan}, {"newerThan", &filter.newerThan}} {
+		if v := q.Get(age.param); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return filter, fmt.Errorf("invalid %s %q, expected a duration like 72h", age.param, v)
+			}
+			*age.dur = d
+		}
+	}
+	return filter, nil
+}
+
+// caseUpdate is the body of PUT /cases/{caseID}, only the fields which are set are changed.
+type caseUpdate struct {
+	Status      *CaseStatus      `json:"status"`
+	Assignee    *string          `json:"assignee"`
+	Disposition *CaseDisposition `json:"disposition"`
+}
+
+func (u caseUpdate) validate() error {
+	if u.Status == nil && u.Assignee == nil && u.Disposition == nil {
+		return errors.New("no case fields to update")
+	}
+	if u.Status != nil {
+		if err := u.Status.validate(); err != nil {
+			return err
+		}
+	}
+	if u.Disposition != nil {
+		return u.Disposition.validate()
+	}
+	return nil
+}
+
+const caseColumns = `case_id, subject_id, list, entity_id, name, match_score, status, assignee, disposition, record_hash, created_at, updated_at, closed_at`
+
+func (r *sqliteSubjectRepository) getCase(caseID string) (*Case, error) {
+	cases, err := queryCases(r.db, `select `+caseColumns+` from cases where case_id = ? limit 1;`, caseID)
+	if err != nil || len(cases) == 0 {
+		return nil, err
+	}
+	return cases[0], nil
+}
+
+func (r *sqliteSubjectRepository) getCases(filter caseFilter, now time.Time) ([]*Case, error) {
+	where, args := filter.where(now)
+	args = append(args, filter.limit, filter.offset)
+	return queryCases(r.db, `select `+caseColumns+` from cases`+where+` order by created_at desc, case_id limit ? offset ?;`, args...)
+}
+
+func (r *sqliteSubjectRepository) getSubjectCases(subjectID string) ([]*Case, error) {
+	return queryCases(r.db, `select `+caseColumns+` from cases where subject_id = ? order by created_at, case_id;`, subjectID)
+}
+
+// updateCase applies update to a case, returning nil if the case doesn't exist. Clearing a case closes it.
+func (r *sqliteSubjectRepository) updateCase(caseID string, update caseUpdate, now time.Time) (*Case, error) {
+	sets := []string{"updated_at = ?"}
+	args := []interface{}{now}
+	if update.Status != nil {
+		sets = append(sets, "status = ?", "closed_at = ?")
+		if *update.Status == CaseCleared {
+			args = append(args, *update.Status, now)
+		} else {
+			args = append(args, *update.Status, nil)
+		}
+	}
+	if update.Assignee != nil {
+		sets = append(sets, "assignee = ?")
+		args = append(args, *update.Assignee)
+	}
+	if update.Disposition != nil {
+		sets = append(sets, "disposition = ?")
+		args = append(args, *update.Disposition)
+	}
+	args = append(args, caseID)
+
+	res, err := r.db.Exec(`update cases set `+strings.Join(sets, ", ")+` where case_id = ?;`, args...)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+	return r.getCase(caseID)
+}
+
+func (r *sqliteSubjectRepository) getCaseComments(caseID string) ([]*CaseComment, error) {
+	rows, err := r.db.Query(`select comment_id, user_id, body, created_at from case_comments where case_id = ? order by created_at, comment_id;`, caseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*CaseComment
+	for rows.Next() {
+		var comment CaseComment
+		var userID sql.NullString
+		if err := rows.Scan(&comment.ID, &userID, &comment.Comment, &comment.CreatedAt); err != nil {
+			return nil, err
+		}
+		comment.UserID = userID.String
+		comments = append(comments, &comment)
+	}
+	return comments, rows.Err()
+}
+
+func (r *sqliteSubjectRepository) addCaseComment(caseID string, comment *CaseComment) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	res, err := tx.Exec(`update cases set updated_at = ? where case_id = ?;`, comment.CreatedAt, caseID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return errCaseMissing
+	}
+	if err := insertCaseComment(tx, caseID, comment); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertCaseComment(tx *sql.Tx, caseID string, comment *CaseComment) error {
+	query := `insert into case_comments (comment_id, case_id, user_id, body, created_at) values (?, ?, ?, ?, ?);`
+	_, err := tx.Exec(query, comment.ID, caseID, comment.UserID, comment.Comment, comment.CreatedAt)
+	return err
+}
+
+func (r *sqliteSubjectRepository) openCases(cases []*Case, now time.Time) ([]*Case, []*Case, error) {
+	if len(cases) == 0 {
+		return nil, nil, nil
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	opened, reopened, err := openCases(tx, cases, now)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	return opened, reopened, tx.Commit()
+}
+
+func openCases(tx *sql.Tx, cases []*Case, now time.Time) ([]*Case, []*Case, error) {
+	existing, err := queryCases(tx, `select `+caseColumns+` from cases where subject_id = ?;`, cases[0].SubjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	known := make(map[string]*Case, len(existing))
+	for i := range existing {
+		known[existing[i].key()] = existing[i]
+	}
+
+	var opened, reopened []*Case
+	for _, c := range cases {
+		if c.SubjectID != cases[0].SubjectID {
+			return nil, nil, errors.New("openCases: every case must be for the same subject")
+		}
+		prev := known[c.key()]
+		if prev == nil {
+			query := `insert into cases (` + caseColumns + `) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+			if _, err := tx.Exec(query, c.ID, c.SubjectID, c.List, c.EntityID, c.Name, c.Match, c.Status, c.Assignee, c.Disposition, c.RecordHash, c.CreatedAt, c.UpdatedAt, c.ClosedAt); err != nil {
+				return nil, nil, err
+			}
+			known[c.key()] = c
+			opened = append(opened, c)
+			continue
+		}
+		if prev.RecordHash == c.RecordHash {
+			continue
+		}
+		if prev.RecordHash == "" {
+			// cases opened before record hashes were kept only start tracking changes now
+			if _, err := tx.Exec(`update cases set record_hash = ? where case_id = ?;`, c.RecordHash, prev.ID); err != nil {
+				return nil, nil, err
+			}
+			prev.RecordHash = c.RecordHash
+			continue
+		}
+
+		comment := fmt.Sprintf("%s record %s changed in a later download", c.List, c.EntityID)
+		if prev.Status == CaseCleared {
+			comment = fmt.Sprintf("%s, reopened case which was cleared", comment)
+			if prev.Disposition != "" {
+				comment = fmt.Sprintf("%s as %s", comment, prev.Disposition)
+			}
+			prev.Status, prev.Disposition, prev.ClosedAt = CaseOpen, "", nil
+			reopened = append(reopened, prev)
+		}
+		prev.RecordHash, prev.UpdatedAt = c.RecordHash, now
+
+		query := `update cases set status = ?, disposition = ?, record_hash = ?, updated_at = ?, closed_at = ? where case_id = ?;`
+		if _, err := tx.Exec(query, prev.Status, prev.Disposition, prev.RecordHash, prev.UpdatedAt, prev.ClosedAt, prev.ID); err != nil {
+			return nil, nil, err
+		}
+		if err := insertCaseComment(tx, prev.ID, &CaseComment{ID: base.ID(), Comment: comment, CreatedAt: now}); err != nil {
+			return nil, nil, err
+		}
+	}
+	return opened, reopened, nil
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func queryCases(db queryer, query string, args ...interface{}) ([]*Case, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cases []*Case
+	for rows.Next() {
+		var c Case
+		var assignee, disposition, hash sql.NullString
+		err := rows.Scan(&c.ID, &c.SubjectID, &c.List, &c.EntityID, &c.Name, &c.Match, &c.Status, &assignee, &disposition, &hash, &c.CreatedAt, &c.UpdatedAt, &c.ClosedAt)
+		if err != nil {
+			return nil, err
+		}
+		c.Assignee, c.Disposition, c.RecordHash = assignee.String, CaseDisposition(disposition.String), hash.String
+		cases = append(cases, &c)
+	}
+	return cases, rows.Err()
+}
+
+func addCaseRoutes(logger log.Logger, r *mux.Router, repo subjectRepository) {
+	r.Methods("GET").Path("/cases").HandlerFunc(getCases(logger, repo))
+	r.Methods("GET").Path("/cases/{caseID}").HandlerFunc(getCase(logger, repo))
+	r.Methods("PUT").Path("/cases/{caseID}").HandlerFunc(updateCase(logger, repo))
+	r.Methods("POST").Path("/cases/{caseID}/comments").HandlerFunc(addCaseComment(logger, repo))
+}
+
+func getCaseID(w http.ResponseWriter, r *http.Request) string {
+	v, ok := mux.Vars(r)["caseID"]
+	if !ok || v == "" {
+		moovhttp.Problem(w, errNoCaseID)
+		return ""
+	}
+	return v
+}
+
+func getCases(logger log.Logger, repo subjectRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		filter, err := readCaseFilter(r)
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		cases, err := repo.getCases(filter, time.Now())
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if cases == nil {
+			cases = []*Case{}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(cases)
+	}
+}
+
+func getCase(logger log.Logger, repo subjectRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		caseID := getCaseID(w, r)
+		if caseID == "" {
+			return
+		}
+		c, err := repo.getCase(caseID)
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		writeCase(w, c, repo)
+	}
+}
+
+func updateCase(logger log.Logger, repo subjectRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		caseID := getCaseID(w, r)
+		if caseID == "" {
+			return
+		}
+		var update caseUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if err := update.validate(); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		c, err := repo.updateCase(caseID, update, time.Now())
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		writeCase(w, c, repo)
+	}
+}
+
+type caseCommentRequest struct {
+	Comment string `json:"comment"`
+}
+
+func addCaseComment(logger log.Logger, repo subjectRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		caseID, userID := getCaseID(w, r), moovhttp.GetUserId(r)
+		if caseID == "" {
+			return
+		}
+		if userID == "" {
+			moovhttp.Problem(w, errNoUserID)
+			return
+		}
+		var req caseCommentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if strings.TrimSpace(req.Comment) == "" {
+			moovhttp.Problem(w, errNoComment)
+			return
+		}
+		comment := &CaseComment{
+			ID:        base.ID(),
+			UserID:    userID,
+			Comment:   req.Comment,
+			CreatedAt: time.Now(),
+		}
+		if err := repo.addCaseComment(caseID, comment); err != nil {
+			if err == errCaseMissing {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			moovhttp.Problem(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(comment)
+	}
+}
+
+// writeCase responds with c and its comments, or a 404 if c is nil.
+func writeCase(w http.ResponseWriter, c *Case, repo subjectRepository) {
+	if c == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	comments, err := repo.getCaseComments(c.ID)
+	if err != nil {
+		moovhttp.Problem(w, err)
+		return
+	}
+	c.Comments = comments
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(c)
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cardonator/ofac"
+	"github.com/gorilla/mux"
+)
+
+func TestCases__readCaseFilter(t *testing.T) {
+	req := httptest.NewRequest("GET", "/cases?status=open,escalated&olderThan=24h&assignee=jane&limit=5", nil)
+	filter, err := readCaseFilter(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filter.statuses) != 2 || filter.olderThan != 24*time.Hour || filter.assignee != "jane" || filter.limit != 5 {
+		t.Errorf("unexpected filter: %#v", filter)
+	}
+
+	for _, query := range []string{"status=closed", "olderThan=3", "newerThan=-1h"} {
+		req := httptest.NewRequest("GET", "/cases?"+query, nil)
+		if _, err := readCaseFilter(req); err == nil {
+			t.Errorf("%s: expected error", query)
+		}
+	}
+}
+
+func TestCases__caseUpdate(t *testing.T) {
+	open, bogus := CaseOpen, CaseDisposition("maybe")
+	if err := (caseUpdate{Status: &open}).validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (caseUpdate{}).validate(); err == nil {
+		t.Error("expected error")
+	}
+	if err := (caseUpdate{Disposition: &bogus}).validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestCases__workflow(t *testing.T) {
+	repo := createTestSubjectRepository(t)
+	defer repo.close()
+
+	router := mux.NewRouter()
+	addSubjectRoutes(nil, router, customerSearcher, repo)
+	addCaseRoutes(nil, router, repo)
+
+	w := serveSubjects(router, "POST", "/subjects", `{"subjectID": "cust-1", "name": "Banco Nacional de Cuba"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	var subject subjectResponse
+	if err := json.NewDecoder(w.Body).Decode(&subject); err != nil || len(subject.Cases) == 0 {
+		t.Fatalf("subject=%#v err=%v", subject, err)
+	}
+	caseID := subject.Cases[0].ID
+
+	// assign the case and leave a comment
+	w = serveSubjects(router, "PUT", "/cases/"+caseID, `{"assignee": "jane"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	req := httptest.NewRequest("POST", fmt.Sprintf("/cases/%s/comments", caseID), strings.NewReader(`{"comment": "checking the DOB"}`))
+	req.Header.Set("x-user-id", "jane")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveSubjects(router, "POST", fmt.Sprintf("/cases/%s/comments", caseID), `{"comment": "no user"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+
+	// close it as a false positive
+	w = serveSubjects(router, "PUT", "/cases/"+caseID, `{"status": "cleared", "disposition": "false_positive"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	var c Case
+	if err := json.NewDecoder(w.Body).Decode(&c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Assignee != "jane" || c.Disposition != CaseFalsePositive || c.ClosedAt == nil || len(c.Comments) != 1 || c.Comments[0].UserID != "jane" {
+		t.Errorf("unexpected case: %#v", c)
+	}
+
+	// filter cases
+	cases := listCases(t, router, url.Values{"status": {"cleared"}, "assignee": {"jane"}})
+	if len(cases) != 1 || cases[0].ID != caseID {
+		t.Errorf("unexpected cases: %#v", cases)
+	}
+	if cases := listCases(t, router, url.Values{"status": {"open,escalated"}, "subjectID": {"cust-1"}}); len(cases) != len(subject.Cases)-1 {
+		t.Errorf("unexpected cases: %#v", cases)
+	}
+	if cases := listCases(t, router, url.Values{"olderThan": {"1h"}}); len(cases) != 0 {
+		t.Errorf("unexpected cases: %#v", cases)
+	}
+	if cases := listCases(t, router, url.Values{"newerThan": {"1h"}}); len(cases) != len(subject.Cases) {
+		t.Errorf("unexpected cases: %#v", cases)
+	}
+}
+
+func TestCases__reopen(t *testing.T) {
+	repo := createTestSubjectRepository(t)
+	defer repo.close()
+
+	sdn := func(remarks string) *searcher {
+		return &searcher{
+			SDNs: precomputeSDNs([]*ofac.SDN{{EntityID: "306", SDNName: "BANCO NACIONAL DE CUBA", SDNType: "individual", Remarks: remarks}}),
+		}
+	}
+	subject := &Subject{ID: "cust-1", Name: "Banco Nacional de Cuba"}
+	if err := repo.upsertSubject(subject); err != nil {
+		t.Fatal(err)
+	}
+	opened, _, err := sdn("a.k.a. 'BNC'.").screenSubject(subject, repo, nil, time.Now())
+	if err != nil || len(opened) != 1 {
+		t.Fatalf("opened=%#v err=%v", opened, err)
+	}
+	cleared, fp := CaseCleared, CaseFalsePositive
+	if _, err := repo.updateCase(opened[0].ID, caseUpdate{Status: &cleared, Disposition: &fp}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// an unchanged record keeps the case cleared
+	if screening, err := sdn("a.k.a. 'BNC'.").rescreenSubjects(repo); err != nil || screening.Reopened != 0 {
+		t.Errorf("screening=%#v err=%v", screening, err)
+	}
+
+	// the record changed in a later download
+	if screening, err := sdn("a.k.a. 'BNC'; Linked To: CUBA.").rescreenSubjects(repo); err != nil || screening.Opened != 0 || screening.Reopened != 1 {
+		t.Errorf("screening=%#v err=%v", screening, err)
+	}
+	c, err := repo.getCase(opened[0].ID)
+	if err != nil || c == nil {
+		t.Fatalf("case=%#v err=%v", c, err)
+	}
+	if c.Status != CaseOpen || c.Disposition != "" || c.ClosedAt != nil {
+		t.Errorf("unexpected case: %#v", c)
+	}
+	comments, err := repo.getCaseComments(c.ID)
+	if err != nil || len(comments) != 1 || !strings.Contains(comments[0].Comment, "false_positive") {
+		t.Errorf("comments=%#v err=%v", comments, err)
+	}
+}
+
+func listCases(t *testing.T, router *mux.Router, query url.Values) []*Case {
+	t.Helper()
+
+	w := serveSubjects(router, "GET", "/cases?"+query.Encode(), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	var cases []*Case
+	if err := json.NewDecoder(w.Body).Decode(&cases); err != nil {
+		t.Fatal(err)
+	}
+	return cases
+}
@@ -53,15 +53,40 @@ type CompanyStatus struct {
 	Note      string             `json:"note"`
 	Status    CompanyBlockStatus `json:"block"`
 	CreatedAt time.Time          `json:"createdAt"`
+
+	// OverrideID, State, ApprovedBy and ExpiresAt track the override's approval and expiry
+	OverrideID string      `json:"overrideID,omitempty"`
+	State      StatusState `json:"state,omitempty"`
+	ApprovedBy string      `json:"approvedBy,omitempty"`
+	ExpiresAt  *time.Time  `json:"expiresAt,omitempty"`
+}
+
+func companyStatusFromChange(change *StatusChange) *CompanyStatus {
+	if change == nil {
+		return nil
+	}
+	return &CompanyStatus{
+		UserID:     change.UserID,
+		Note:       change.Note,
+		Status:     CompanyBlockStatus(change.Status),
+		CreatedAt:  change.CreatedAt,
+		OverrideID: change.OverrideID,
+		State:      change.State,
+		ApprovedBy: change.ApprovedBy,
+		ExpiresAt:  change.ExpiresAt,
+	}
 }
 
 type companyWatchResponse struct {
-	WatchID string `json:"watchID"`
+	WatchID       string `json:"watchID"`
+	SigningSecret string `json:"signingSecret"`
 }
 
 func addCompanyRoutes(logger log.Logger, r *mux.Router, searcher *searcher, companyRepo companyRepository, watchRepo *sqliteWatchRepository) {
 	r.Methods("GET").Path("/companies/{companyID}").HandlerFunc(getCompany(logger, searcher, companyRepo))
 	r.Methods("PUT").Path("/companies/{companyID}").HandlerFunc(updateCompanyStatus(logger, searcher, companyRepo))
+	r.Methods("GET").Path("/companies/{companyID}/status/history").HandlerFunc(getCompanyStatusHistory(logger, companyRepo))
+	r.Methods("POST").Path("/companies/{companyID}/status/{overrideID}/approve").HandlerFunc(approveCompanyStatus(logger, companyRepo))
 
 	r.Methods("POST").Path("/companies/{companyID}/watch").HandlerFunc(addCompanyWatch(logger, searcher, watchRepo))
 	r.Methods("DELETE").Path("/companies/{companyID}/watch/{watchID}").HandlerFunc(removeCompanyWatch(logger, searcher, watchRepo))
@@ -109,6 +134,15 @@ func getCompanyByID(id string, searcher *searcher, repo companyRepository) (*Com
 type companyRepository interface {
 	getCompanyStatus(companyID string) (*CompanyStatus, error)
 	upsertCompanyStatus(companyID string, status *CompanyStatus) error
+
+	// getCompanyStatusHistory returns every status change of a company, oldest first.
+	getCompanyStatusHistory(companyID string) ([]StatusChange, error)
+
+	// approveCompanyStatus approves a pending override on behalf of approverID, who didn't request it.
+	approveCompanyStatus(companyID, overrideID, approverID string) (*CompanyStatus, error)
+
+	// expireCompanyStatuses reverts overrides which expired at or before now, returning how many were reverted.
+	expireCompanyStatuses(now time.Time) (int, error)
 }
 
 type sqliteCompanyRepository struct {
@@ -119,39 +153,48 @@ func (r *sqliteCompanyRepository) close() error {
 	return r.db.Close()
 }
 
+// getCompanyStatus returns the override in effect for a company: its newest approved override which hasn't expired.
 func (r *sqliteCompanyRepository) getCompanyStatus(companyID string) (*CompanyStatus, error) {
 	if companyID == "" {
 		return nil, errors.New("getCompanyStatus: no Company.ID")
 	}
-	query := `select user_id, note, status, created_at from company_status where company_id = ? and deleted_at is null order by created_at desc limit 1;`
-	stmt, err := r.db.Prepare(query)
+	change, err := readCurrentStatus(r.db, "company_status", "company_id", companyID, time.Now())
 	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
-
-	row := stmt.QueryRow(companyID)
-
-	var status CompanyStatus
-	err = row.Scan(&status.UserID, &status.Note, &status.Status, &status.CreatedAt)
-	if err != nil && !strings.Contains(err.Error(), "no rows in result set") {
 		return nil, fmt.Errorf("getCompanyStatus: %v", err)
 	}
-	if status.UserID == "" {
-		return nil, nil // not found
-	}
-	return &status, nil
+	return companyStatusFromChange(change), nil
 }
 
 func (r *sqliteCompanyRepository) upsertCompanyStatus(companyID string, status *CompanyStatus) error {
-	query := `insert or replace into company_status (company_id, user_id, note, status, created_at) values (?, ?, ?, ?, ?);`
-	stmt, err := r.db.Prepare(query)
+	// MySQL and Postgres keep microseconds, so truncate before the time is hashed
+	status.CreatedAt = status.CreatedAt.Truncate(time.Microsecond)
+
+	return appendStatus(r.db, "company_status", "company_id", companyID, StatusChange{
+		UserID:     status.UserID,
+		Note:       status.Note,
+		Status:     string(status.Status),
+		CreatedAt:  status.CreatedAt,
+		OverrideID: status.OverrideID,
+		State:      status.State,
+		ApprovedBy: status.ApprovedBy,
+		ExpiresAt:  status.ExpiresAt,
+	})
+}
+
+func (r *sqliteCompanyRepository) approveCompanyStatus(companyID, overrideID, approverID string) (*CompanyStatus, error) {
+	change, err := approveStatus(r.db, "company_status", "company_id", companyID, overrideID, approverID, time.Now())
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer stmt.Close()
-	_, err = stmt.Exec(companyID, status.UserID, status.Note, status.Status, status.CreatedAt)
-	return err
+	return companyStatusFromChange(change), nil
+}
+
+func (r *sqliteCompanyRepository) expireCompanyStatuses(now time.Time) (int, error) {
+	return expireStatuses(r.db, "company_status", "company_id", now)
+}
+
+func (r *sqliteCompanyRepository) getCompanyStatusHistory(companyID string) ([]StatusChange, error) {
+	return readStatusHistory(r.db, "company_status", "company_id", companyID)
 }
 
 func getCompany(logger log.Logger, searcher *searcher, companyRepo companyRepository) http.HandlerFunc {
@@ -177,6 +220,9 @@ type companyStatusRequest struct {
 
 	// Status represents a manual exception or unsafe designation
 	Status string `json:"status"`
+
+	// ExpiresAt is when the override is reverted, exceptions always expire (see statusExceptionExpiry)
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 func updateCompanyStatus(logger log.Logger, searcher *searcher, companyRepo companyRepository) http.HandlerFunc {
@@ -201,17 +247,20 @@ func updateCompanyStatus(logger log.Logger, searcher *searcher, companyRepo comp
 		status := CompanyBlockStatus(strings.ToLower(strings.TrimSpace(req.Status)))
 		switch status {
 		case CompanyUnsafe, CompanyException:
-			companyStatus := &CompanyStatus{
-				UserID:    userID,
-				Note:      req.Notes,
-				Status:    status,
-				CreatedAt: time.Now(),
+			// exceptions wait for a second user's approval, see approveCompanyStatus
+			change, err := newStatusOverride(userID, req.Notes, string(status), status == CompanyException, req.ExpiresAt, time.Now())
+			if err != nil {
+				moovhttp.Problem(w, err)
+				return
 			}
+			companyStatus := companyStatusFromChange(&change)
 			if err := companyRepo.upsertCompanyStatus(companyID, companyStatus); err != nil {
 				moovhttp.Problem(w, err)
 				return
 			}
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
 			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(companyStatus)
 			return
 		default:
 			w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -230,16 +279,14 @@ func addCompanyWatch(logger log.Logger, searcher *searcher, repo watchRepository
 			moovhttp.Problem(w, err)
 			return
 		}
-		if req.AuthToken == "" {
-			moovhttp.Problem(w, errNoAuthToken)
+		if err := req.validateNotification(); err != nil {
+			moovhttp.Problem(w, err)
 			return
 		}
-		webhook, err := validateWebhook(req.Webhook)
-		if err != nil {
+		if err := req.setSigningSecret(); err != nil {
 			moovhttp.Problem(w, err)
 			return
 		}
-		req.Webhook = webhook
 
 		companyID := getCompanyID(w, r)
 		if companyID == "" {
@@ -253,7 +300,7 @@ func addCompanyWatch(logger log.Logger, searcher *searcher, repo watchRepository
 
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(companyWatchResponse{watchID})
+		json.NewEncoder(w).Encode(companyWatchResponse{watchID, req.SigningSecret})
 	}
 }
 
@@ -288,16 +335,19 @@ func addCompanyNameWatch(logger log.Logger, searcher *searcher, repo watchReposi
 			moovhttp.Problem(w, err)
 			return
 		}
-		if req.AuthToken == "" {
-			moovhttp.Problem(w, errNoAuthToken)
+		if err := req.validateNotification(); err != nil {
+			moovhttp.Problem(w, err)
 			return
 		}
-		webhook, err := validateWebhook(req.Webhook)
-		if err != nil {
+		if err := req.setSigningSecret(); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if err := req.validateNameWatch(); err != nil {
 			moovhttp.Problem(w, err)
 			return
 		}
-		watchID, err := repo.addCompanyNameWatch(name, webhook, req.AuthToken)
+		watchID, err := repo.addCompanyNameWatch(name, req)
 		if err != nil {
 			moovhttp.Problem(w, err)
 			return
@@ -305,7 +355,7 @@ func addCompanyNameWatch(logger log.Logger, searcher *searcher, repo watchReposi
 
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(companyWatchResponse{watchID})
+		json.NewEncoder(w).Encode(companyWatchResponse{watchID, req.SigningSecret})
 	}
 }
 
@@ -189,6 +189,9 @@ func TestCompany_addWatch(t *testing.T) {
 	if watch.WatchID == "" {
 		t.Error("empty watch.WatchID")
 	}
+	if watch.SigningSecret == "" {
+		t.Error("empty watch.SigningSecret")
+	}
 }
 
 func TestCompany_addWatchNoBody(t *testing.T) {
@@ -262,6 +265,32 @@ func TestCompany_addNameWatch(t *testing.T) {
 	}
 }
 
+func TestCompany_addNameWatchInvalidOptions(t *testing.T) {
+	companyRepo := createTestCompanyRepository(t)
+	defer companyRepo.close()
+	watchRepo := createTestWatchRepository(t)
+	defer watchRepo.close()
+
+	router := mux.NewRouter()
+	addCompanyRoutes(nil, router, companySearcher, companyRepo, watchRepo)
+
+	bodies := []string{
+		`{"webhook": "https://moov.io", "authToken": "foo", "minMatch": 1.2}`,
+		`{"webhook": "https://moov.io", "authToken": "foo", "lists": ["sdn", "other"]}`,
+	}
+	for i := range bodies {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/companies/watch?name=foo", strings.NewReader(bodies[i]))
+		req.Header.Set("x-user-id", "test")
+		router.ServeHTTP(w, req)
+		w.Flush()
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("#%d: bogus status code: %d", i, w.Code)
+		}
+	}
+}
+
 func TestCompany_addCompanyNameWatchNoBody(t *testing.T) {
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest("POST", "/companies/watch?name=foo", nil)
@@ -56,15 +56,40 @@ type CustomerStatus struct {
 	Note      string              `json:"note"`
 	Status    CustomerBlockStatus `json:"block"`
 	CreatedAt time.Time           `json:"createdAt"`
+
+	// OverrideID, State, ApprovedBy and ExpiresAt track the override's approval and expiry
+	OverrideID string      `json:"overrideID,omitempty"`
+	State      StatusState `json:"state,omitempty"`
+	ApprovedBy string      `json:"approvedBy,omitempty"`
+	ExpiresAt  *time.Time  `json:"expiresAt,omitempty"`
+}
+
+func customerStatusFromChange(change *StatusChange) *CustomerStatus {
+	if change == nil {
+		return nil
+	}
+	return &CustomerStatus{
+		UserID:     change.UserID,
+		Note:       change.Note,
+		Status:     CustomerBlockStatus(change.Status),
+		CreatedAt:  change.CreatedAt,
+		OverrideID: change.OverrideID,
+		State:      change.State,
+		ApprovedBy: change.ApprovedBy,
+		ExpiresAt:  change.ExpiresAt,
+	}
 }
 
 type customerWatchResponse struct {
-	WatchID string `json:"watchID"`
+	WatchID       string `json:"watchID"`
+	SigningSecret string `json:"signingSecret"`
 }
 
 func addCustomerRoutes(logger log.Logger, r *mux.Router, searcher *searcher, custRepo *sqliteCustomerRepository, watchRepo *sqliteWatchRepository) {
 	r.Methods("GET").Path("/customers/{customerID}").HandlerFunc(getCustomer(logger, searcher, custRepo))
 	r.Methods("PUT").Path("/customers/{customerID}").HandlerFunc(updateCustomerStatus(logger, searcher, custRepo))
+	r.Methods("GET").Path("/customers/{customerID}/status/history").HandlerFunc(getCustomerStatusHistory(logger, custRepo))
+	r.Methods("POST").Path("/customers/{customerID}/status/{overrideID}/approve").HandlerFunc(approveCustomerStatus(logger, custRepo))
 
 	r.Methods("POST").Path("/customers/{customerID}/watch").HandlerFunc(addCustomerWatch(logger, searcher, watchRepo))
 	r.Methods("DELETE").Path("/customers/{customerID}/watch/{watchID}").HandlerFunc(removeCustomerWatch(logger, searcher, watchRepo))
@@ -113,6 +138,15 @@ func getCustomerByID(id string, searcher *searcher, custRepo customerRepository)
 type customerRepository interface {
 	getCustomerStatus(customerID string) (*CustomerStatus, error)
 	upsertCustomerStatus(customerID string, status *CustomerStatus) error
+
+	// getCustomerStatusHistory returns every status change of a customer, oldest first.
+	getCustomerStatusHistory(customerID string) ([]StatusChange, error)
+
+	// approveCustomerStatus approves a pending override on behalf of approverID, who didn't request it.
+	approveCustomerStatus(customerID, overrideID, approverID string) (*CustomerStatus, error)
+
+	// expireCustomerStatuses reverts overrides which expired at or before now, returning how many were reverted.
+	expireCustomerStatuses(now time.Time) (int, error)
 }
 
 type sqliteCustomerRepository struct {
@@ -123,39 +157,48 @@ func (r *sqliteCustomerRepository) close() error {
 	return r.db.Close()
 }
 
+// getCustomerStatus returns the override in effect for a customer: its newest approved override which hasn't expired.
 func (r *sqliteCustomerRepository) getCustomerStatus(customerID string) (*CustomerStatus, error) {
 	if customerID == "" {
 		return nil, errors.New("getCustomerStatus: no Customer.ID")
 	}
-	query := `select user_id, note, status, created_at from customer_status where customer_id = ? and deleted_at is null order by created_at desc limit 1;`
-	stmt, err := r.db.Prepare(query)
+	change, err := readCurrentStatus(r.db, "customer_status", "customer_id", customerID, time.Now())
 	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
-
-	row := stmt.QueryRow(customerID)
-
-	var status CustomerStatus
-	err = row.Scan(&status.UserID, &status.Note, &status.Status, &status.CreatedAt)
-	if err != nil && !strings.Contains(err.Error(), "no rows in result set") {
 		return nil, fmt.Errorf("getCustomerStatus: %v", err)
 	}
-	if status.UserID == "" {
-		return nil, nil // not found
-	}
-	return &status, nil
+	return customerStatusFromChange(change), nil
 }
 
 func (r *sqliteCustomerRepository) upsertCustomerStatus(customerID string, status *CustomerStatus) error {
-	query := `insert or replace into customer_status (customer_id, user_id, note, status, created_at) values (?, ?, ?, ?, ?);`
-	stmt, err := r.db.Prepare(query)
+	// MySQL and Postgres keep microseconds, so truncate before the time is hashed
+	status.CreatedAt = status.CreatedAt.Truncate(time.Microsecond)
+
+	return appendStatus(r.db, "customer_status", "customer_id", customerID, StatusChange{
+		UserID:     status.UserID,
+		Note:       status.Note,
+		Status:     string(status.Status),
+		CreatedAt:  status.CreatedAt,
+		OverrideID: status.OverrideID,
+		State:      status.State,
+		ApprovedBy: status.ApprovedBy,
+		ExpiresAt:  status.ExpiresAt,
+	})
+}
+
+func (r *sqliteCustomerRepository) approveCustomerStatus(customerID, overrideID, approverID string) (*CustomerStatus, error) {
+	change, err := approveStatus(r.db, "customer_status", "customer_id", customerID, overrideID, approverID, time.Now())
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer stmt.Close()
-	_, err = stmt.Exec(customerID, status.UserID, status.Note, status.Status, status.CreatedAt)
-	return err
+	return customerStatusFromChange(change), nil
+}
+
+func (r *sqliteCustomerRepository) expireCustomerStatuses(now time.Time) (int, error) {
+	return expireStatuses(r.db, "customer_status", "customer_id", now)
+}
+
+func (r *sqliteCustomerRepository) getCustomerStatusHistory(customerID string) ([]StatusChange, error) {
+	return readStatusHistory(r.db, "customer_status", "customer_id", customerID)
 }
 
 func getCustomer(logger log.Logger, searcher *searcher, custRepo customerRepository) http.HandlerFunc {
@@ -194,16 +237,19 @@ func addCustomerNameWatch(logger log.Logger, searcher *searcher, repo *sqliteWat
 			moovhttp.Problem(w, err)
 			return
 		}
-		if req.AuthToken == "" {
-			moovhttp.Problem(w, errNoAuthToken)
+		if err := req.validateNotification(); err != nil {
+			moovhttp.Problem(w, err)
 			return
 		}
-		webhook, err := validateWebhook(req.Webhook)
-		if err != nil {
+		if err := req.setSigningSecret(); err != nil {
 			moovhttp.Problem(w, err)
 			return
 		}
-		watchID, err := repo.addCustomerNameWatch(name, webhook, req.AuthToken)
+		if err := req.validateNameWatch(); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		watchID, err := repo.addCustomerNameWatch(name, req)
 		if err != nil {
 			moovhttp.Problem(w, err)
 			return
@@ -211,7 +257,7 @@ func addCustomerNameWatch(logger log.Logger, searcher *searcher, repo *sqliteWat
 
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(customerWatchResponse{watchID}); err != nil {
+		if err := json.NewEncoder(w).Encode(customerWatchResponse{watchID, req.SigningSecret}); err != nil {
 			moovhttp.Problem(w, err)
 			return
 		}
@@ -227,16 +273,14 @@ func addCustomerWatch(logger log.Logger, searcher *searcher, repo *sqliteWatchRe
 			moovhttp.Problem(w, err)
 			return
 		}
-		if req.AuthToken == "" {
-			moovhttp.Problem(w, errNoAuthToken)
+		if err := req.validateNotification(); err != nil {
+			moovhttp.Problem(w, err)
 			return
 		}
-		webhook, err := validateWebhook(req.Webhook)
-		if err != nil {
+		if err := req.setSigningSecret(); err != nil {
 			moovhttp.Problem(w, err)
 			return
 		}
-		req.Webhook = webhook
 
 		customerID := getCustomerID(w, r)
 		watchID, err := repo.addCustomerWatch(customerID, req)
@@ -247,7 +291,7 @@ func addCustomerWatch(logger log.Logger, searcher *searcher, repo *sqliteWatchRe
 
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(customerWatchResponse{watchID}); err != nil {
+		if err := json.NewEncoder(w).Encode(customerWatchResponse{watchID, req.SigningSecret}); err != nil {
 			moovhttp.Problem(w, err)
 			return
 		}
@@ -259,6 +303,9 @@ type customerStatusRequest struct {
 
 	// Status represents a manual exception or unsafe designation
 	Status string `json:"status"`
+
+	// ExpiresAt is when the override is reverted, exceptions always expire (see statusExceptionExpiry)
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 func updateCustomerStatus(logger log.Logger, searcher *searcher, custRepo customerRepository) http.HandlerFunc {
@@ -281,17 +328,20 @@ func updateCustomerStatus(logger log.Logger, searcher *searcher, custRepo custom
 		status := CustomerBlockStatus(strings.ToLower(strings.TrimSpace(req.Status)))
 		switch status {
 		case CustomerUnsafe, CustomerException:
-			custStatus := &CustomerStatus{
-				UserID:    userID,
-				Note:      req.Notes,
-				Status:    status,
-				CreatedAt: time.Now(),
+			// exceptions wait for a second user's approval, see approveCustomerStatus
+			change, err := newStatusOverride(userID, req.Notes, string(status), status == CustomerException, req.ExpiresAt, time.Now())
+			if err != nil {
+				moovhttp.Problem(w, err)
+				return
 			}
+			custStatus := customerStatusFromChange(&change)
 			if err := custRepo.upsertCustomerStatus(custID, custStatus); err != nil {
 				moovhttp.Problem(w, err)
 				return
 			}
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
 			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(custStatus)
 			return
 		default:
 			w.Header().Set("Content-Type", "application/json; charset=utf-8")
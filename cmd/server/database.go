@@ -0,0 +1,104 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Databases which can be selected with DATABASE_TYPE
+const (
+	sqliteDatabase   = "sqlite"
+	postgresDatabase = "postgres"
+	mysqlDatabase    = "mysql"
+)
+
+// getDatabaseType returns the database selected with DATABASE_TYPE, which defaults to sqlite.
+func getDatabaseType() string {
+	tpe := strings.ToLower(strings.TrimSpace(os.Getenv("DATABASE_TYPE")))
+	if tpe == "" {
+		return sqliteDatabase
+	}
+	return tpe
+}
+
+// createDatabase connects to the database selected by databaseType and applies its migrations.
+//
+// Every repository (the sqlite* types) writes portable SQL with '?' placeholders, so the same
+// repositories are used regardless of the database. Only the schema differs between databases.
+//
+// sqlite is configured with SQLITE_DB_PATH, postgres with POSTGRES_URL and mysql with MYSQL_DSN.
+func createDatabase(logger log.Logger, databaseType string) (*sql.DB, error) {
+	db, migrations, err := openDatabase(logger, databaseType)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrateUp(logger, databaseType, db, migrations, 0); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// openDatabase connects to the database selected by databaseType and returns its migrations.
+func openDatabase(logger log.Logger, databaseType string) (*sql.DB, []migration, error) {
+	var db *sql.DB
+	var err error
+	var migrations []migration
+
+	switch databaseType {
+	case sqliteDatabase:
+		db, err = createSqliteConnection(logger, getSqlitePath())
+		migrations = sqliteMigrations
+	case postgresDatabase:
+		db, err = createPostgresConnection(logger, os.Getenv("POSTGRES_URL"))
+		migrations = postgresMigrations
+	case mysqlDatabase:
+		db, err = createMySQLConnection(logger, os.Getenv("MYSQL_DSN"))
+		migrations = mysqlMigrations
+	default:
+		return nil, nil, fmt.Errorf("unknown DATABASE_TYPE %q, expected one of: %s, %s, %s", databaseType, sqliteDatabase, postgresDatabase, mysqlDatabase)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, migrations, nil
+}
+
+// replaceRow deletes any row in table whose keyColumn matches the first value and inserts the values as
+// a new row. It's the portable form of sqlite's "insert or replace", so callers should use a transaction.
+func replaceRow(tx *sql.Tx, table string, keyColumn string, columns []string, values ...interface{}) error {
+	if len(values) != len(columns) || len(columns) == 0 || columns[0] != keyColumn {
+		return fmt.Errorf("replaceRow: %s needs %s as its first column and a value for each column", table, keyColumn)
+	}
+	if _, err := tx.Exec(`delete from `+table+` where `+keyColumn+` = ?;`, values[0]); err != nil {
+		return err
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	query := `insert into ` + table + ` (` + strings.Join(columns, ", ") + `) values (` + placeholders + `);`
+	_, err := tx.Exec(query, values...)
+	return err
+}
+
+// isUniqueViolation returns true when err is a conflict with a unique index from any of our databases
+func isUniqueViolation(err error) bool {
+	switch e := err.(type) {
+	case sqlite3.Error:
+		return e.ExtendedCode == sqlite3.ErrConstraintUnique || e.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	case *pq.Error:
+		return e.Code == "23505" // unique_violation
+	case *mysql.MySQLError:
+		return e.Number == 1062 // ER_DUP_ENTRY
+	}
+	return false
+}
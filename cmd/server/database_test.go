@@ -0,0 +1,370 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestDatabase__getDatabaseType(t *testing.T) {
+	if v := getDatabaseType(); v != sqliteDatabase {
+		t.Errorf("got %s", v)
+	}
+
+	os.Setenv("DATABASE_TYPE", " Postgres ")
+	defer os.Unsetenv("DATABASE_TYPE")
+	if v := getDatabaseType(); v != postgresDatabase {
+		t.Errorf("got %s", v)
+	}
+}
+
+func TestDatabase__createDatabase(t *testing.T) {
+	if _, err := createDatabase(nil, "oracle"); err == nil || !strings.Contains(err.Error(), "unknown DATABASE_TYPE") {
+		t.Errorf("expected error: %v", err)
+	}
+	if _, err := createDatabase(nil, postgresDatabase); err == nil {
+		t.Error("expected error without POSTGRES_URL")
+	}
+	if _, err := createDatabase(nil, mysqlDatabase); err == nil {
+		t.Error("expected error without MYSQL_DSN")
+	}
+	if _, err := createMySQLConnection(nil, "not a dsn"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestDatabase__postgresPlaceholders(t *testing.T) {
+	cases := map[string]string{
+		`select 1`:                                      `select 1`,
+		`select * from t where a = ? and b = ?;`:        `select * from t where a = $1 and b = $2;`,
+		`select '?' as q, "?" from t where a = ?`:       `select '?' as q, "?" from t where a = $1`,
+		`insert into t (a, b) values (?, 'it''s', ?);`:  `insert into t (a, b) values ($1, 'it''s', $2);`,
+		`update t set a = ? where id = ? and b is null`: `update t set a = $1 where id = $2 and b is null`,
+	}
+	for query, expected := range cases {
+		if v := postgresPlaceholders(query); v != expected {
+			t.Errorf("%s\n  got      %s\n  expected %s", query, v, expected)
+		}
+	}
+}
+
+func TestDatabase__postgresConnector(t *testing.T) {
+	conn := &recordingConn{}
+	db := sql.OpenDB(&postgresConnector{&recordingConnector{conn}})
+	defer db.Close()
+
+	if _, err := db.Exec(`delete from t where a = ? and b = ?`, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := db.Prepare(`select a from t where b = ?`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+
+	expected := []string{`delete from t where a = $1 and b = $2`, `select a from t where b = $1`}
+	if strings.Join(conn.queries, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected queries: %#v", conn.queries)
+	}
+}
+
+func TestDatabase__replaceRow(t *testing.T) {
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+
+	columns := []string{"watch_id", "notifier", "created_at"}
+	for _, notifier := range []string{"kafka", "nats"} {
+		tx, err := db.db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := replaceRow(tx, "watch_notifiers", "watch_id", columns, "watch", notifier, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var n int
+	var notifier string
+	if err := db.db.QueryRow(`select count(*), max(notifier) from watch_notifiers where watch_id = ?`, "watch").Scan(&n, &notifier); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || notifier != "nats" {
+		t.Errorf("n=%d notifier=%s", n, notifier)
+	}
+
+	// the key must be the first column
+	tx, _ := db.db.Begin()
+	defer tx.Rollback()
+	if err := replaceRow(tx, "watch_notifiers", "notifier", columns, "watch", "kafka", time.Now()); err == nil {
+		t.Error("expected error")
+	}
+}
+
+// TestDatabase__repositories runs our repositories against sqlite and, when POSTGRES_TEST_URL or
+// MYSQL_TEST_DSN are set, against those databases.
+func TestDatabase__repositories(t *testing.T) {
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := createTestSqliteDB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.close()
+		exerciseRepositories(t, db.db)
+	})
+	t.Run("postgres", func(t *testing.T) {
+		url := os.Getenv("POSTGRES_TEST_URL")
+		if url == "" {
+			t.Skip("POSTGRES_TEST_URL isn't set")
+		}
+		os.Setenv("POSTGRES_URL", url)
+		defer os.Unsetenv("POSTGRES_URL")
+
+		db, err := createDatabase(log.NewNopLogger(), postgresDatabase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		exerciseRepositories(t, db)
+	})
+	t.Run("mysql", func(t *testing.T) {
+		dsn := os.Getenv("MYSQL_TEST_DSN")
+		if dsn == "" {
+			t.Skip("MYSQL_TEST_DSN isn't set")
+		}
+		os.Setenv("MYSQL_DSN", dsn)
+		defer os.Unsetenv("MYSQL_DSN")
+
+		db, err := createDatabase(log.NewNopLogger(), mysqlDatabase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		exerciseRepositories(t, db)
+	})
+}
+
+func exerciseRepositories(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	// company status, the latest status wins
+	companyRepo := &sqliteCompanyRepository{db}
+	companyID := base.ID()
+	for _, status := range []CompanyBlockStatus{CompanyUnsafe, CompanyException} {
+		s := &CompanyStatus{UserID: base.ID(), Status: status, CreatedAt: time.Now()}
+		if err := companyRepo.upsertCompanyStatus(companyID, s); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if status, err := companyRepo.getCompanyStatus(companyID); err != nil || status == nil || status.Status != CompanyException {
+		t.Fatalf("status=%#v err=%v", status, err)
+	}
+
+	// watches, along with their options, secrets and notifiers
+	watchRepo := &sqliteWatchRepository{db, log.NewNopLogger()}
+	watchID, err := watchRepo.addCompanyNameWatch("john doe", watchRequest{
+		Webhook: "https://moov.io", AuthToken: "foo", SigningSecret: "secret", Notifier: webhookNotifierName, MinMatch: 0.9, Lists: []string{"SDN"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	minMatch, notifier := 0.95, fileNotifierName
+	if err := watchRepo.updateWatch(watchID, watchUpdate{MinMatch: &minMatch, Notifier: &notifier}); err != nil {
+		t.Fatal(err)
+	}
+	w, err := watchRepo.getWatch(watchID)
+	if err != nil || w == nil {
+		t.Fatalf("watch=%#v err=%v", w, err)
+	}
+	if w.Name != "john doe" || w.MinMatch != 0.95 || w.Notifier != fileNotifierName || len(w.Lists) != 1 {
+		t.Errorf("unexpected watch: %#v", w)
+	}
+
+	cursor := watchRepo.getWatchesCursor(nil, 100)
+	var found bool
+	for {
+		watches, err := cursor.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(watches) == 0 {
+			break
+		}
+		for i := range watches {
+			if watches[i].id == watchID {
+				found = watches[i].signingSecret == "secret"
+			}
+		}
+	}
+	if !found {
+		t.Errorf("watch %s not found with its secret", watchID)
+	}
+
+	// notifications are replaced
+	webhookRepo := &sqliteWebhookRepository{db}
+	for _, entityID := range []string{"1", "2"} {
+		n := &watchNotification{entityID: entityID, hash: "hash", payload: []byte(`{}`), changeType: WatchNewMatch, notifiedAt: time.Now()}
+		if err := webhookRepo.recordNotification(watchID, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := webhookRepo.getLastNotification(watchID); err != nil || n == nil || n.entityID != "2" {
+		t.Errorf("notification=%#v err=%v", n, err)
+	}
+
+	// watch runs
+	runRepo := &sqliteWatchRunRepository{db}
+	run, err := runRepo.startWatchRun(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	run.Processed = 3
+	if err := runRepo.updateWatchRun(run); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := runRepo.getWatchRun(run.ID); err != nil || found == nil || found.Processed != 3 {
+		t.Errorf("run=%#v err=%v", found, err)
+	}
+
+	// subjects are replaced and only open one case per record
+	subjectRepo := &sqliteSubjectRepository{db}
+	subject := &Subject{ID: base.ID(), Name: "john doe", Addresses: []SubjectAddress{{Country: "US"}}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	for i := 0; i < 2; i++ {
+		if err := subjectRepo.upsertSubject(subject); err != nil {
+			t.Fatal(err)
+		}
+		c := &Case{ID: base.ID(), SubjectID: subject.ID, List: sdnList, EntityID: "1", Match: 0.95, Status: CaseOpen, RecordHash: "a", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if _, _, err := subjectRepo.openCases([]*Case{c}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s, err := subjectRepo.getSubject(subject.ID); err != nil || s == nil || len(s.Addresses) != 1 {
+		t.Errorf("subject=%#v err=%v", s, err)
+	}
+	cases, err := subjectRepo.getSubjectCases(subject.ID)
+	if err != nil || len(cases) != 1 || cases[0].Match != 0.95 {
+		t.Fatalf("cases=%#v err=%v", cases, err)
+	}
+
+	// cleared cases are reopened when their record changes
+	cleared, fp := CaseCleared, CaseFalsePositive
+	if c, err := subjectRepo.updateCase(cases[0].ID, caseUpdate{Status: &cleared, Disposition: &fp}, time.Now()); err != nil || c == nil || c.ClosedAt == nil {
+		t.Fatalf("case=%#v err=%v", c, err)
+	}
+	changed := &Case{ID: base.ID(), SubjectID: subject.ID, List: sdnList, EntityID: "1", Status: CaseOpen, RecordHash: "b", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if _, reopened, err := subjectRepo.openCases([]*Case{changed}, time.Now()); err != nil || len(reopened) != 1 {
+		t.Errorf("reopened=%#v err=%v", reopened, err)
+	}
+	filter := caseFilter{statuses: []CaseStatus{CaseOpen}, subjectID: subject.ID, newerThan: time.Hour, limit: 10}
+	if found, err := subjectRepo.getCases(filter, time.Now()); err != nil || len(found) != 1 || found[0].ClosedAt != nil || found[0].Disposition != "" {
+		t.Errorf("cases=%#v err=%v", found, err)
+	}
+	if comments, err := subjectRepo.getCaseComments(cases[0].ID); err != nil || len(comments) != 1 {
+		t.Errorf("comments=%#v err=%v", comments, err)
+	}
+
+	// whitelist entries, invalidated entries are only listed on request
+	whitelistRepo := &sqliteWhitelistRepository{db}
+	entry := &WhitelistEntry{ID: base.ID(), SubjectID: subject.ID, List: sdnList, EntityID: "306", RecordHash: "abc", Reviewer: "jane", Reason: "different DOB", CreatedAt: time.Now()}
+	if err := whitelistRepo.addWhitelistEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := whitelistRepo.invalidateWhitelistEntries([]string{entry.ID}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := whitelistRepo.getWhitelist(false); err != nil || len(entries) != 0 {
+		t.Errorf("entries=%#v err=%v", entries, err)
+	}
+	if entries, err := whitelistRepo.getWhitelist(true); err != nil || len(entries) != 1 || entries[0].InvalidatedAt == nil {
+		t.Errorf("entries=%#v err=%v", entries, err)
+	}
+	if removed, err := whitelistRepo.removeWhitelistEntry(entry.ID); err != nil || !removed {
+		t.Errorf("removed=%v err=%v", removed, err)
+	}
+
+	// request quotas
+	quotaRepo := &sqliteQuotaRepository{db}
+	client := "ip:" + base.ID()
+	for i := 1; i <= 2; i++ {
+		if n, err := quotaRepo.incrementQuota(client, "2019-06-01"); n != i || err != nil {
+			t.Errorf("n=%d err=%v", n, err)
+		}
+	}
+
+	// concurrent first requests of a day are each counted once
+	counts := make(chan int, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(counts); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := quotaRepo.incrementQuota(client, "2019-06-02")
+			if err != nil {
+				t.Error(err)
+			}
+			counts <- n
+		}()
+	}
+	wg.Wait()
+	close(counts)
+	seen := make(map[int]bool)
+	for n := range counts {
+		seen[n] = true
+	}
+	if len(seen) != cap(counts) || !seen[1] || !seen[cap(counts)] {
+		t.Errorf("unexpected quota counts: %v", seen)
+	}
+}
+
+// recordingConnector hands out conn so tests can inspect the queries it was sent
+type recordingConnector struct {
+	conn *recordingConn
+}
+
+func (c *recordingConnector) Connect(ctx context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c *recordingConnector) Driver() driver.Driver                            { return nil }
+
+type recordingConn struct {
+	queries []string
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	c.queries = append(c.queries, query)
+	return recordingStmt{}, nil
+}
+func (c *recordingConn) Close() error              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.queries = append(c.queries, query)
+	return driver.RowsAffected(0), nil
+}
+
+type recordingStmt struct{}
+
+func (recordingStmt) Close() error  { return nil }
+func (recordingStmt) NumInput() int { return -1 }
+func (recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+func (recordingStmt) Query(args []driver.Value) (driver.Rows, error) { return nil, io.EOF }
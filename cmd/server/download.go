@@ -5,15 +5,19 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/cardonator/ofac"
+	"github.com/moov-io/base"
 	moovhttp "github.com/moov-io/base/http"
 
 	"github.com/go-kit/kit/log"
@@ -42,51 +46,163 @@ type Download struct {
 	DeniedPersons     int       `json:"deniedPersons"`
 	SectoralSanctions int       `json:"sectoralSanctions"`
 	BISEntities       int       `json:"bisEntities"`
+	// Status is refreshed, unchanged or rejected
+	Status downloadStatus `json:"status"`
+	// Reasons are why a rejected refresh failed its guardrails
+	Reasons []string `json:"reasons,omitempty"`
 }
 
 type downloadStats struct {
-	SDNs              int `json:"SDNs"`
-	Alts              int `json:"altNames"`
-	Addresses         int `json:"addresses"`
-	DeniedPersons     int `json:"deniedPersons"`
-	SectoralSanctions int `json:"sectoralSanctions"`
-	BISEntities       int `json:"bisEntities"`
+	Timestamp         time.Time      `json:"timestamp"`
+	SDNs              int            `json:"SDNs"`
+	Alts              int            `json:"altNames"`
+	Addresses         int            `json:"addresses"`
+	DeniedPersons     int            `json:"deniedPersons"`
+	SectoralSanctions int            `json:"sectoralSanctions"`
+	BISEntities       int            `json:"bisEntities"`
+	Status            downloadStatus `json:"status"`
+	Reasons           []string       `json:"reasons,omitempty"`
 }
 
-// periodicDataRefresh will forever block for interval's duration and then download and reparse the OFAC data.
-// Download stats are recorded as part of a successful re-download and parse.
-func (s *searcher) periodicDataRefresh(interval time.Duration, downloadRepo downloadRepository, updates chan *downloadStats) {
+// downloadStatus is the outcome of a data refresh
+type downloadStatus string
+
+const (
+	// downloadRefreshed means the lists were parsed and replaced our data
+	downloadRefreshed downloadStatus = "refreshed"
+
+	// downloadUnchanged means no list had changed since the last refresh, so our data was kept
+	downloadUnchanged downloadStatus = "unchanged"
+
+	// downloadRejected means the lists failed our guardrails, so our data was kept
+	downloadRejected downloadStatus = "rejected"
+)
+
+// periodicDataRefresh will block for interval's duration and then download and reparse the OFAC data until
+// stop is closed, cancelling a download in progress when ctx is done. Download stats are recorded as part of a successful re-download and parse. Watches aren't
+// re-searched when none of the lists changed.
+func (s *searcher) periodicDataRefresh(ctx context.Context, stop <-chan struct{}, interval time.Duration, downloadRepo downloadRepository, updates chan *downloadStats) {
 	for {
-		time.Sleep(interval)
-		stats, err := s.refreshData()
+		select {
+		case <-time.After(interval):
+		case <-stop:
+			return
+		}
+		stats, err := s.refreshData(ctx)
 		if err != nil {
 			if s.logger != nil {
 				s.logger.Log("main", fmt.Sprintf("ERROR: refreshing sanctions lists: %v", err))
 			}
 		} else {
 			downloadRepo.recordStats(stats)
+			if stats.Status == downloadUnchanged {
+				continue
+			}
 			if s.logger != nil {
 				s.logger.Log("main", fmt.Sprintf("Sanctions lists refreshed - Addresses=%d AltNames=%d SDNs=%d DPL=%d SectoralSanctions=%d ELs=%d",
 					stats.Addresses, stats.Alts, stats.SDNs, stats.DeniedPersons, stats.SectoralSanctions, stats.BISEntities))
 			}
-			updates <- stats // send stats for re-search and watch notifications
+			// send stats for re-search and watch notifications
+			select {
+			case updates <- stats:
+			case <-stop:
+				return
+			}
 		}
 	}
 }
 
 // refreshData reaches out to the OFAC and BIS Denied Persons List websites to download the latest
-// files and then runs ofac.Reader to parse and index data for searches.
-func (s *searcher) refreshData() (*downloadStats, error) {
+// files and then runs ofac.Reader to parse and index data for searches. The downloads are cancelled when ctx is done.
+//
+// Requests are conditional on the ETag and Last-Modified of our previous download. When no list has changed
+// our data is kept (without parsing the files) and stats with the downloadUnchanged status are returned.
+//
+// When INITIAL_DATA_DIRECTORY is set the lists are read from that directory or bundle instead of downloaded.
+//
+// Lists which fail our guardrails (see loadData) are discarded and a *refreshRejectedError is returned.
+func (s *searcher) refreshData(ctx context.Context) (*downloadStats, error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
 	if s.logger != nil {
 		s.logger.Log("download", "Starting refresh of sanctions lists")
 	}
 
+	// Read the lists from disk instead of downloading them
+	if s.offline != nil {
+		dir, cleanup, err := s.offline.open()
+		if err != nil {
+			return nil, fmt.Errorf("ERROR: opening offline sanctions lists: %v", err)
+		}
+		defer cleanup()
+
+		data, err := s.loadData(dir)
+		if err != nil {
+			return nil, err
+		}
+		return s.replaceData(data), nil
+	}
+
+	previous := s.manifest
+	if previous == nil && s.downloadRepo != nil {
+		m, err := s.downloadRepo.latestManifest()
+		if err != nil && s.logger != nil {
+			s.logger.Log("download", fmt.Sprintf("problem reading previous download: %v", err))
+		}
+		previous = m
+	}
+
 	// Download files
-	dir, err := (&ofac.Downloader{}).GetFiles()
+	manifest, err := (&ofac.Downloader{Previous: previous}).GetFilesContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("ERROR: downloading sanctions lists: %v", err)
 	}
+	dir := manifest.Dir
+	if s.logger != nil {
+		for _, f := range manifest.Files {
+			s.logger.Log("download", fmt.Sprintf("downloaded %s size=%d sha256=%s attempts=%d unchanged=%v", f.Name, f.Size, f.SHA256, f.Attempts, f.Unchanged))
+		}
+	}
 
+	if manifest.Unchanged() && s.manifest != nil {
+		s.saveManifest(previous, manifest)
+		if s.logger != nil {
+			s.logger.Log("download", "No change to sanctions lists since the last refresh, keeping current data")
+		}
+		lastOFACDataRefreshSuccess.WithLabelValues().Set(float64(time.Now().Unix()))
+		dataRefreshRejected.WithLabelValues().Set(0)
+		return s.currentStats(downloadUnchanged), nil
+	}
+
+	data, err := s.loadData(dir)
+	if err != nil {
+		if previous == nil || previous.Dir != dir {
+			os.RemoveAll(dir)
+		}
+		return nil, err
+	}
+	stats := s.replaceData(data)
+	s.saveManifest(previous, manifest)
+	return stats, nil
+}
+
+// listData holds the parsed and precomputed records of every list
+type listData struct {
+	sdns []*SDN
+	adds []*Address
+	alts []*Alt
+	dps  []*DP
+	ssis []*SSI
+	els  []*EL
+
+	// warnings are the rows skipped, or read with a bad column, while parsing
+	warnings base.ErrorList
+}
+
+// parseData runs ofac.Reader over the lists in dir and precomputes their records for searching. Rows which
+// can't be parsed are skipped and returned as warnings.
+func parseData(dir string) (*listData, error) {
 	// Parse each file
 	r := &ofac.Reader{}
 	r.FileName = filepath.Join(dir, "add.csv")
@@ -111,30 +227,41 @@ func (s *searcher) refreshData() (*downloadStats, error) {
 	}
 
 	// Precompute new data once for slight performance win
-	sdns := precomputeSDNs(r.SDNs)
-	adds := precomputeAddresses(r.Addresses)
-	alts := precomputeAlts(r.AlternateIdentities)
-	dps := precomputeDPs(r.DeniedPersons)
-	ssis := precomputeSSIs(r.SectoralSanctions)
-	els := precomputeELs(r.BISEntities)
-
-	stats := &downloadStats{
-		SDNs:              len(sdns),
-		Alts:              len(alts),
-		Addresses:         len(adds),
-		DeniedPersons:     len(dps),
-		SectoralSanctions: len(ssis),
-		BISEntities:       len(els),
+	return &listData{
+		sdns: precomputeSDNs(r.SDNs),
+		adds: precomputeAddresses(r.Addresses),
+		alts: precomputeAlts(r.AlternateIdentities),
+		dps:  precomputeDPs(r.DeniedPersons),
+		ssis: precomputeSSIs(r.SectoralSanctions),
+		els:  precomputeELs(r.BISEntities),
+
+		warnings: r.Errors(),
+	}, nil
+}
+
+func (d *listData) stats(status downloadStatus) *downloadStats {
+	return &downloadStats{
+		Timestamp:         time.Now(),
+		SDNs:              len(d.sdns),
+		Alts:              len(d.alts),
+		Addresses:         len(d.adds),
+		DeniedPersons:     len(d.dps),
+		SectoralSanctions: len(d.ssis),
+		BISEntities:       len(d.els),
+		Status:            status,
 	}
+}
 
+// replaceData swaps in d as the records we search and returns their stats.
+func (s *searcher) replaceData(d *listData) *downloadStats {
 	// Set new records after precomputation (to minimize lock contention)
 	s.Lock()
-	s.SDNs = sdns
-	s.Addresses = adds
-	s.Alts = alts
-	s.DPs = dps
-	s.SSIs = ssis
-	s.ELs = els
+	s.SDNs = d.sdns
+	s.Addresses = d.adds
+	s.Alts = d.alts
+	s.DPs = d.dps
+	s.SSIs = d.ssis
+	s.ELs = d.els
 	s.Unlock()
 
 	if s.logger != nil {
@@ -143,8 +270,49 @@ func (s *searcher) refreshData() (*downloadStats, error) {
 
 	// record successful data refresh
 	lastOFACDataRefreshSuccess.WithLabelValues().Set(float64(time.Now().Unix()))
+	dataRefreshRejected.WithLabelValues().Set(0)
 
-	return stats, nil
+	return d.stats(downloadRefreshed)
+}
+
+// maxLoggedWarnings is how many parse warnings are logged for each refresh
+const maxLoggedWarnings = 20
+
+// logParseWarnings logs the rows skipped, or read with a bad column, while parsing the lists
+func (s *searcher) logParseWarnings(warnings base.ErrorList) {
+	if s.logger == nil || len(warnings) == 0 {
+		return
+	}
+	for i := range warnings {
+		if i == maxLoggedWarnings {
+			s.logger.Log("download", fmt.Sprintf("... and %d more parse warnings", len(warnings)-maxLoggedWarnings))
+			break
+		}
+		s.logger.Log("download", fmt.Sprintf("parse warning: %v", warnings[i]))
+	}
+}
+
+// saveManifest keeps the manifest of the files our data was parsed from (in memory and our repository) for
+// the next refresh and removes the previous download's files.
+func (s *searcher) saveManifest(previous, manifest *ofac.Manifest) {
+	s.manifest = manifest
+	if s.downloadRepo != nil {
+		if err := s.downloadRepo.saveManifest(manifest); err != nil && s.logger != nil {
+			s.logger.Log("download", fmt.Sprintf("problem saving download manifest: %v", err))
+		}
+	}
+	if previous != nil && previous.Dir != "" && previous.Dir != manifest.Dir {
+		os.RemoveAll(previous.Dir)
+	}
+}
+
+// currentStats counts the records we're searching
+func (s *searcher) currentStats(status downloadStatus) *downloadStats {
+	s.RLock()
+	defer s.RUnlock()
+
+	d := listData{sdns: s.SDNs, adds: s.Addresses, alts: s.Alts, dps: s.DPs, ssis: s.SSIs, els: s.ELs}
+	return d.stats(status)
 }
 
 func addDownloadRoutes(logger log.Logger, r *mux.Router, repo downloadRepository) {
@@ -173,6 +341,10 @@ func getLatestDownloads(logger log.Logger, repo downloadRepository) http.Handler
 type downloadRepository interface {
 	latestDownloads(limit int) ([]Download, error)
 	recordStats(stats *downloadStats) error
+
+	// latestManifest returns the files of the last download our data was parsed from, or nil
+	latestManifest() (*ofac.Manifest, error)
+	saveManifest(manifest *ofac.Manifest) error
 }
 
 type sqliteDownloadRepository struct {
@@ -189,19 +361,22 @@ func (r *sqliteDownloadRepository) recordStats(stats *downloadStats) error {
 		return errors.New("recordStats: nil downloadStats")
 	}
 
-	query := `insert into ofac_download_stats (downloaded_at, sdns, alt_names, addresses, denied_persons, sectoral_sanctions, bis_entities) values (?, ?, ?, ?, ?, ?, ?);`
+	if stats.Status == "" {
+		stats.Status = downloadRefreshed
+	}
+	query := `insert into ofac_download_stats (downloaded_at, sdns, alt_names, addresses, denied_persons, sectoral_sanctions, bis_entities, status, reasons) values (?, ?, ?, ?, ?, ?, ?, ?, ?);`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(time.Now(), stats.SDNs, stats.Alts, stats.Addresses, stats.DeniedPersons, stats.SectoralSanctions, stats.BISEntities)
+	_, err = stmt.Exec(stats.Timestamp, stats.SDNs, stats.Alts, stats.Addresses, stats.DeniedPersons, stats.SectoralSanctions, stats.BISEntities, stats.Status, strings.Join(stats.Reasons, "\n"))
 	return err
 }
 
 func (r *sqliteDownloadRepository) latestDownloads(limit int) ([]Download, error) {
-	query := `select downloaded_at, sdns, alt_names, addresses, denied_persons, sectoral_sanctions, bis_entities from ofac_download_stats order by downloaded_at desc limit ?;`
+	query := `select downloaded_at, sdns, alt_names, addresses, denied_persons, sectoral_sanctions, bis_entities, coalesce(status, ''), coalesce(reasons, '') from ofac_download_stats order by downloaded_at desc limit ?;`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return nil, err
@@ -217,9 +392,101 @@ func (r *sqliteDownloadRepository) latestDownloads(limit int) ([]Download, error
 	var downloads []Download
 	for rows.Next() {
 		var dl Download
-		if err := rows.Scan(&dl.Timestamp, &dl.SDNs, &dl.Alts, &dl.Addresses, &dl.DeniedPersons, &dl.SectoralSanctions, &dl.BISEntities); err == nil {
+		var reasons string
+		if err := rows.Scan(&dl.Timestamp, &dl.SDNs, &dl.Alts, &dl.Addresses, &dl.DeniedPersons, &dl.SectoralSanctions, &dl.BISEntities, &dl.Status, &reasons); err == nil {
+			if dl.Status == "" {
+				dl.Status = downloadRefreshed // recorded before statuses were
+			}
+			if reasons != "" {
+				dl.Reasons = strings.Split(reasons, "\n")
+			}
 			downloads = append(downloads, dl)
 		}
 	}
 	return downloads, rows.Err()
 }
+
+func (r *sqliteDownloadRepository) latestManifest() (*ofac.Manifest, error) {
+	rows, err := r.db.Query(`select name, url, etag, last_modified, sha256, size, dir from download_sources order by name asc;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var manifest *ofac.Manifest
+	for rows.Next() {
+		var f ofac.ManifestFile
+		var dir string
+		if err := rows.Scan(&f.Name, &f.URL, &f.ETag, &f.LastModified, &f.SHA256, &f.Size, &dir); err != nil {
+			return nil, fmt.Errorf("latestManifest: %v", err)
+		}
+		if manifest == nil {
+			manifest = &ofac.Manifest{Dir: dir}
+		}
+		manifest.Files = append(manifest.Files, &f)
+	}
+	return manifest, rows.Err()
+}
+
+func (r *sqliteDownloadRepository) saveManifest(manifest *ofac.Manifest) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`delete from download_sources;`); err != nil {
+		tx.Rollback()
+		return err
+	}
+	now := time.Now()
+	for _, f := range manifest.Files {
+		query := `insert into download_sources (name, url, etag, last_modified, sha256, size, dir, updated_at) values (?, ?, ?, ?, ?, ?, ?, ?);`
+		if _, err := tx.Exec(query, f.Name, f.URL, f.ETag, f.LastModified, f.SHA256, f.Size, manifest.Dir, now); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// addDownloadSources is the migration which records the checksum, ETag and Last-Modified of each downloaded
+// list and the status of each refresh
+func addDownloadSources(databaseType string) []string {
+	timestamp := map[string]string{sqliteDatabase: "datetime", postgresDatabase: "timestamptz", mysqlDatabase: "datetime(6)"}[databaseType]
+
+	return []string{
+		fmt.Sprintf(`create table if not exists download_sources(name varchar(64) primary key, url text, etag text, last_modified text, sha256 varchar(64), size integer, dir text, updated_at %s);`, timestamp),
+		`alter table ofac_download_stats add column status varchar(16);`,
+	}
+}
+
+// dropDownloadSources reverts addDownloadSources
+func dropDownloadSources(databaseType string) []string {
+	out := []string{`drop table if exists download_sources;`}
+	if databaseType == sqliteDatabase {
+		return append(out,
+			`create table ofac_download_stats_copy(downloaded_at datetime, sdns, alt_names, addresses, denied_persons, sectoral_sanctions, bis_entities);`,
+			`insert into ofac_download_stats_copy select downloaded_at, sdns, alt_names, addresses, denied_persons, sectoral_sanctions, bis_entities from ofac_download_stats;`,
+			`drop table ofac_download_stats;`,
+			`alter table ofac_download_stats_copy rename to ofac_download_stats;`,
+		)
+	}
+	return append(out, `alter table ofac_download_stats drop column status;`)
+}
+
+// addDownloadReasons is the migration which records why a refresh was rejected
+func addDownloadReasons() []string {
+	return []string{`alter table ofac_download_stats add column reasons text;`}
+}
+
+// dropDownloadReasons reverts addDownloadReasons
+func dropDownloadReasons(databaseType string) []string {
+	if databaseType == sqliteDatabase {
+		return []string{
+			`create table ofac_download_stats_copy(downloaded_at datetime, sdns, alt_names, addresses, denied_persons, sectoral_sanctions, bis_entities, status varchar(16));`,
+			`insert into ofac_download_stats_copy select downloaded_at, sdns, alt_names, addresses, denied_persons, sectoral_sanctions, bis_entities, status from ofac_download_stats;`,
+			`drop table ofac_download_stats;`,
+			`alter table ofac_download_stats_copy rename to ofac_download_stats;`,
+		}
+	}
+	return []string{`alter table ofac_download_stats drop column reasons;`}
+}
@@ -20,7 +20,7 @@ func manualRefreshHandler(logger log.Logger, searcher *searcher, downloadRepo do
 		if logger != nil {
 			logger.Log("main", "admin: refreshing sanctions lists")
 		}
-		if stats, err := searcher.refreshData(); err != nil {
+		if stats, err := searcher.refreshData(r.Context()); err != nil {
 			if logger != nil {
 				logger.Log("main", fmt.Sprintf("ERROR: admin: problem refreshing sanctions lists: %v", err))
 			}
@@ -5,10 +5,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/cardonator/ofac"
 
 	"github.com/go-kit/kit/log"
 	"github.com/gorilla/mux"
@@ -30,7 +37,7 @@ func TestSearcher__refreshData(t *testing.T) {
 	}
 
 	s := &searcher{}
-	stats, err := s.refreshData()
+	stats, err := s.refreshData(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -51,6 +58,29 @@ func TestSearcher__refreshData(t *testing.T) {
 	}
 }
 
+func TestDownload__parseDataWarnings(t *testing.T) {
+	dir := copyTestData(t)
+	defer os.RemoveAll(dir)
+
+	fd, err := os.OpenFile(filepath.Join(dir, "sdn.csv"), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.WriteString("\n999999,\"CUT OFF\",-0- \n")
+	fd.Close()
+
+	data, err := parseData(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.sdns) != 7379 || len(data.warnings) != 1 {
+		t.Errorf("SDNs=%d warnings=%v", len(data.sdns), data.warnings)
+	}
+	if !strings.Contains(data.warnings[0].Error(), "record:SDN") {
+		t.Errorf("unexpected warning: %v", data.warnings[0])
+	}
+}
+
 func createTestDownloadRepository(t *testing.T) *sqliteDownloadRepository {
 	t.Helper()
 
@@ -66,7 +96,7 @@ func TestDownload_record(t *testing.T) {
 	repo := createTestDownloadRepository(t)
 	defer repo.close()
 
-	stats := &downloadStats{1, 12, 42, 13, 30, 3}
+	stats := &downloadStats{time.Now(), 1, 12, 42, 13, 30, 3, downloadUnchanged, nil}
 	if err := repo.recordStats(stats); err != nil {
 		t.Fatal(err)
 	}
@@ -97,6 +127,52 @@ func TestDownload_record(t *testing.T) {
 	if dl.BISEntities != stats.BISEntities {
 		t.Errorf("dl.ELs=%d stats.ELs=%d", dl.BISEntities, stats.BISEntities)
 	}
+	if dl.Status != downloadUnchanged {
+		t.Errorf("dl.Status=%s", dl.Status)
+	}
+}
+
+func TestDownload_manifest(t *testing.T) {
+	repo := createTestDownloadRepository(t)
+	defer repo.close()
+
+	if m, err := repo.latestManifest(); m != nil || err != nil {
+		t.Fatalf("manifest=%#v err=%v", m, err)
+	}
+
+	manifest := &ofac.Manifest{
+		Dir: "/tmp/sanctions-lists-downloader123",
+		Files: []*ofac.ManifestFile{
+			{Name: "add.csv", URL: "https://www.treasury.gov/ofac/downloads/add.csv", Size: 42, SHA256: "abc", ETag: `"1"`},
+			{Name: "sdn.csv", URL: "https://www.treasury.gov/ofac/downloads/sdn.csv", Size: 12, SHA256: "def", LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"},
+		},
+	}
+	if err := repo.saveManifest(manifest); err != nil {
+		t.Fatal(err)
+	}
+	manifest.Files = manifest.Files[1:]
+	if err := repo.saveManifest(manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := repo.latestManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found == nil || found.Dir != manifest.Dir || len(found.Files) != 1 {
+		t.Fatalf("unexpected manifest: %#v", found)
+	}
+	if f := found.File("sdn.csv"); f == nil || f.Size != 12 || f.SHA256 != "def" || f.LastModified == "" || f.Unchanged {
+		t.Errorf("unexpected file: %#v", f)
+	}
+}
+
+func TestDownload_currentStats(t *testing.T) {
+	s := &searcher{SDNs: make([]*SDN, 3), DPs: make([]*DP, 2)}
+	stats := s.currentStats(downloadUnchanged)
+	if stats.SDNs != 3 || stats.DeniedPersons != 2 || stats.Alts != 0 || stats.Status != downloadUnchanged {
+		t.Errorf("unexpected stats: %#v", stats)
+	}
 }
 
 func TestDownload_route(t *testing.T) {
@@ -108,7 +184,7 @@ func TestDownload_route(t *testing.T) {
 	defer repo.close()
 
 	// save a record
-	if err := repo.recordStats(&downloadStats{1, 421, 1511, 731, 230, 32}); err != nil {
+	if err := repo.recordStats(&downloadStats{time.Now(), 1, 421, 1511, 731, 230, 32, downloadRefreshed, nil}); err != nil {
 		t.Fatalf("%T: %s", err, err)
 	}
 
@@ -0,0 +1,231 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cardonator/ofac"
+	"github.com/moov-io/base"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	dataRefreshRejected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "data_refresh_rejected",
+		Help: "Set to 1 when the latest refresh of the sanctions lists was rejected by its guardrails, 0 once one is accepted",
+	}, nil)
+
+	dataRefreshRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "data_refresh_rejections",
+		Help: "Counter of sanctions list refreshes rejected by their guardrails",
+	}, nil)
+
+	// defaultMaxDropPercent is how far (in percent) a list's record count can drop in one refresh
+	defaultMaxDropPercent = 20.0
+)
+
+func init() {
+	prometheus.MustRegister(dataRefreshRejected)
+	prometheus.MustRegister(dataRefreshRejections)
+}
+
+// listNames are the names guardrails are configured with, matching the fields of downloadStats
+var listNames = []string{"SDNs", "altNames", "addresses", "deniedPersons", "sectoralSanctions", "bisEntities"}
+
+func listCounts(stats *downloadStats) map[string]int {
+	return map[string]int{
+		"SDNs":              stats.SDNs,
+		"altNames":          stats.Alts,
+		"addresses":         stats.Addresses,
+		"deniedPersons":     stats.DeniedPersons,
+		"sectoralSanctions": stats.SectoralSanctions,
+		"bisEntities":       stats.BISEntities,
+	}
+}
+
+// refreshGuardrails reject a refresh of the sanctions lists which looks broken (e.g. a truncated download),
+// so the data we search is kept until a good refresh.
+type refreshGuardrails struct {
+	// maxDropPercent is how far each list's record count can drop from our current data, keyed by list name
+	// with "" as the default. Zero allows any drop.
+	maxDropPercent map[string]float64
+
+	// minRecords is the fewest records each list can have, keyed by list name
+	minRecords map[string]int
+
+	// validateFiles rejects lists with rows which were skipped while parsing them (see checkWarnings)
+	validateFiles bool
+}
+
+// readRefreshGuardrails reads DATA_REFRESH_MAX_DROP_PERCENT, DATA_REFRESH_MIN_RECORDS and
+// DATA_REFRESH_VALIDATE_FILES from our environment.
+func readRefreshGuardrails() (*refreshGuardrails, error) {
+	g := &refreshGuardrails{
+		maxDropPercent: map[string]float64{"": defaultMaxDropPercent},
+		minRecords:     make(map[string]int),
+		validateFiles:  true,
+	}
+	if v := os.Getenv("DATA_REFRESH_MAX_DROP_PERCENT"); v != "" {
+		drops, err := parseListLimits(v, true)
+		if err != nil {
+			return nil, fmt.Errorf("DATA_REFRESH_MAX_DROP_PERCENT: %v", err)
+		}
+		for name, pct := range drops {
+			g.maxDropPercent[name] = pct
+		}
+	}
+	mins, err := parseListLimits(os.Getenv("DATA_REFRESH_MIN_RECORDS"), false)
+	if err != nil {
+		return nil, fmt.Errorf("DATA_REFRESH_MIN_RECORDS: %v", err)
+	}
+	for name, n := range mins {
+		g.minRecords[name] = int(n)
+	}
+	if v := os.Getenv("DATA_REFRESH_VALIDATE_FILES"); v != "" {
+		validate, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("DATA_REFRESH_VALIDATE_FILES: invalid value %q", v)
+		}
+		g.validateFiles = validate
+	}
+	return g, nil
+}
+
+// parseListLimits reads comma separated limits for lists, e.g. "SDNs=5000,deniedPersons=300". When allowDefault
+// is true a limit without a list name (e.g. "20,SDNs=10") is returned under "".
+func parseListLimits(str string, allowDefault bool) (map[string]float64, error) {
+	out := make(map[string]float64)
+	for _, rule := range strings.Split(str, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		name, value := "", rule
+		if idx := strings.Index(rule, "="); idx >= 0 {
+			value = strings.TrimSpace(rule[idx+1:])
+			for i := range listNames {
+				if strings.EqualFold(strings.TrimSpace(rule[:idx]), listNames[i]) {
+					name = listNames[i]
+				}
+			}
+			if name == "" {
+				return nil, fmt.Errorf("unknown list in %q, expected one of %s", rule, strings.Join(listNames, ", "))
+			}
+		} else if !allowDefault {
+			return nil, fmt.Errorf("invalid limit %q, expected list=value", rule)
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid value in %q", rule)
+		}
+		out[name] = n
+	}
+	return out, nil
+}
+
+// checkCounts returns why next can't replace our current data, or nothing when it can
+func (g *refreshGuardrails) checkCounts(current, next *downloadStats) []string {
+	var reasons []string
+	prev, counts := listCounts(current), listCounts(next)
+	for _, name := range listNames {
+		n := counts[name]
+		if min, exists := g.minRecords[name]; exists && n < min {
+			reasons = append(reasons, fmt.Sprintf("%s: %d records is below the minimum of %d", name, n, min))
+		}
+		maxDrop, exists := g.maxDropPercent[name]
+		if !exists {
+			maxDrop = g.maxDropPercent[""]
+		}
+		if maxDrop <= 0 || prev[name] == 0 || n >= prev[name] {
+			continue
+		}
+		if drop := float64(prev[name]-n) / float64(prev[name]) * 100; drop > maxDrop {
+			reasons = append(reasons, fmt.Sprintf("%s: dropped %.1f%% from %d to %d records, more than the maximum of %g%%", name, drop, prev[name], n, maxDrop))
+		}
+	}
+	return reasons
+}
+
+// checkWarnings returns a reason for each list with rows which were skipped while parsing it, e.g. rows with
+// the wrong number of columns from a truncated download, or a header naming the wrong columns. Rows read with
+// a bad column aren't rejected.
+func (g *refreshGuardrails) checkWarnings(warnings base.ErrorList) []string {
+	if !g.validateFiles {
+		return nil
+	}
+	var records []string
+	first, bad := make(map[string]*base.ParseError), make(map[string]int)
+	for i := range warnings {
+		perr, ok := warnings[i].(*base.ParseError)
+		if !ok {
+			continue
+		}
+		if _, ok := perr.Err.(*ofac.ColumnError); ok {
+			continue
+		}
+		if first[perr.Record] == nil {
+			first[perr.Record] = perr
+			records = append(records, perr.Record)
+		}
+		bad[perr.Record]++
+	}
+	var reasons []string
+	for _, record := range records {
+		reason := fmt.Sprintf("%s: line %d: %v", record, first[record].Line, first[record].Err)
+		if n := bad[record]; n > 1 {
+			reason += fmt.Sprintf(" (and %d more bad rows)", n-1)
+		}
+		reasons = append(reasons, reason)
+	}
+	return reasons
+}
+
+// refreshRejectedError is returned when a refresh fails its guardrails
+type refreshRejectedError struct {
+	reasons []string
+}
+
+func (e *refreshRejectedError) Error() string {
+	return fmt.Sprintf("refresh rejected: %s", strings.Join(e.reasons, "; "))
+}
+
+// loadData parses the lists in dir, logging any rows which were skipped, and checks them against our guardrails.
+// A rejected refresh is recorded with its reasons, raises the data_refresh_rejected metric and is returned as a
+// *refreshRejectedError.
+func (s *searcher) loadData(dir string) (*listData, error) {
+	data, err := parseData(dir)
+	if err != nil {
+		return nil, err
+	}
+	s.logParseWarnings(data.warnings)
+
+	var reasons []string
+	if s.guardrails != nil {
+		reasons = append(s.guardrails.checkWarnings(data.warnings), s.guardrails.checkCounts(s.currentStats(""), data.stats(""))...)
+	}
+	if len(reasons) == 0 {
+		return data, nil
+	}
+
+	stats := data.stats(downloadRejected)
+	stats.Reasons = reasons
+	if s.downloadRepo != nil {
+		if err := s.downloadRepo.recordStats(stats); err != nil && s.logger != nil {
+			s.logger.Log("download", fmt.Sprintf("problem recording rejected refresh: %v", err))
+		}
+	}
+	dataRefreshRejected.WithLabelValues().Set(1)
+	dataRefreshRejections.WithLabelValues().Inc()
+
+	rejected := &refreshRejectedError{reasons: reasons}
+	if s.logger != nil {
+		s.logger.Log("download", fmt.Sprintf("ERROR: keeping current data, %v", rejected))
+	}
+	return nil, rejected
+}
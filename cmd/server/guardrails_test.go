@@ -0,0 +1,205 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cardonator/ofac"
+	"github.com/moov-io/base"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// copyTestData copies test/testdata into a new temp directory
+func copyTestData(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "ofac-guardrails")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range append(dataBundleFiles, "sdn_comments.csv") {
+		bs, err := ioutil.ReadFile(filepath.Join(testDataDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), bs, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// truncateTestFile cuts the file down to its first n bytes, like an interrupted download
+func truncateTestFile(t *testing.T, path string, n int64) {
+	t.Helper()
+
+	if err := os.Truncate(path, n); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGuardrails__read(t *testing.T) {
+	setenv := func(drop, min, validate string) {
+		os.Setenv("DATA_REFRESH_MAX_DROP_PERCENT", drop)
+		os.Setenv("DATA_REFRESH_MIN_RECORDS", min)
+		os.Setenv("DATA_REFRESH_VALIDATE_FILES", validate)
+	}
+	defer setenv("", "", "")
+
+	setenv("", "", "")
+	g, err := readRefreshGuardrails()
+	if err != nil || g.maxDropPercent[""] != defaultMaxDropPercent || len(g.minRecords) != 0 || !g.validateFiles {
+		t.Errorf("g=%#v err=%v", g, err)
+	}
+
+	setenv("10, sdns=5", "SDNs=5000,deniedPersons=300", "false")
+	g, err = readRefreshGuardrails()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.maxDropPercent[""] != 10 || g.maxDropPercent["SDNs"] != 5 || g.minRecords["deniedPersons"] != 300 || g.validateFiles {
+		t.Errorf("g=%#v", g)
+	}
+
+	for _, env := range [][]string{{"lots", "", ""}, {"foo=5", "", ""}, {"", "5000", ""}, {"", "SDNs=-1", ""}, {"", "", "sometimes"}} {
+		setenv(env[0], env[1], env[2])
+		if _, err := readRefreshGuardrails(); err == nil {
+			t.Errorf("%v: expected error", env)
+		}
+	}
+}
+
+func TestGuardrails__checkCounts(t *testing.T) {
+	g := &refreshGuardrails{
+		maxDropPercent: map[string]float64{"": 20, "deniedPersons": 0},
+		minRecords:     map[string]int{"SDNs": 1000},
+	}
+	current := &downloadStats{SDNs: 7000, Alts: 9000, DeniedPersons: 500}
+
+	if reasons := g.checkCounts(current, &downloadStats{SDNs: 6500, Alts: 9500, DeniedPersons: 10}); len(reasons) != 0 {
+		t.Errorf("unexpected reasons: %v", reasons)
+	}
+	reasons := g.checkCounts(current, &downloadStats{SDNs: 200, Alts: 7000, DeniedPersons: 500})
+	if len(reasons) != 3 {
+		t.Fatalf("reasons=%v", reasons)
+	}
+	if reasons[0] != "SDNs: 200 records is below the minimum of 1000" || reasons[1] != "SDNs: dropped 97.1% from 7000 to 200 records, more than the maximum of 20%" {
+		t.Errorf("reasons=%v", reasons)
+	}
+	if !strings.HasPrefix(reasons[2], "altNames: dropped 22.2%") {
+		t.Errorf("reasons=%v", reasons)
+	}
+
+	// there's nothing to drop from on startup
+	if reasons := g.checkCounts(&downloadStats{}, &downloadStats{SDNs: 1000}); len(reasons) != 0 {
+		t.Errorf("unexpected reasons: %v", reasons)
+	}
+}
+
+func TestGuardrails__checkWarnings(t *testing.T) {
+	dir := copyTestData(t)
+	defer os.RemoveAll(dir)
+
+	g := &refreshGuardrails{validateFiles: true}
+	check := func() []string {
+		t.Helper()
+		data, err := parseData(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g.checkWarnings(data.warnings)
+	}
+	if reasons := check(); len(reasons) != 0 {
+		t.Fatalf("unexpected reasons: %v", reasons)
+	}
+
+	// cut sdn.csv off in the middle of a row
+	truncateTestFile(t, filepath.Join(dir, "sdn.csv"), 1000)
+	if err := ioutil.WriteFile(filepath.Join(dir, "dpl.txt"), []byte("Name\tCity\nfoo\tbar\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reasons := check()
+	if len(reasons) != 2 || !strings.HasPrefix(reasons[0], "SDN: line ") || reasons[1] != `DPL: line 1: unexpected header "Name\tCity" (and 1 more bad rows)` {
+		t.Errorf("reasons=%q", reasons)
+	}
+
+	// rows with a bad column are still read
+	if reasons := g.checkWarnings(base.ErrorList{&base.ParseError{Line: 2, Record: "SDN", Err: &ofac.ColumnError{Column: 1}}}); len(reasons) != 0 {
+		t.Errorf("unexpected reasons: %v", reasons)
+	}
+
+	g.validateFiles = false
+	if reasons := check(); len(reasons) != 0 {
+		t.Errorf("unexpected reasons: %v", reasons)
+	}
+}
+
+func TestGuardrails__rejectedRefresh(t *testing.T) {
+	dir := copyTestData(t)
+	defer os.RemoveAll(dir)
+
+	offline, err := newOfflineData(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := createTestDownloadRepository(t)
+	defer repo.close()
+
+	s := &searcher{
+		offline:      offline,
+		downloadRepo: repo,
+		guardrails:   &refreshGuardrails{maxDropPercent: map[string]float64{"": 20}, validateFiles: true},
+	}
+	stats, err := s.refreshData(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a truncated download ending on a row boundary only fails the drop check
+	bs, err := ioutil.ReadFile(filepath.Join(dir, "sdn.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.SplitAfter(string(bs), "\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, "sdn.csv"), []byte(strings.Join(rows[:200], "")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.refreshData(context.Background())
+	rejected, ok := err.(*refreshRejectedError)
+	if !ok || len(rejected.reasons) != 1 || !strings.HasPrefix(rejected.reasons[0], "SDNs: dropped") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(s.SDNs) != stats.SDNs {
+		t.Errorf("SDNs=%d, expected %d to be kept", len(s.SDNs), stats.SDNs)
+	}
+	if v := testutil.ToFloat64(dataRefreshRejected); v != 1 {
+		t.Errorf("data_refresh_rejected=%v", v)
+	}
+
+	downloads, err := repo.latestDownloads(5)
+	if err != nil || len(downloads) != 1 {
+		t.Fatalf("downloads=%#v err=%v", downloads, err)
+	}
+	if dl := downloads[0]; dl.Status != downloadRejected || dl.SDNs != 200 || len(dl.Reasons) != 1 || dl.Reasons[0] != rejected.reasons[0] {
+		t.Errorf("download=%#v", dl)
+	}
+
+	// a good refresh clears the alert
+	if err := ioutil.WriteFile(filepath.Join(dir, "sdn.csv"), bs, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.refreshData(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if v := testutil.ToFloat64(dataRefreshRejected); v != 0 {
+		t.Errorf("data_refresh_rejected=%v", v)
+	}
+}
@@ -6,18 +6,17 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/cardonator/ofac"
-	"github.com/moov-io/base/admin"
 	moovhttp "github.com/moov-io/base/http"
 	"github.com/moov-io/base/http/bind"
 
@@ -33,6 +32,9 @@ var (
 	flagLogFormat = flag.String("log.format", "", "Format for log lines (Options: json, plain")
 
 	ofacDataRefreshInterval = 12 * time.Hour
+
+	// shutdownTimeout is how long in-flight HTTP requests and background work are waited on when shutting down
+	shutdownTimeout = 30 * time.Second
 )
 
 func main() {
@@ -47,6 +49,15 @@ func main() {
 	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
 	logger = log.With(logger, "caller", log.DefaultCaller)
 
+	// Run 'migrate' and exit instead of starting our servers
+	if flag.Arg(0) == "migrate" {
+		if err := migrateCommand(logger, getDatabaseType(), flag.Args()[1:], os.Stdout); err != nil {
+			logger.Log("migrate", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	logger.Log("startup", fmt.Sprintf("Starting ofac server version %s", ofac.Version))
 
 	// Channel for errors
@@ -58,19 +69,34 @@ func main() {
 		errs <- fmt.Errorf("%s", <-c)
 	}()
 
-	// Setup SQLite database
-	if sqliteVersion, _, _ := sqlite3.Version(); sqliteVersion != "" {
-		logger.Log("main", fmt.Sprintf("sqlite version %s", sqliteVersion))
+	// Background loops (data refreshes, watch runs, webhook retries and rate limit pruning) stop picking up work once stop is closed,
+	// and work still running is only cancelled with ctx when it outlasts SHUTDOWN_TIMEOUT
+	shutdownTimeout = readWebhookDuration(os.Getenv("SHUTDOWN_TIMEOUT"), shutdownTimeout)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := make(chan struct{})
+	var background sync.WaitGroup
+	goBackground := func(f func()) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			f()
+		}()
 	}
-	db, err := createSqliteConnection(logger, getSqlitePath())
-	if err != nil {
-		logger.Log("main", err)
-		os.Exit(1)
+
+	// Setup database (sqlite, postgres or mysql)
+	databaseType := getDatabaseType()
+	if databaseType == sqliteDatabase {
+		if sqliteVersion, _, _ := sqlite3.Version(); sqliteVersion != "" {
+			logger.Log("main", fmt.Sprintf("sqlite version %s", sqliteVersion))
+		}
 	}
-	if err := migrate(logger, db); err != nil {
+	db, err := createDatabase(logger, databaseType)
+	if err != nil {
 		logger.Log("main", err)
 		os.Exit(1)
 	}
+	logger.Log("main", fmt.Sprintf("using %s database", databaseType))
 	defer func() {
 		if err := db.Close(); err != nil {
 			logger.Log("main", err)
@@ -88,44 +114,70 @@ func main() {
 	idleTimeout, _ := time.ParseDuration("60s")
 
 	serve := &http.Server{
-		Addr:    *httpAddr,
-		Handler: router,
-		TLSConfig: &tls.Config{
-			InsecureSkipVerify:       false,
-			PreferServerCipherSuites: true,
-			MinVersion:               tls.VersionTLS12,
-		},
+		Addr:         *httpAddr,
+		Handler:      router,
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writTimeout,
 		IdleTimeout:  idleTimeout,
 	}
-	shutdownServer := func() {
-		if err := serve.Shutdown(context.TODO()); err != nil {
-			logger.Log("shutdown", err)
+
+	// Read the certificate both servers are served with over TLS, reloading it when the files change
+	tlsFiles, err := readTLSFiles()
+	if err != nil {
+		logger.Log("main", err)
+		os.Exit(1)
+	}
+	var serverTLS *reloadingTLS
+	if tlsFiles != nil {
+		serverTLS, err = newReloadingTLS(logger, *tlsFiles)
+		if err != nil {
+			logger.Log("main", fmt.Sprintf("problem reading TLS certificate: %v", err))
+			os.Exit(1)
 		}
+		stopTLSReload := make(chan struct{})
+		defer close(stopTLSReload)
+		go serverTLS.watch(tlsReloadInterval, stopTLSReload)
 	}
 
 	// Start Admin server (with Prometheus metrics)
-	adminServer := admin.NewServer(*adminAddr)
+	adminServer := newAdminServer(*adminAddr)
 	go func() {
 		logger.Log("admin", fmt.Sprintf("listening on %s", adminServer.BindAddr()))
-		if err := adminServer.Listen(); err != nil {
+		if err := adminServer.Listen(serverTLS); err != nil {
 			err = fmt.Errorf("problem starting admin http: %v", err)
 			logger.Log("admin", err)
 			errs <- err
 		}
 	}()
-	defer adminServer.Shutdown()
 
 	// Setup download repository
 	downloadRepo := &sqliteDownloadRepository{db, logger}
 	defer downloadRepo.close()
 
+	// Setup our whitelist of false positives
+	whitelistRepo := &sqliteWhitelistRepository{db}
+	defer whitelistRepo.close()
+
 	// Start our searcher (and downloader)
 	searcher := &searcher{
-		logger: logger,
+		whitelistRepo: whitelistRepo,
+		downloadRepo:  downloadRepo,
+		logger:        logger,
+	}
+	if path := os.Getenv("INITIAL_DATA_DIRECTORY"); path != "" {
+		searcher.offline, err = newOfflineData(path)
+		if err != nil {
+			logger.Log("main", fmt.Sprintf("ERROR: problem reading INITIAL_DATA_DIRECTORY: %v", err))
+			os.Exit(1)
+		}
+		logger.Log("main", fmt.Sprintf("reading sanctions lists from %s instead of downloading them", path))
+	}
+	searcher.guardrails, err = readRefreshGuardrails()
+	if err != nil {
+		logger.Log("main", err)
+		os.Exit(1)
 	}
-	if stats, err := searcher.refreshData(); err != nil {
+	if stats, err := searcher.refreshData(ctx); err != nil {
 		logger.Log("main", fmt.Sprintf("ERROR: failed to download/parse initial sanctions lists data: %v", err))
 		os.Exit(1)
 	} else {
@@ -139,6 +191,8 @@ func main() {
 	defer watchRepo.close()
 	webhookRepo := &sqliteWebhookRepository{db}
 	defer webhookRepo.close()
+	runRepo := &sqliteWatchRunRepository{db}
+	defer runRepo.close()
 
 	// Setup company / customer repositories
 	companyRepo := &sqliteCompanyRepository{db}
@@ -146,35 +200,131 @@ func main() {
 	custRepo := &sqliteCustomerRepository{db}
 	defer custRepo.close()
 
+	// Setup repository of our own subjects and the cases from screening them
+	subjectRepo := &sqliteSubjectRepository{db}
+	defer subjectRepo.close()
+
+	// Setup Kafka, NATS and file notifiers which watches can use instead of webhooks
+	closeNotifiers, err := setupNotifiers(logger)
+	if err != nil {
+		logger.Log("main", err)
+		os.Exit(1)
+	}
+	defer closeNotifiers()
+
 	// Setup periodic download and re-search
 	updates := make(chan *downloadStats)
 	ofacDataRefreshInterval = getOFACRefreshInterval(logger, os.Getenv("OFAC_DATA_REFRESH"))
-	go searcher.periodicDataRefresh(ofacDataRefreshInterval, downloadRepo, updates)
-	go searcher.spawnResearching(logger, companyRepo, custRepo, watchRepo, webhookRepo, updates)
+	goBackground(func() {
+		searcher.periodicDataRefresh(ctx, stop, ofacDataRefreshInterval, downloadRepo, updates)
+	})
+	goBackground(func() {
+		searcher.spawnResearching(ctx, stop, logger, companyRepo, custRepo, watchRepo, webhookRepo, runRepo, subjectRepo, updates)
+	})
+	goBackground(func() {
+		spawnWebhookRetries(ctx, stop, logger, webhookRetryInterval, webhookRepo)
+	})
+	goBackground(func() {
+		spawnStatusExpiry(stop, logger, statusExpiryInterval, companyRepo, custRepo)
+	})
 
 	// Add manual OFAC data refresh endpoint
 	adminServer.AddHandler(manualRefreshPath, manualRefreshHandler(logger, searcher, downloadRepo))
 
+	// Add endpoint for uploading a bundle of sanctions lists
+	adminServer.AddHandler(manualUploadPath, manualUploadHandler(logger, searcher, downloadRepo))
+
+	// Add webhook outbox endpoints for listing and replaying failed deliveries
+	addWebhookDeliveryRoutes(logger, adminServer, webhookRepo)
+
+	// Add endpoints for the progress of watch re-search runs
+	addWatchRunRoutes(logger, adminServer, runRepo)
+
 	// Add searcher for HTTP routes
 	addCompanyRoutes(logger, router, searcher, companyRepo, watchRepo)
 	addCustomerRoutes(logger, router, searcher, custRepo, watchRepo)
+	addWatchRoutes(logger, router, watchRepo)
 	addSDNRoutes(logger, router, searcher)
 	addSearchRoutes(logger, router, searcher)
 	addDownloadRoutes(logger, router, downloadRepo)
+	addSubjectRoutes(logger, router, searcher, subjectRepo)
+	addCaseRoutes(logger, router, subjectRepo)
+	addWhitelistRoutes(logger, router, searcher, whitelistRepo)
+
+	// Require API keys, JWTs or mTLS client certificates on the business HTTP routes
+	authenticators, err := setupAuthenticators(logger)
+	if err != nil {
+		logger.Log("main", err)
+		os.Exit(1)
+	}
+	if len(authenticators) > 0 {
+		router.Use(authMiddleware(logger, authenticators))
+	}
+
+	// Limit how often each client calls the business HTTP routes, after they're authenticated
+	quotaRepo := &sqliteQuotaRepository{db}
+	defer quotaRepo.close()
+	rateLimits, err := setupRateLimits(logger, quotaRepo, stop, goBackground)
+	if err != nil {
+		logger.Log("main", err)
+		os.Exit(1)
+	}
+	if rateLimits != nil {
+		router.Use(rateLimits)
+	}
 
 	// Start business logic HTTP server
 	go func() {
-		logger.Log("transport", "HTTP", "addr", *httpAddr)
-		errs <- serve.ListenAndServe()
-		// TODO(adam): support TLS
-		// func (srv *Server) ListenAndServeTLS(certFile, keyFile string) error
+		if serverTLS != nil {
+			logger.Log("transport", "HTTPS", "addr", *httpAddr)
+		} else {
+			logger.Log("transport", "HTTP", "addr", *httpAddr)
+		}
+		errs <- listen(serve, serverTLS)
 	}()
 
 	// Block/Wait for an error
 	if err := <-errs; err != nil {
-		shutdownServer()
 		logger.Log("exit", err)
 	}
+	shutdown(logger, shutdownTimeout, stop, cancel, &background, serve.Shutdown, adminServer.Shutdown)
+}
+
+// shutdown closes stop so background loops don't pick up new work and stops our HTTP servers from accepting
+// requests, then waits up to timeout for in-flight requests and background work to finish. Only when the
+// timeout expires is cancel called to interrupt what's still running; interrupted watch runs are then resumed
+// on the next start and cancelled webhook deliveries are retried from the outbox.
+func shutdown(logger log.Logger, timeout time.Duration, stop chan struct{}, cancel context.CancelFunc, background *sync.WaitGroup, servers ...func(context.Context) error) {
+	ctx, cancelTimeout := context.WithTimeout(context.Background(), timeout)
+	defer cancelTimeout()
+
+	logger.Log("shutdown", fmt.Sprintf("draining HTTP requests and background work for up to %v", timeout))
+	close(stop)
+
+	var wg sync.WaitGroup
+	for i := range servers {
+		wg.Add(1)
+		go func(shutdown func(context.Context) error) {
+			defer wg.Done()
+			if err := shutdown(ctx); err != nil {
+				logger.Log("shutdown", err)
+			}
+		}(servers[i])
+	}
+	wg.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		logger.Log("shutdown", "finished draining")
+	case <-ctx.Done():
+		logger.Log("shutdown", "timed out waiting for background work to finish, cancelling it")
+		cancel()
+	}
 }
 
 func addPingRoute(r *mux.Router) {
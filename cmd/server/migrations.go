@@ -0,0 +1,253 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// migration is one numbered change to our schema. up applies the change and down reverts it.
+//
+// Applied migrations are recorded in schema_migrations, so a migration must never be edited once
+// released. Change the schema by appending a migration (with the next version) to each database's list.
+type migration struct {
+	version     int
+	description string
+	up          []string
+	down        []string
+}
+
+// schemaMigrationsTables creates the table which records applied migrations, for each database
+var schemaMigrationsTables = map[string]string{
+	sqliteDatabase:   `create table if not exists schema_migrations(version integer primary key, description, applied_at datetime);`,
+	postgresDatabase: `create table if not exists schema_migrations(version integer primary key, description text, applied_at timestamptz);`,
+	mysqlDatabase:    `create table if not exists schema_migrations(version integer primary key, description text, applied_at datetime(6));`,
+}
+
+// schemaTables are the tables created by our first migration
+var schemaTables = []string{
+	"customer_name_watches", "customer_status", "customer_watches",
+	"company_name_watches", "company_status", "company_watches",
+	"name_watch_options", "name_watch_hits",
+	"watch_signing_secrets", "watch_notifiers",
+	"ofac_download_stats", "webhook_stats", "watch_runs", "watch_notifications", "webhook_deliveries",
+}
+
+// dropTables reverts our first migration
+func dropTables() []string {
+	var out []string
+	for i := len(schemaTables) - 1; i >= 0; i-- {
+		out = append(out, fmt.Sprintf(`drop table if exists %s;`, schemaTables[i]))
+	}
+	return out
+}
+
+// schemaIndexes are the indexed columns of each table, by table name
+var schemaIndexes = []struct {
+	table   string
+	columns []string
+}{
+	{"company_status", []string{"company_id", "created_at", "deleted_at"}},
+	{"company_watches", []string{"company_id", "created_at", "deleted_at"}},
+	{"company_name_watches", []string{"created_at", "deleted_at"}},
+	{"customer_status", []string{"customer_id", "created_at", "deleted_at"}},
+	{"customer_watches", []string{"customer_id", "created_at", "deleted_at"}},
+	{"customer_name_watches", []string{"created_at", "deleted_at"}},
+}
+
+func indexName(table, column string) string {
+	return fmt.Sprintf("idx_%s_%s", table, column)
+}
+
+func createIndexes() []string {
+	var out []string
+	for _, idx := range schemaIndexes {
+		for _, column := range idx.columns {
+			out = append(out, fmt.Sprintf(`create index %s on %s (%s);`, indexName(idx.table, column), idx.table, column))
+		}
+	}
+	return out
+}
+
+// dropIndexes reverts createIndexes, MySQL names the table an index is dropped from.
+func dropIndexes(databaseType string) []string {
+	var out []string
+	for _, idx := range schemaIndexes {
+		for _, column := range idx.columns {
+			if databaseType == mysqlDatabase {
+				out = append(out, fmt.Sprintf(`drop index %s on %s;`, indexName(idx.table, column), idx.table))
+			} else {
+				out = append(out, fmt.Sprintf(`drop index if exists %s;`, indexName(idx.table, column)))
+			}
+		}
+	}
+	return out
+}
+
+// schemaVersion returns the newest migration applied to db, or zero if none have been.
+func schemaVersion(db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow(`select max(version) from schema_migrations;`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("problem reading schema version: %v", err)
+	}
+	return int(version.Int64), nil
+}
+
+func ensureSchemaMigrations(databaseType string, db *sql.DB) error {
+	query, exists := schemaMigrationsTables[databaseType]
+	if !exists {
+		return fmt.Errorf("no schema_migrations table for %s", databaseType)
+	}
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("problem creating schema_migrations: %v", err)
+	}
+	return nil
+}
+
+// migrateUp applies each migration newer than the database's version, up to and including target.
+// A target of zero applies every migration.
+//
+// Each migration is applied in a transaction along with its schema_migrations row. MySQL commits DDL
+// statements as they run, so a failed MySQL migration may need to be cleaned up by hand.
+func migrateUp(logger log.Logger, databaseType string, db *sql.DB, migrations []migration, target int) error {
+	if err := ensureSchemaMigrations(databaseType, db); err != nil {
+		return err
+	}
+	current, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.version <= current || (target > 0 && m.version > target) {
+			continue
+		}
+		err := runMigration(db, m.up, `insert into schema_migrations (version, description, applied_at) values (?, ?, ?);`, m.version, m.description, time.Now())
+		if err != nil {
+			return fmt.Errorf("migration #%d (%s) had problem: %v", m.version, m.description, err)
+		}
+		if logger != nil {
+			logger.Log(databaseType, fmt.Sprintf("applied migration #%d (%s)", m.version, m.description))
+		}
+	}
+	return nil
+}
+
+// migrateDown reverts each applied migration newer than target, newest first.
+func migrateDown(logger log.Logger, databaseType string, db *sql.DB, migrations []migration, target int) error {
+	if err := ensureSchemaMigrations(databaseType, db); err != nil {
+		return err
+	}
+	current, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.version > current || m.version <= target {
+			continue
+		}
+		if err := runMigration(db, m.down, `delete from schema_migrations where version = ?;`, m.version); err != nil {
+			return fmt.Errorf("reverting migration #%d (%s) had problem: %v", m.version, m.description, err)
+		}
+		if logger != nil {
+			logger.Log(databaseType, fmt.Sprintf("reverted migration #%d (%s)", m.version, m.description))
+		}
+	}
+	return nil
+}
+
+func runMigration(db *sql.DB, statements []string, record string, args ...interface{}) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for i := range statements {
+		if _, err := tx.Exec(statements[i]); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrateCommand implements the migrate subcommand:
+//
+//	ofac migrate [up [version]]  apply every migration, or those up to version
+//	ofac migrate down [version]  revert the newest migration, or every migration after version
+//	ofac migrate status          print each migration and if it has been applied
+func migrateCommand(logger log.Logger, databaseType string, args []string, out io.Writer) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	var target int
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid migration version %q", args[1])
+		}
+		target = n
+	}
+	if len(args) > 2 {
+		return errors.New("usage: migrate [up [version] | down [version] | status]")
+	}
+
+	db, migrations, err := openDatabase(logger, databaseType)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch action {
+	case "up":
+		return migrateUp(logger, databaseType, db, migrations, target)
+
+	case "down":
+		if len(args) < 2 {
+			// revert only the newest migration
+			if err := ensureSchemaMigrations(databaseType, db); err != nil {
+				return err
+			}
+			current, err := schemaVersion(db)
+			if err != nil {
+				return err
+			}
+			if target = current - 1; target < 0 {
+				target = 0
+			}
+		}
+		return migrateDown(logger, databaseType, db, migrations, target)
+
+	case "status":
+		if err := ensureSchemaMigrations(databaseType, db); err != nil {
+			return err
+		}
+		current, err := schemaVersion(db)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s database is at version %d\n", databaseType, current)
+		for _, m := range migrations {
+			state := "pending"
+			if m.version <= current {
+				state = "applied"
+			}
+			fmt.Fprintf(out, "  #%d %s: %s\n", m.version, m.description, state)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown migrate action %q, expected up, down or status", action)
+}
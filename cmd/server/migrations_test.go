@@ -0,0 +1,273 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMigrations__versions(t *testing.T) {
+	all := map[string][]migration{
+		sqliteDatabase:   sqliteMigrations,
+		postgresDatabase: postgresMigrations,
+		mysqlDatabase:    mysqlMigrations,
+	}
+	for databaseType, migrations := range all {
+		if len(migrations) != len(sqliteMigrations) {
+			t.Errorf("%s has %d migrations, sqlite has %d", databaseType, len(migrations), len(sqliteMigrations))
+		}
+		for i, m := range migrations {
+			if m.version != i+1 {
+				t.Errorf("%s migration #%d has version %d", databaseType, i+1, m.version)
+			}
+			if m.description != sqliteMigrations[i].description {
+				t.Errorf("%s migration #%d: %q", databaseType, m.version, m.description)
+			}
+			if len(m.up) == 0 || len(m.down) == 0 {
+				t.Errorf("%s migration #%d is missing up or down steps", databaseType, m.version)
+			}
+		}
+		// each table created by the first migration is dropped when it's reverted
+		for _, table := range schemaTables {
+			if !strings.Contains(strings.Join(migrations[0].up, "\n"), "create table if not exists "+table+"(") {
+				t.Errorf("%s doesn't create %s", databaseType, table)
+			}
+		}
+		if len(migrations[0].up) != len(schemaTables) {
+			t.Errorf("%s creates %d tables, expected %d", databaseType, len(migrations[0].up), len(schemaTables))
+		}
+	}
+}
+
+// TestMigrations__dialects checks the SQL generated for each database without connecting to Postgres or
+// MySQL: every migration changes the same tables and indexes as sqlite's, with that database's column types.
+func TestMigrations__dialects(t *testing.T) {
+	objects := regexp.MustCompile(`(create table if not exists|drop table if exists|alter table|create index|create unique index|drop index if exists|drop index) (\w+)`)
+	changes := func(steps []string) []string {
+		var out []string
+		for _, step := range steps {
+			for _, m := range objects.FindAllStringSubmatch(strings.ToLower(step), -1) {
+				out = append(out, m[1]+" "+m[2])
+			}
+		}
+		sort.Strings(out)
+		return out
+	}
+	// column types and statements which belong to another database
+	foreign := map[string][]string{
+		sqliteDatabase:   {"timestamptz", "bytea", "double precision", "datetime(6)"},
+		postgresDatabase: {" datetime", " blob", "insert or ", "autoincrement"},
+		mysqlDatabase:    {"timestamptz", "bytea", "double precision", "text primary key", "insert or ", "autoincrement"},
+	}
+	all := map[string][]migration{
+		sqliteDatabase:   sqliteMigrations,
+		postgresDatabase: postgresMigrations,
+		mysqlDatabase:    mysqlMigrations,
+	}
+	for databaseType, migrations := range all {
+		for i, m := range migrations {
+			if i >= len(sqliteMigrations) {
+				break
+			}
+			if got, expected := changes(m.up), changes(sqliteMigrations[i].up); strings.Join(got, ",") != strings.Join(expected, ",") {
+				t.Errorf("%s migration #%d changes %v, sqlite changes %v", databaseType, m.version, got, expected)
+			}
+			for _, step := range append(append([]string{}, m.up...), m.down...) {
+				for _, bad := range foreign[databaseType] {
+					if strings.Contains(strings.ToLower(step), bad) {
+						t.Errorf("%s migration #%d uses %q: %s", databaseType, m.version, strings.TrimSpace(bad), step)
+					}
+				}
+				if !strings.HasSuffix(strings.TrimSpace(step), ";") {
+					t.Errorf("%s migration #%d step isn't terminated: %s", databaseType, m.version, step)
+				}
+			}
+		}
+	}
+}
+
+func TestMigrations__upAndDown(t *testing.T) {
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+
+	latest := sqliteMigrations[len(sqliteMigrations)-1].version
+	if v, err := schemaVersion(db.db); err != nil || v != latest {
+		t.Fatalf("version=%d err=%v", v, err)
+	}
+	if n := countSqliteIndexes(t, db.db); n != latestSqliteIndexes() {
+		t.Errorf("found %d indexes", n)
+	}
+
+	// applying migrations again is a no-op
+	if err := migrate(nil, db.db); err != nil {
+		t.Fatal(err)
+	}
+
+	// revert the status hashes (and override approvals), keeping each status
+	companyRepo := &sqliteCompanyRepository{db.db}
+	if err := companyRepo.upsertCompanyStatus("foo", &CompanyStatus{UserID: "bar", Status: CompanyUnsafe, CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateDown(nil, sqliteDatabase, db.db, sqliteMigrations, 2); err != nil {
+		t.Fatal(err)
+	}
+	if n := countSqliteIndexes(t, db.db); n != len(createIndexes()) {
+		t.Errorf("found %d indexes", n)
+	}
+	var userID string
+	if err := db.db.QueryRow(`select user_id from company_status where company_id = 'foo';`).Scan(&userID); err != nil || userID != "bar" {
+		t.Errorf("userID=%s err=%v", userID, err)
+	}
+
+	// revert the indexes
+	if err := migrateDown(nil, sqliteDatabase, db.db, sqliteMigrations, 1); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := schemaVersion(db.db); v != 1 {
+		t.Errorf("version=%d", v)
+	}
+	if n := countSqliteIndexes(t, db.db); n != 0 {
+		t.Errorf("found %d indexes", n)
+	}
+
+	// revert everything
+	if err := migrateDown(nil, sqliteDatabase, db.db, sqliteMigrations, 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := schemaVersion(db.db); v != 0 {
+		t.Errorf("version=%d", v)
+	}
+	var tables int
+	db.db.QueryRow(`select count(*) from sqlite_master where type = 'table' and name != 'schema_migrations'`).Scan(&tables)
+	if tables != 0 {
+		t.Errorf("found %d tables", tables)
+	}
+
+	// and back up to a specific version
+	if err := migrateUp(nil, sqliteDatabase, db.db, sqliteMigrations, 1); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := schemaVersion(db.db); v != 1 {
+		t.Errorf("version=%d", v)
+	}
+}
+
+func TestMigrations__existingDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ofac-migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := createSqliteConnection(nil, filepath.Join(dir, "ofac.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// databases created before versioned migrations have our tables, but no schema_migrations
+	for _, query := range sqliteMigrations[0].up {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(`insert into company_status (company_id, user_id, status) values ('foo', 'bar', 'unsafe');`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrate(nil, db); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	db.QueryRow(`select count(*) from company_status;`).Scan(&n)
+	if n != 1 {
+		t.Errorf("found %d statuses", n)
+	}
+	if n := countSqliteIndexes(t, db); n != latestSqliteIndexes() {
+		t.Errorf("found %d indexes", n)
+	}
+}
+
+func TestMigrations__command(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ofac-migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Setenv("SQLITE_DB_PATH", filepath.Join(dir, "ofac.db"))
+	defer os.Unsetenv("SQLITE_DB_PATH")
+
+	status := func() string {
+		var buf bytes.Buffer
+		if err := migrateCommand(nil, sqliteDatabase, []string{"status"}, &buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	if s := status(); !strings.Contains(s, "at version 0") || strings.Contains(s, "applied") {
+		t.Errorf("unexpected status: %s", s)
+	}
+	if err := migrateCommand(nil, sqliteDatabase, []string{"up", "1"}, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if s := status(); !strings.Contains(s, "#1 create tables: applied") || !strings.Contains(s, "pending") {
+		t.Errorf("unexpected status: %s", s)
+	}
+	if err := migrateCommand(nil, sqliteDatabase, nil, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if s := status(); strings.Contains(s, "pending") {
+		t.Errorf("unexpected status: %s", s)
+	}
+
+	// down without a version reverts one migration
+	if err := migrateCommand(nil, sqliteDatabase, []string{"down"}, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if s := status(); !strings.Contains(s, fmt.Sprintf("at version %d", len(sqliteMigrations)-1)) {
+		t.Errorf("unexpected status: %s", s)
+	}
+
+	// bad input
+	for _, args := range [][]string{{"sideways"}, {"up", "abc"}, {"down", "-1"}, {"up", "1", "2"}} {
+		if err := migrateCommand(nil, sqliteDatabase, args, ioutil.Discard); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+	if err := migrateCommand(nil, "oracle", nil, ioutil.Discard); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func countSqliteIndexes(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`select count(*) from sqlite_master where type = 'index' and name like 'idx_%'`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// latestSqliteIndexes is how many indexes every migration creates: our column indexes, the status
+// chains, the cases indexes, the case workflow's created_at and comments indexes and the whitelist's
+// entity_id index.
+func latestSqliteIndexes() int {
+	return len(createIndexes()) + 2 + len(createCaseIndexes()) + 2 + 1
+}
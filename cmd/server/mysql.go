@@ -0,0 +1,149 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	// mysqlMigrations mirror our sqlite migrations with MySQL column types
+	mysqlMigrations = []migration{
+		{
+			version:     1,
+			description: "create tables",
+			up: []string{
+				// Customer tables
+				`create table if not exists customer_name_watches(id varchar(64) primary key, name text, webhook text, auth_token text, created_at datetime(6), deleted_at datetime(6));`,
+				`create table if not exists customer_status(customer_id varchar(64), user_id text, note text, status varchar(32), created_at datetime(6), deleted_at datetime(6));`,
+				`create table if not exists customer_watches(id varchar(64) primary key, customer_id varchar(64), webhook text, auth_token text, created_at datetime(6), deleted_at datetime(6));`,
+
+				// Company status
+				`create table if not exists company_name_watches(id varchar(64) primary key, name text, webhook text, auth_token text, created_at datetime(6), deleted_at datetime(6));`,
+				`create table if not exists company_status(company_id varchar(64), user_id text, note text, status varchar(32), created_at datetime(6), deleted_at datetime(6));`,
+				`create table if not exists company_watches(id varchar(64) primary key, company_id varchar(64), webhook text, auth_token text, created_at datetime(6), deleted_at datetime(6));`,
+
+				// Name watch match criteria
+				`create table if not exists name_watch_options(watch_id varchar(64) primary key, min_match double, lists text, created_at datetime(6));`,
+				`create table if not exists name_watch_hits(watch_id varchar(64), hit_key text, created_at datetime(6));`,
+
+				// Secrets used to sign webhook deliveries
+				`create table if not exists watch_signing_secrets(watch_id varchar(64) primary key, secret text, created_at datetime(6));`,
+
+				// Notifier (webhook, kafka, nats or file) picked by a watch
+				`create table if not exists watch_notifiers(watch_id varchar(64) primary key, notifier varchar(32), created_at datetime(6));`,
+
+				// OFAC download stats
+				`create table if not exists ofac_download_stats(downloaded_at datetime(6), sdns integer, alt_names integer, addresses integer, denied_persons integer, sectoral_sanctions integer, bis_entities integer);`,
+
+				// Webhook stats
+				`create table if not exists webhook_stats(watch_id varchar(64), attempted_at datetime(6), status integer);`,
+
+				// Progress of re-searching every watch after a data refresh
+				`create table if not exists watch_runs(run_id varchar(64) primary key, downloaded_at datetime(6), status varchar(32), started integer, processed integer, failed integer, started_at datetime(6), updated_at datetime(6), finished_at datetime(6));`,
+
+				// Last delivered notification per watch
+				`create table if not exists watch_notifications(watch_id varchar(64) primary key, entity_id varchar(64), payload_hash varchar(64), payload longblob, change_type varchar(32), notified_at datetime(6));`,
+
+				// Webhook outbox, holding deliveries until they succeed or are dead-lettered
+				`create table if not exists webhook_deliveries(delivery_id varchar(64) primary key, watch_id varchar(64), webhook text, auth_token text, body longblob, status varchar(32), attempts integer, last_status integer, last_error text, next_attempt_at datetime(6), created_at datetime(6), updated_at datetime(6));`,
+			},
+			down: dropTables(),
+		},
+		{
+			version:     2,
+			description: "index company_id, customer_id, created_at and deleted_at",
+			up:          createIndexes(),
+			down:        dropIndexes(mysqlDatabase),
+		},
+		{
+			version:     3,
+			description: "hash chain company and customer statuses",
+			up:          addStatusHashes(),
+			down:        dropStatusHashes(mysqlDatabase),
+		},
+		{
+			version:     4,
+			description: "approve and expire status overrides",
+			up:          addStatusOverrides(mysqlDatabase),
+			down:        dropStatusOverrides(mysqlDatabase),
+		},
+		{
+			version:     5,
+			description: "create subjects and cases",
+			up: append([]string{
+				`create table if not exists subjects(subject_id varchar(64) primary key, name text, dob varchar(10), addresses text, created_at datetime(6), updated_at datetime(6), screened_at datetime(6));`,
+				`create table if not exists cases(case_id varchar(64) primary key, subject_id varchar(64), list varchar(16), entity_id varchar(255), name text, match_score double, status varchar(32), created_at datetime(6), updated_at datetime(6));`,
+			}, createCaseIndexes()...),
+			down: dropSubjectTables(),
+		},
+		{
+			version:     6,
+			description: "assign, comment on and reopen cases",
+			up:          addCaseWorkflow(mysqlDatabase),
+			down:        dropCaseWorkflow(mysqlDatabase),
+		},
+		{
+			version:     7,
+			description: "whitelist false positives",
+			up:          addWhitelistTable(mysqlDatabase),
+			down:        dropWhitelistTable(),
+		},
+		{
+			version:     8,
+			description: "count daily requests for quotas",
+			up:          addQuotaTable(),
+			down:        dropQuotaTable(),
+		},
+		{
+			version:     9,
+			description: "record download sources and unchanged refreshes",
+			up:          addDownloadSources(mysqlDatabase),
+			down:        dropDownloadSources(mysqlDatabase),
+		},
+		{
+			version:     10,
+			description: "record why refreshes were rejected",
+			up:          addDownloadReasons(),
+			down:        dropDownloadReasons(mysqlDatabase),
+		},
+	}
+)
+
+// createMySQLConnection opens a MySQL database from a go-sql-driver/mysql DSN (e.g. user:pass@tcp(localhost:3306)/ofac).
+// Timestamps are always parsed into time.Time values and stored in UTC unless the DSN sets loc.
+func createMySQLConnection(logger log.Logger, dsn string) (*sql.DB, error) {
+	if dsn == "" {
+		return nil, errors.New("no MYSQL_DSN provided")
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("problem with mysql DSN: %v", err)
+	}
+	cfg.ParseTime = true
+	if cfg.Loc == nil {
+		cfg.Loc = time.UTC
+	}
+
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, fmt.Errorf("problem opening mysql: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		err = fmt.Errorf("problem with Ping against mysql: %v", err)
+		if logger != nil {
+			logger.Log("mysql", err)
+		}
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Notifier sends a watch's notification (a delivery from the outbox) to where the watch's owner reads it.
+//
+// Notify returns an HTTP style status code so outcomes from every transport are recorded the same way:
+// a 2xx status when the notification was accepted and 0 when it wasn't. Notifiers should give up once ctx is done.
+type Notifier interface {
+	Notify(ctx context.Context, d *webhookDelivery) (int, error)
+}
+
+// Names of the notifiers a watch can use
+const (
+	webhookNotifierName = "webhook"
+	kafkaNotifierName   = "kafka"
+	natsNotifierName    = "nats"
+	fileNotifierName    = "file"
+)
+
+var (
+	// defaultNotifier is used by watches which didn't pick a notifier
+	defaultNotifier = webhookNotifierName
+
+	notifiersMu sync.RWMutex
+	notifiers   = map[string]Notifier{
+		webhookNotifierName: webhookNotifier{},
+	}
+
+	errUnknownNotifier = fmt.Errorf("unknown notifier, expected one of: %s, %s, %s, %s", webhookNotifierName, kafkaNotifierName, natsNotifierName, fileNotifierName)
+)
+
+// registerNotifier makes a notifier available to watches under name
+func registerNotifier(name string, n Notifier) {
+	notifiersMu.Lock()
+	defer notifiersMu.Unlock()
+	notifiers[name] = n
+}
+
+// findNotifier returns the notifier registered under name, or the default notifier if name is empty.
+func findNotifier(name string) (Notifier, error) {
+	if name == "" {
+		name = defaultNotifier
+	}
+	notifiersMu.RLock()
+	defer notifiersMu.RUnlock()
+
+	if n, exists := notifiers[name]; exists {
+		return n, nil
+	}
+	switch name {
+	case webhookNotifierName, kafkaNotifierName, natsNotifierName, fileNotifierName:
+		return nil, fmt.Errorf("%s notifier isn't configured", name)
+	}
+	return nil, errUnknownNotifier
+}
+
+// notify sends d with its watch's notifier
+func notify(ctx context.Context, d *webhookDelivery) (int, error) {
+	n, err := findNotifier(d.Notifier)
+	if err != nil {
+		return 0, err
+	}
+	return n.Notify(ctx, d)
+}
+
+// setupNotifiers registers the Kafka, NATS and file notifiers which are configured from environmental
+// variables and sets the default notifier from WATCH_NOTIFIER. The returned function closes every notifier.
+func setupNotifiers(logger log.Logger) (func(), error) {
+	var closers []func() error
+	closeAll := func() {
+		for i := range closers {
+			if err := closers[i](); err != nil && logger != nil {
+				logger.Log("notifier", fmt.Sprintf("problem closing notifier: %v", err))
+			}
+		}
+	}
+
+	if brokers := os.Getenv("KAFKA_BROKERS"); brokers != "" {
+		n, err := newKafkaNotifier(strings.Split(brokers, ","), os.Getenv("KAFKA_TOPIC"))
+		if err != nil {
+			return closeAll, fmt.Errorf("problem setting up kafka notifier: %v", err)
+		}
+		registerNotifier(kafkaNotifierName, n)
+		closers = append(closers, n.Close)
+		logger.Log("notifier", fmt.Sprintf("sending kafka notifications to topic %s", n.topic))
+	}
+	if url := os.Getenv("NATS_URL"); url != "" {
+		n, err := newNATSNotifier(url, os.Getenv("NATS_SUBJECT"))
+		if err != nil {
+			return closeAll, fmt.Errorf("problem setting up nats notifier: %v", err)
+		}
+		registerNotifier(natsNotifierName, n)
+		closers = append(closers, n.Close)
+		logger.Log("notifier", fmt.Sprintf("sending nats notifications to subject %s", n.subject))
+	}
+	if path := os.Getenv("WATCH_EVENTS_FILE"); path != "" {
+		n, err := newFileNotifier(path)
+		if err != nil {
+			return closeAll, fmt.Errorf("problem setting up file notifier: %v", err)
+		}
+		registerNotifier(fileNotifierName, n)
+		closers = append(closers, n.Close)
+		logger.Log("notifier", fmt.Sprintf("appending notifications to %s", path))
+	}
+
+	if name := os.Getenv("WATCH_NOTIFIER"); name != "" {
+		if _, err := findNotifier(name); err != nil {
+			return closeAll, fmt.Errorf("WATCH_NOTIFIER: %v", err)
+		}
+		defaultNotifier = name
+	}
+	return closeAll, nil
+}
+
+// webhookNotifier makes an HTTP POST to the watch's webhook, see callWebhook.
+type webhookNotifier struct{}
+
+func (webhookNotifier) Notify(ctx context.Context, d *webhookDelivery) (int, error) {
+	return callWebhook(ctx, d)
+}
+
+// watchEvent is the message written by the Kafka, NATS and file notifiers. It carries the same delivery ID,
+// timestamp and signature as the headers sent with webhooks.
+type watchEvent struct {
+	DeliveryID string          `json:"deliveryID"`
+	WatchID    string          `json:"watchID"`
+	Timestamp  string          `json:"timestamp"`
+	Signature  string          `json:"signature,omitempty"`
+	Body       json.RawMessage `json:"body"`
+}
+
+func encodeWatchEvent(d *webhookDelivery) ([]byte, error) {
+	event := watchEvent{
+		DeliveryID: d.ID,
+		WatchID:    d.WatchID,
+		Timestamp:  strconv.FormatInt(time.Now().Unix(), 10),
+		Body:       d.Body,
+	}
+	if d.signingSecret != "" {
+		event.Signature = signWebhook(d.signingSecret, event.Timestamp, d.Body)
+	}
+	bs, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("problem encoding event for watch %s: %v", d.WatchID, err)
+	}
+	return bs, nil
+}
+
+// fileNotifier appends each notification as one line of JSON to a file.
+type fileNotifier struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func newFileNotifier(path string) (*fileNotifier, error) {
+	fd, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileNotifier{file: fd}, nil
+}
+
+func (n *fileNotifier) Notify(ctx context.Context, d *webhookDelivery) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	bs, err := encodeWatchEvent(d)
+	if err != nil {
+		return 0, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.file == nil {
+		return 0, errors.New("file notifier is closed")
+	}
+	// Write the event and newline at once so a line is never split between events
+	if _, err := n.file.Write(append(bs, '\n')); err != nil {
+		return 0, fmt.Errorf("problem writing event for watch %s: %v", d.WatchID, err)
+	}
+	if err := n.file.Sync(); err != nil {
+		return 0, fmt.Errorf("problem syncing event for watch %s: %v", d.WatchID, err)
+	}
+	return http.StatusOK, nil
+}
+
+func (n *fileNotifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.file == nil {
+		return nil
+	}
+	err := n.file.Close()
+	n.file = nil
+	return err
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+var defaultKafkaTopic = "ofac.watch.events"
+
+// kafkaNotifier produces each notification to a Kafka topic. Messages are keyed by watch ID so
+// every notification for a watch lands on the same partition, in order.
+type kafkaNotifier struct {
+	topic    string
+	producer sarama.SyncProducer
+}
+
+func newKafkaNotifier(brokers []string, topic string) (*kafkaNotifier, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("no kafka brokers")
+	}
+	if topic == "" {
+		topic = defaultKafkaTopic
+	}
+	cfg := sarama.NewConfig()
+	cfg.ClientID = "ofac"
+	cfg.Producer.RequiredAcks = sarama.WaitForAll
+	cfg.Producer.Return.Successes = true // required by SyncProducer
+	cfg.Producer.Timeout = 10 * time.Second
+	cfg.Producer.Retry.Max = 3
+
+	producer, err := sarama.NewSyncProducer(brokers, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaNotifier{
+		topic:    topic,
+		producer: producer,
+	}, nil
+}
+
+// Notify sends d to Kafka, which can't be cancelled once it's started so ctx is only checked beforehand.
+func (n *kafkaNotifier) Notify(ctx context.Context, d *webhookDelivery) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	bs, err := encodeWatchEvent(d)
+	if err != nil {
+		return 0, err
+	}
+	_, _, err = n.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: n.topic,
+		Key:   sarama.StringEncoder(d.WatchID),
+		Value: sarama.ByteEncoder(bs),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("kafka problem with watch %s: %v", d.WatchID, err)
+	}
+	return http.StatusOK, nil
+}
+
+func (n *kafkaNotifier) Close() error {
+	return n.producer.Close()
+}
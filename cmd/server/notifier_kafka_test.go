@@ -0,0 +1,88 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/moov-io/base"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+)
+
+func TestKafkaNotifier(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	n := &kafkaNotifier{topic: "events", producer: producer}
+
+	d := newWebhookDelivery(watch{id: base.ID(), signingSecret: "secret"}, bytes.NewBufferString(`{"id":"306"}`))
+	producer.ExpectSendMessageWithCheckerFunctionAndSucceed(func(value []byte) error {
+		var event watchEvent
+		if err := json.Unmarshal(value, &event); err != nil {
+			return err
+		}
+		if event.DeliveryID != d.ID || event.WatchID != d.WatchID || string(event.Body) != `{"id":"306"}` {
+			return fmt.Errorf("unexpected event: %#v", event)
+		}
+		if event.Signature != signWebhook("secret", event.Timestamp, event.Body) {
+			return errors.New("bad signature")
+		}
+		return nil
+	})
+	if status, err := n.Notify(context.Background(), d); status != http.StatusOK || err != nil {
+		t.Errorf("status=%d err=%v", status, err)
+	}
+
+	producer.ExpectSendMessageAndFail(sarama.ErrNotEnoughReplicas)
+	if status, err := n.Notify(context.Background(), d); status != 0 || err == nil {
+		t.Errorf("status=%d err=%v", status, err)
+	}
+
+	if err := n.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKafkaNotifier__broker(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(defaultKafkaTopic, 0, broker.BrokerID()),
+		"ProduceRequest": sarama.NewMockProduceResponse(t),
+	})
+
+	n, err := newKafkaNotifier([]string{broker.Addr()}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	if n.topic != defaultKafkaTopic {
+		t.Errorf("topic=%s", n.topic)
+	}
+	d := newWebhookDelivery(watch{id: base.ID()}, bytes.NewBufferString(`{"id":"306"}`))
+	if status, err := n.Notify(context.Background(), d); status != http.StatusOK || err != nil {
+		t.Errorf("status=%d err=%v", status, err)
+	}
+
+	var produced int
+	for _, rr := range broker.History() {
+		if _, ok := rr.Request.(*sarama.ProduceRequest); ok {
+			produced++
+		}
+	}
+	if produced != 1 {
+		t.Errorf("got %d produce requests", produced)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+var defaultNATSSubject = "ofac.watch.events"
+
+// natsNotifier publishes each notification to a NATS subject.
+type natsNotifier struct {
+	subject string
+	conn    *nats.Conn
+}
+
+func newNATSNotifier(url string, subject string) (*natsNotifier, error) {
+	if subject == "" {
+		subject = defaultNATSSubject
+	}
+	conn, err := nats.Connect(url, nats.Name("ofac"), nats.Timeout(10*time.Second), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	return &natsNotifier{
+		subject: subject,
+		conn:    conn,
+	}, nil
+}
+
+func (n *natsNotifier) Notify(ctx context.Context, d *webhookDelivery) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	bs, err := encodeWatchEvent(d)
+	if err != nil {
+		return 0, err
+	}
+	if err := n.conn.Publish(n.subject, bs); err != nil {
+		return 0, fmt.Errorf("nats problem with watch %s: %v", d.WatchID, err)
+	}
+	// Flush so the notification has reached the server before it's marked as delivered
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := n.conn.FlushWithContext(ctx); err != nil {
+		return 0, fmt.Errorf("nats problem with watch %s: %v", d.WatchID, err)
+	}
+	return http.StatusOK, nil
+}
+
+func (n *natsNotifier) Close() error {
+	n.conn.Close()
+	return nil
+}
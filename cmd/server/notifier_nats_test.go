@@ -0,0 +1,73 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+)
+
+func TestNATSNotifier(t *testing.T) {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = server.RANDOM_PORT
+	srv := natsserver.RunServer(&opts)
+	defer srv.Shutdown()
+
+	sub, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	messages := make(chan *nats.Msg, 1)
+	if _, err := sub.ChanSubscribe("watches", messages); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := newNATSNotifier(srv.ClientURL(), "watches")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	d := newWebhookDelivery(watch{id: base.ID(), signingSecret: "secret"}, bytes.NewBufferString(`{"id":"306"}`))
+	if status, err := n.Notify(context.Background(), d); status != http.StatusOK || err != nil {
+		t.Fatalf("status=%d err=%v", status, err)
+	}
+
+	select {
+	case msg := <-messages:
+		var event watchEvent
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.DeliveryID != d.ID || event.WatchID != d.WatchID || string(event.Body) != `{"id":"306"}` {
+			t.Errorf("unexpected event: %#v", event)
+		}
+		if event.Signature != signWebhook("secret", event.Timestamp, event.Body) {
+			t.Errorf("bad signature: %#v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	// publishing fails once the connection is closed
+	n.Close()
+	if status, err := n.Notify(context.Background(), d); status != 0 || err == nil {
+		t.Errorf("status=%d err=%v", status, err)
+	}
+}
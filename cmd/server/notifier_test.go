@@ -0,0 +1,265 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/go-kit/kit/log"
+)
+
+type testNotifier struct {
+	status int
+	err    error
+
+	deliveries []*webhookDelivery
+}
+
+func (n *testNotifier) Notify(_ context.Context, d *webhookDelivery) (int, error) {
+	n.deliveries = append(n.deliveries, d)
+	return n.status, n.err
+}
+
+// useTestNotifier registers n under name, the returned function restores the previous notifiers.
+func useTestNotifier(t *testing.T, name string, n Notifier) func() {
+	t.Helper()
+
+	notifiersMu.Lock()
+	previous := make(map[string]Notifier)
+	for k, v := range notifiers {
+		previous[k] = v
+	}
+	notifiersMu.Unlock()
+	previousDefault := defaultNotifier
+
+	registerNotifier(name, n)
+	return func() {
+		notifiersMu.Lock()
+		notifiers = previous
+		notifiersMu.Unlock()
+		defaultNotifier = previousDefault
+	}
+}
+
+// readWatchEvents returns every event written to a JSONL file
+func readWatchEvents(t *testing.T, path string) []watchEvent {
+	t.Helper()
+
+	fd, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	var events []watchEvent
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		var event watchEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestNotifier__find(t *testing.T) {
+	if n, err := findNotifier(""); err != nil {
+		t.Fatal(err)
+	} else if _, ok := n.(webhookNotifier); !ok {
+		t.Errorf("unexpected default notifier: %T", n)
+	}
+	if _, err := findNotifier("other"); err != errUnknownNotifier {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := findNotifier(kafkaNotifierName); err == nil || !strings.Contains(err.Error(), "isn't configured") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	defer useTestNotifier(t, kafkaNotifierName, &testNotifier{})()
+	if _, err := findNotifier(kafkaNotifierName); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNotifier__validateNotification(t *testing.T) {
+	defer useTestNotifier(t, natsNotifierName, &testNotifier{})()
+
+	// webhooks need an authToken and HTTPS url
+	req := watchRequest{}
+	if err := req.validateNotification(); err != errNoAuthToken {
+		t.Errorf("unexpected error: %v", err)
+	}
+	req = watchRequest{AuthToken: "foo", Webhook: "http://moov.io"}
+	if err := req.validateNotification(); err == nil {
+		t.Error("expected error")
+	}
+
+	// other notifiers don't
+	req = watchRequest{Notifier: natsNotifierName}
+	if err := req.validateNotification(); err != nil {
+		t.Error(err)
+	}
+	req = watchRequest{Notifier: kafkaNotifierName}
+	if err := req.validateNotification(); err == nil {
+		t.Error("expected error, kafka isn't configured")
+	}
+	req = watchRequest{Notifier: "other"}
+	if err := req.validateNotification(); err != errUnknownNotifier {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNotifier__file(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ofac-notifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.jsonl")
+
+	n, err := newFileNotifier(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := newWebhookDelivery(watch{id: base.ID(), signingSecret: "secret"}, bytes.NewBufferString(`{"id":"306"}`))
+	second := newWebhookDelivery(watch{id: base.ID()}, bytes.NewBufferString(`{"id":"307"}`))
+	for _, d := range []*webhookDelivery{first, second} {
+		if status, err := n.Notify(context.Background(), d); status != http.StatusOK || err != nil {
+			t.Fatalf("status=%d err=%v", status, err)
+		}
+	}
+	if err := n.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// reopening appends
+	n, err = newFileNotifier(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Notify(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+	n.Close()
+	if _, err := n.Notify(context.Background(), first); err == nil {
+		t.Error("expected error after close")
+	}
+
+	events := readWatchEvents(t, path)
+	if len(events) != 3 {
+		t.Fatalf("unexpected events: %#v", events)
+	}
+	if events[0].DeliveryID != first.ID || events[0].WatchID != first.WatchID || string(events[0].Body) != `{"id":"306"}` {
+		t.Errorf("unexpected event: %#v", events[0])
+	}
+	if expected := signWebhook("secret", events[0].Timestamp, events[0].Body); events[0].Signature != expected {
+		t.Errorf("signature=%q expected %q", events[0].Signature, expected)
+	}
+	if events[1].DeliveryID != second.ID || events[1].Signature != "" {
+		t.Errorf("unexpected event: %#v", events[1])
+	}
+}
+
+func TestNotifier__setup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ofac-notifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer useTestNotifier(t, webhookNotifierName, webhookNotifier{})()
+
+	os.Setenv("WATCH_EVENTS_FILE", filepath.Join(dir, "events.jsonl"))
+	os.Setenv("WATCH_NOTIFIER", fileNotifierName)
+	defer os.Unsetenv("WATCH_EVENTS_FILE")
+	defer os.Unsetenv("WATCH_NOTIFIER")
+
+	closeNotifiers, err := setupNotifiers(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeNotifiers()
+	if defaultNotifier != fileNotifierName {
+		t.Errorf("defaultNotifier=%s", defaultNotifier)
+	}
+	if n, err := findNotifier(""); err != nil {
+		t.Error(err)
+	} else if _, ok := n.(*fileNotifier); !ok {
+		t.Errorf("unexpected notifier: %T", n)
+	}
+
+	// default notifier must be configured
+	os.Setenv("WATCH_NOTIFIER", natsNotifierName)
+	if _, err := setupNotifiers(log.NewNopLogger()); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestNotifier__recordsOutcomes(t *testing.T) {
+	watchRepo := createTestWatchRepository(t)
+	defer watchRepo.close()
+	webhookRepo := &sqliteWebhookRepository{watchRepo.db}
+
+	n := &testNotifier{status: http.StatusOK}
+	defer useTestNotifier(t, kafkaNotifierName, n)()
+
+	watchID, err := watchRepo.addCustomerWatch(base.ID(), watchRequest{Notifier: kafkaNotifierName, SigningSecret: "a-long-enough-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	watches, err := watchRepo.getWatchesCursor(nil, 10).Next()
+	if err != nil || len(watches) != 1 {
+		t.Fatalf("watches=%#v err=%v", watches, err)
+	}
+	if watches[0].notifier != kafkaNotifierName {
+		t.Errorf("unexpected watch: %#v", watches[0])
+	}
+
+	d := newWebhookDelivery(watches[0], bytes.NewBufferString(`{"id":"306"}`))
+	if err := webhookRepo.enqueueDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+	if err := attemptWebhookDelivery(context.Background(), nil, webhookRepo, d); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.deliveries) != 1 || n.deliveries[0].signingSecret != "a-long-enough-secret" {
+		t.Errorf("unexpected deliveries: %#v", n.deliveries)
+	}
+
+	// failures are recorded and retried with the watch's notifier
+	n.status, n.err = 0, errors.New("broker unavailable")
+	d = newWebhookDelivery(watches[0], bytes.NewBufferString(`{"id":"307"}`))
+	if err := webhookRepo.enqueueDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+	if err := attemptWebhookDelivery(context.Background(), nil, webhookRepo, d); err == nil {
+		t.Error("expected error")
+	}
+	due, err := webhookRepo.getDueDeliveries(time.Now().Add(webhookMaxBackoff), 10)
+	if err != nil || len(due) != 1 || due[0].Notifier != kafkaNotifierName {
+		t.Fatalf("due=%#v err=%v", due, err)
+	}
+
+	deliveries, err := watchRepo.getWatchDeliveries(watchID, time.Time{}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 2 || deliveries[0].Delivered || !deliveries[1].Delivered {
+		t.Errorf("unexpected deliveries: %#v", deliveries)
+	}
+}
@@ -0,0 +1,392 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+)
+
+const manualUploadPath = "/ofac/data"
+
+var (
+	// dataBundleFiles are the lists read from an offline directory or bundle, sdn_comments.csv is optional
+	dataBundleFiles = []string{"add.csv", "alt.csv", "sdn.csv", "dpl.txt", "csl.csv"}
+
+	// maxDataBundleSize is the largest bundle which can be uploaded, or file extracted from a bundle
+	maxDataBundleSize int64 = 512 << 20
+)
+
+// offlineData reads the sanctions lists from a directory, or a .tar.gz or .zip bundle, instead of downloading
+// them. It's set from INITIAL_DATA_DIRECTORY.
+type offlineData struct {
+	path  string
+	isDir bool
+}
+
+func newOfflineData(path string) (*offlineData, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		// We stopped while swapping in an uploaded bundle, so put back the lists it was replacing
+		if _, err := os.Stat(path + ".old"); err == nil {
+			if err := os.Rename(path+".old", path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &offlineData{path: path, isDir: info.IsDir()}, nil
+}
+
+// open returns a directory holding the lists and a function to remove it once they're read
+func (o *offlineData) open() (string, func(), error) {
+	if o.isDir {
+		return o.path, func() {}, nil
+	}
+	dir, err := extractDataBundle(o.path)
+	if err != nil {
+		return "", nil, err
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+// replace writes the lists from an uploaded bundle (extracted into dir, with the bundle itself at bundlePath)
+// over our path so they're read on the next refresh and restart.
+//
+// A directory is replaced whole, so lists missing from the bundle (e.g. sdn_comments.csv) aren't kept and a
+// crash leaves either the old lists or the new ones. The lists are copied into a new directory next to ours,
+// then a symlink at our path is flipped to it in one rename. Otherwise our directory is moved aside (to
+// path+".old", which newOfflineData moves back if we stop in between) and the new one is moved in.
+func (o *offlineData) replace(bundlePath, dir string) error {
+	if !o.isDir {
+		return replaceFile(bundlePath, o.path)
+	}
+	info, err := os.Stat(o.path)
+	if err != nil {
+		return err
+	}
+	next, err := ioutil.TempDir(filepath.Dir(o.path), filepath.Base(o.path)+"-")
+	if err != nil {
+		return err
+	}
+	if err := copyDataBundle(dir, next, info.Mode().Perm()); err == nil {
+		err = o.swap(next)
+	}
+	if err != nil {
+		os.RemoveAll(next)
+	}
+	return err
+}
+
+// copyDataBundle copies the lists in dir into next, which is given perm
+func copyDataBundle(dir, next string, perm os.FileMode) error {
+	for _, name := range append(dataBundleFiles, "sdn_comments.csv") {
+		src := filepath.Join(dir, name)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := replaceFile(src, filepath.Join(next, name)); err != nil {
+			return err
+		}
+	}
+	return os.Chmod(next, perm)
+}
+
+// swap moves next, a directory next to ours, in as our path and removes the lists it replaced
+func (o *offlineData) swap(next string) error {
+	info, err := os.Lstat(o.path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		prev, err := os.Readlink(o.path)
+		if err != nil {
+			return err
+		}
+		link := next + ".link"
+		if err := os.Symlink(filepath.Base(next), link); err != nil {
+			return err
+		}
+		if err := os.Rename(link, o.path); err != nil {
+			os.Remove(link)
+			return err
+		}
+		// Only remove directories we created, not the one our symlink was set up with
+		if !filepath.IsAbs(prev) {
+			prev = filepath.Join(filepath.Dir(o.path), prev)
+		}
+		if filepath.Dir(prev) == filepath.Dir(o.path) && strings.HasPrefix(filepath.Base(prev), filepath.Base(o.path)+"-") {
+			os.RemoveAll(prev)
+		}
+		return nil
+	}
+
+	old := o.path + ".old"
+	if err := os.RemoveAll(old); err != nil {
+		return err
+	}
+	if err := os.Rename(o.path, old); err != nil {
+		return err
+	}
+	if err := os.Rename(next, o.path); err != nil {
+		os.Rename(old, o.path)
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+// replaceFile copies src next to dst and renames it over dst
+func replaceFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(dst), "."+filepath.Base(dst))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// extractDataBundle extracts the lists from a .tar.gz or .zip bundle (detected from its contents) into a new
+// temp directory. Files are matched by name, in any directory of the bundle, and every other file is ignored.
+// An error is returned if any of dataBundleFiles is missing.
+func extractDataBundle(path string) (string, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+
+	magic, _ := bufio.NewReader(fd).Peek(4)
+	if _, err := fd.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	dir, err := ioutil.TempDir("", "sanctions-lists-bundle")
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		err = extractTarGz(fd, dir)
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		err = extractZip(fd, dir)
+	default:
+		err = errors.New("unknown bundle format, expected .tar.gz or .zip")
+	}
+	if err == nil {
+		err = checkDataBundle(dir)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// bundleFileName returns the list name to extract a bundle entry as, or an empty string to skip it
+func bundleFileName(name string) string {
+	base := filepath.Base(filepath.Clean("/" + strings.Replace(name, `\`, "/", -1)))
+	if base == "sdn_comments.csv" {
+		return base
+	}
+	for i := range dataBundleFiles {
+		if base == dataBundleFiles[i] {
+			return base
+		}
+	}
+	return ""
+}
+
+func extractBundleFile(r io.Reader, dir, name string) error {
+	fd, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(fd, io.LimitReader(r, maxDataBundleSize+1))
+	if err != nil {
+		fd.Close()
+		return fmt.Errorf("problem extracting %s: %v", name, err)
+	}
+	if n > maxDataBundleSize {
+		fd.Close()
+		return fmt.Errorf("%s is larger than %d bytes", name, maxDataBundleSize)
+	}
+	return fd.Close()
+}
+
+func extractTarGz(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		if name := bundleFileName(hdr.Name); name != "" {
+			if err := extractBundleFile(tr, dir, name); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractZip(fd *os.File, dir string) error {
+	info, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(fd, info.Size())
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		name := bundleFileName(f.Name)
+		if name == "" || f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = extractBundleFile(rc, dir, name)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDataBundle returns an error naming every list missing from dir
+func checkDataBundle(dir string) error {
+	var missing []string
+	for _, name := range dataBundleFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("bundle is missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// loadDataBundle parses the lists extracted from an uploaded bundle into dir and, if they pass our guardrails,
+// replaces the data we search with them in one swap. When we're reading lists from disk the bundle also replaces them.
+func (s *searcher) loadDataBundle(bundlePath, dir string) (*downloadStats, error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	data, err := s.loadData(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.sdns) == 0 {
+		return nil, errors.New("bundle has no SDNs")
+	}
+	if s.offline != nil {
+		if err := s.offline.replace(bundlePath, dir); err != nil {
+			return nil, fmt.Errorf("problem saving bundle to %s: %v", s.offline.path, err)
+		}
+	}
+	return s.replaceData(data), nil
+}
+
+// manualUploadHandler accepts a .tar.gz or .zip bundle of the sanctions lists on the admin server, which is
+// validated and then replaces the data we search.
+func manualUploadHandler(logger log.Logger, searcher *searcher, downloadRepo downloadRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		fail := func(status int, err error) {
+			if logger != nil {
+				logger.Log("main", fmt.Sprintf("ERROR: admin: problem loading uploaded sanctions lists: %v", err))
+			}
+			writeProblem(w, status, err)
+		}
+
+		// Save the bundle so zip files can be read out of order
+		tmp, err := ioutil.TempFile("", "sanctions-lists-upload")
+		if err != nil {
+			fail(http.StatusInternalServerError, err)
+			return
+		}
+		defer os.Remove(tmp.Name())
+		_, err = io.Copy(tmp, http.MaxBytesReader(w, r.Body, maxDataBundleSize))
+		tmp.Close()
+		if err != nil {
+			fail(http.StatusBadRequest, fmt.Errorf("problem reading bundle: %v", err))
+			return
+		}
+
+		dir, err := extractDataBundle(tmp.Name())
+		if err != nil {
+			fail(http.StatusBadRequest, err)
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		stats, err := searcher.loadDataBundle(tmp.Name(), dir)
+		if err != nil {
+			fail(http.StatusBadRequest, err)
+			return
+		}
+		if logger != nil {
+			logger.Log("main", fmt.Sprintf("admin: loaded uploaded sanctions lists - Addresses=%d AltNames=%d SDNs=%d DeniedPersons=%d SectoralSanctions=%d ELs=%d",
+				stats.Addresses, stats.Alts, stats.SDNs, stats.DeniedPersons, stats.SectoralSanctions, stats.BISEntities))
+		}
+		downloadRepo.recordStats(stats)
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(stats)
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cardonator/ofac"
+)
+
+var testDataDir = filepath.Join("..", "..", "test", "testdata")
+
+// testDataBundle returns a .tar.gz (or .zip) of the given files from test/testdata, stored under a "lists/" directory
+func testDataBundle(t *testing.T, zipped bool, names ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var tw *tar.Writer
+	var zw *zip.Writer
+	var gz *gzip.Writer
+	if zipped {
+		zw = zip.NewWriter(&buf)
+	} else {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	}
+	for _, name := range names {
+		bs, err := ioutil.ReadFile(filepath.Join(testDataDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if zipped {
+			w, err := zw.Create("lists/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(bs)
+		} else {
+			tw.WriteHeader(&tar.Header{Name: "lists/" + name, Mode: 0600, Size: int64(len(bs)), Typeflag: tar.TypeReg})
+			tw.Write(bs)
+		}
+	}
+	if zipped {
+		zw.Close()
+	} else {
+		tw.Close()
+		gz.Close()
+	}
+	return buf.Bytes()
+}
+
+func writeTestBundle(t *testing.T, dir string, contents []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "lists.bundle")
+	if err := ioutil.WriteFile(path, contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOffline__extractDataBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ofac-offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, zipped := range []bool{false, true} {
+		path := writeTestBundle(t, dir, testDataBundle(t, zipped, "add.csv", "alt.csv", "sdn.csv", "dpl.txt", "csl.csv", "sdn_comments.csv"))
+		out, err := extractDataBundle(path)
+		if err != nil {
+			t.Fatalf("zipped=%v: %v", zipped, err)
+		}
+		if _, err := os.Stat(filepath.Join(out, "sdn_comments.csv")); err != nil {
+			t.Errorf("zipped=%v: %v", zipped, err)
+		}
+		os.RemoveAll(out)
+
+		// missing lists
+		path = writeTestBundle(t, dir, testDataBundle(t, zipped, "add.csv", "alt.csv", "sdn.csv"))
+		if _, err := extractDataBundle(path); err == nil || err.Error() != "bundle is missing dpl.txt, csl.csv" {
+			t.Errorf("zipped=%v: unexpected error: %v", zipped, err)
+		}
+	}
+
+	path := writeTestBundle(t, dir, []byte("not a bundle"))
+	if _, err := extractDataBundle(path); err == nil {
+		t.Error("expected error")
+	}
+
+	for name, expected := range map[string]string{"sdn.csv": "sdn.csv", "../../etc/sdn.csv": "sdn.csv", `lists\alt.csv`: "alt.csv", "passwd": ""} {
+		if got := bundleFileName(name); got != expected {
+			t.Errorf("%s: got %q", name, got)
+		}
+	}
+}
+
+func TestOffline__refreshData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ofac-offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, path := range []string{testDataDir, writeTestBundle(t, dir, testDataBundle(t, false, "add.csv", "alt.csv", "sdn.csv", "dpl.txt", "csl.csv"))} {
+		offline, err := newOfflineData(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := &searcher{offline: offline}
+		stats, err := s.refreshData(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if len(s.SDNs) == 0 || stats.SDNs != len(s.SDNs) || stats.Status != downloadRefreshed {
+			t.Errorf("%s: SDNs=%d stats=%#v", path, len(s.SDNs), stats)
+		}
+		if len(s.DPs) == 0 || len(s.SSIs) == 0 {
+			t.Errorf("%s: DPs=%d SSIs=%d", path, len(s.DPs), len(s.SSIs))
+		}
+	}
+
+	if _, err := newOfflineData(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestOffline__replaceDirectory(t *testing.T) {
+	parent, err := ioutil.TempDir("", "ofac-offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parent)
+
+	// the bundle doesn't have sdn_comments.csv
+	bundle := writeTestBundle(t, parent, testDataBundle(t, false, dataBundleFiles...))
+	extracted, err := extractDataBundle(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(extracted)
+
+	replace := func(path string) {
+		t.Helper()
+
+		offline, err := newOfflineData(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := offline.replace(bundle, extracted); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range dataBundleFiles {
+			if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+				t.Error(err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(path, "sdn_comments.csv")); !os.IsNotExist(err) {
+			t.Errorf("old sdn_comments.csv was kept: %v", err)
+		}
+	}
+
+	// a directory is moved aside for the new lists
+	dir := filepath.Join(parent, "lists")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "sdn_comments.csv"), []byte("1,old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	replace(dir)
+	if _, err := os.Stat(dir + ".old"); !os.IsNotExist(err) {
+		t.Errorf("old lists weren't removed: %v", err)
+	}
+
+	// the old directory is moved back if we stopped before the new one was moved in
+	if err := os.Rename(dir, dir+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newOfflineData(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// a symlink is flipped to the new lists, and directories we created are removed
+	link := filepath.Join(parent, "current")
+	if err := os.Symlink("lists", link); err != nil {
+		t.Fatal(err)
+	}
+	replace(link)
+	first, _ := os.Readlink(link)
+	replace(link)
+	if _, err := os.Stat(filepath.Join(parent, first)); !os.IsNotExist(err) {
+		t.Errorf("%s wasn't removed: %v", first, err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("original directory was removed: %v", err)
+	}
+}
+
+func TestOffline__uploadHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ofac-offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// INITIAL_DATA_DIRECTORY is a bundle which uploads replace, and we search one SDN
+	path := writeTestBundle(t, dir, testDataBundle(t, true, "add.csv", "alt.csv", "dpl.txt", "csl.csv"))
+	offline, err := newOfflineData(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &searcher{offline: offline}
+	s.SDNs = precomputeSDNs([]*ofac.SDN{{EntityID: "1", SDNName: "foo"}})
+
+	repo := createTestDownloadRepository(t)
+	defer repo.close()
+	handler := manualUploadHandler(nil, s, repo)
+
+	upload := func(body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("POST", manualUploadPath, bytes.NewReader(body)))
+		return w
+	}
+
+	// invalid bundles keep our data
+	for _, body := range [][]byte{[]byte("not a bundle"), testDataBundle(t, false, "sdn.csv")} {
+		if w := upload(body); w.Code != http.StatusBadRequest {
+			t.Errorf("bogus status code: %d", w.Code)
+		}
+	}
+	if len(s.SDNs) != 1 {
+		t.Errorf("SDNs=%d", len(s.SDNs))
+	}
+
+	bundle := testDataBundle(t, false, "add.csv", "alt.csv", "sdn.csv", "dpl.txt", "csl.csv")
+	w := upload(bundle)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	var stats downloadStats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.SDNs == 0 || stats.SDNs != len(s.SDNs) {
+		t.Errorf("SDNs=%d stats=%#v", len(s.SDNs), stats)
+	}
+	if downloads, err := repo.latestDownloads(1); err != nil || len(downloads) != 1 {
+		t.Errorf("downloads=%#v err=%v", downloads, err)
+	}
+
+	// the uploaded bundle replaced INITIAL_DATA_DIRECTORY
+	if bs, err := ioutil.ReadFile(path); err != nil || !bytes.Equal(bs, bundle) {
+		t.Errorf("bundle wasn't saved: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", manualUploadPath, nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/gorilla/mux"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	throttledRequests = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "http_throttled_requests",
+		Help: "Counter of HTTP requests rejected by rate limits or daily quotas",
+	}, []string{"route", "reason"})
+
+	// quotaRetention is how long daily quota counts are kept
+	quotaRetention = 30 * 24 * time.Hour
+)
+
+// rateLimit allows a client burst requests at once, refilled at rate requests per second
+type rateLimit struct {
+	rate  float64
+	burst int
+}
+
+// parseRateLimit reads a limit formatted as rate:burst (e.g. 2:10)
+func parseRateLimit(str string) (rateLimit, error) {
+	parts := strings.Split(strings.TrimSpace(str), ":")
+	if len(parts) != 2 {
+		return rateLimit{}, fmt.Errorf("invalid rate limit %q, expected rate:burst", str)
+	}
+	rate, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || rate <= 0 {
+		return rateLimit{}, fmt.Errorf("invalid rate in %q", str)
+	}
+	burst, err := strconv.Atoi(parts[1])
+	if err != nil || burst < 1 {
+		return rateLimit{}, fmt.Errorf("invalid burst in %q", str)
+	}
+	return rateLimit{rate: rate, burst: burst}, nil
+}
+
+// parseRouteRateLimits reads comma separated route limits, e.g. "GET /search=2:10,POST /subjects=1:5"
+func parseRouteRateLimits(str string) (map[string]rateLimit, error) {
+	out := make(map[string]rateLimit)
+	for _, rule := range strings.Split(str, ",") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		idx := strings.LastIndex(rule, "=")
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid route rate limit %q, expected METHOD /path=rate:burst", rule)
+		}
+		route := strings.Fields(rule[:idx])
+		if len(route) != 2 {
+			return nil, fmt.Errorf("invalid route rate limit %q, expected METHOD /path=rate:burst", rule)
+		}
+		limit, err := parseRateLimit(rule[idx+1:])
+		if err != nil {
+			return nil, err
+		}
+		out[strings.ToUpper(route[0])+" "+route[1]] = limit
+	}
+	return out, nil
+}
+
+// tokenBucket holds the tokens left for one client of a rateLimit
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take removes a token from b, refilling it since its last request first. It returns false and how
+// long until a token is available when b is empty.
+func (b *tokenBucket) take(limit rateLimit, now time.Time) (bool, time.Duration) {
+	b.tokens = math.Min(float64(limit.burst), b.tokens+now.Sub(b.last).Seconds()*limit.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / limit.rate * float64(time.Second))
+}
+
+// rateLimiter keeps a token bucket for each client of each route. Routes without their own limit
+// share a bucket per client under the default limit.
+type rateLimiter struct {
+	defaultLimit *rateLimit
+	routes       map[string]rateLimit
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimiter(defaultLimit *rateLimit, routes map[string]rateLimit) *rateLimiter {
+	return &rateLimiter{
+		defaultLimit: defaultLimit,
+		routes:       routes,
+		buckets:      make(map[string]*tokenBucket),
+	}
+}
+
+// allow returns false and when the client can retry if they're over the limit for route
+func (l *rateLimiter) allow(route, client string, now time.Time) (bool, time.Duration) {
+	limit, exists := l.routes[route]
+	if !exists {
+		if l.defaultLimit == nil {
+			return true, 0
+		}
+		limit, route = *l.defaultLimit, "*"
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := route + "|" + client
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &tokenBucket{tokens: float64(limit.burst), last: now}
+		l.buckets[key] = b
+	}
+	return b.take(limit, now)
+}
+
+// prune removes buckets which have refilled, so idle clients aren't kept in memory
+func (l *rateLimiter) prune(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, b := range l.buckets {
+		limit := l.defaultLimit
+		if r, exists := l.routes[key[:strings.Index(key, "|")]]; exists {
+			limit = &r
+		}
+		if limit == nil || b.tokens+now.Sub(b.last).Seconds()*limit.rate >= float64(limit.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// quotaRepository counts each client's requests per day (in UTC)
+type quotaRepository interface {
+	// incrementQuota counts a request from client on day and returns how many they've made that day
+	incrementQuota(client string, day string) (int, error)
+
+	// deleteQuotasBefore removes the counts of days before day
+	deleteQuotasBefore(day string) error
+
+	close() error
+}
+
+type sqliteQuotaRepository struct {
+	db *sql.DB
+}
+
+func (r *sqliteQuotaRepository) close() error {
+	return r.db.Close()
+}
+
+// addQuotaTable creates the request_quotas table
+func addQuotaTable() []string {
+	return []string{
+		`create table if not exists request_quotas(client_key varchar(128), day varchar(10), requests integer, primary key (client_key, day));`,
+	}
+}
+
+func dropQuotaTable() []string {
+	return []string{`drop table if exists request_quotas;`}
+}
+
+// incrementQuota counts a request against client's day. The first request of a day creates its row outside
+// of the increment's transaction, where a conflict means another request created it first, and then retries.
+func (r *sqliteQuotaRepository) incrementQuota(client string, day string) (int, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		requests, found, err := r.incrementExistingQuota(client, day)
+		if err != nil || found {
+			return requests, err
+		}
+		if _, err := r.db.Exec(`insert into request_quotas (client_key, day, requests) values (?, ?, 0);`, client, day); err != nil && !isUniqueViolation(err) {
+			return 0, err
+		}
+	}
+	return 0, fmt.Errorf("problem incrementing quota for %s on %s", client, day)
+}
+
+// incrementExistingQuota returns found as false when client has no row for day
+func (r *sqliteQuotaRepository) incrementExistingQuota(client string, day string) (int, bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	res, err := tx.Exec(`update request_quotas set requests = requests + 1 where client_key = ? and day = ?;`, client, day)
+	if err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, false, tx.Rollback()
+	}
+	var requests int
+	if err := tx.QueryRow(`select requests from request_quotas where client_key = ? and day = ?;`, client, day).Scan(&requests); err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+	return requests, true, tx.Commit()
+}
+
+func (r *sqliteQuotaRepository) deleteQuotasBefore(day string) error {
+	_, err := r.db.Exec(`delete from request_quotas where day < ?;`, day)
+	return err
+}
+
+// dailyQuota rejects clients once they've made limit requests in a day
+type dailyQuota struct {
+	limit int
+	repo  quotaRepository
+
+	mu     sync.Mutex
+	pruned string // day old counts were last removed on
+}
+
+// allow counts a request from client and returns false, with how long until the quota resets, once
+// they're over the limit. Requests are allowed when they can't be counted.
+func (q *dailyQuota) allow(logger log.Logger, client string, now time.Time) (bool, time.Duration) {
+	now = now.UTC()
+	day := now.Format("2006-01-02")
+
+	q.mu.Lock()
+	if q.pruned != day {
+		q.pruned = day
+		if err := q.repo.deleteQuotasBefore(now.Add(-quotaRetention).Format("2006-01-02")); err != nil && logger != nil {
+			logger.Log("ratelimit", fmt.Sprintf("problem removing old quotas: %v", err))
+		}
+	}
+	q.mu.Unlock()
+
+	requests, err := q.repo.incrementQuota(client, day)
+	if err != nil {
+		if logger != nil {
+			logger.Log("ratelimit", fmt.Sprintf("problem counting request for quota: %v", err))
+		}
+		return true, 0
+	}
+	if requests <= q.limit {
+		return true, 0
+	}
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return false, midnight.Sub(now)
+}
+
+// rateLimitClient identifies who made a request: the user they authenticated as, or their IP address.
+// Credentials aren't trusted until authMiddleware has checked them, so anyone sending made up API keys
+// shares their IP address's limits.
+func rateLimitClient(r *http.Request) string {
+	if id := requestIdentity(r); id != nil && id.UserID != "" {
+		return "user:" + id.UserID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// rateLimitMiddleware responds with 429 Too Many Requests (and a Retry-After header) to clients over
+// their rate limit or daily quota. Either can be nil. CORS preflight requests and /ping aren't limited.
+func rateLimitMiddleware(logger log.Logger, limiter *rateLimiter, quota *dailyQuota) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "OPTIONS" || r.URL.Path == "/ping" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			route := r.Method + " " + r.URL.Path
+			if current := mux.CurrentRoute(r); current != nil {
+				if tpl, err := current.GetPathTemplate(); err == nil {
+					route = r.Method + " " + tpl
+				}
+			}
+			client, now := rateLimitClient(r), time.Now()
+
+			if limiter != nil {
+				if ok, wait := limiter.allow(route, client, now); !ok {
+					throttle(w, route, "rate", wait, errors.New("rate limit exceeded"))
+					return
+				}
+			}
+			if quota != nil {
+				if ok, wait := quota.allow(logger, client, now); !ok {
+					throttle(w, route, "quota", wait, errors.New("daily quota exceeded"))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func throttle(w http.ResponseWriter, route, reason string, wait time.Duration, err error) {
+	throttledRequests.With("route", cleanMetricsPath(route), "reason", reason).Add(1)
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	writeProblem(w, http.StatusTooManyRequests, err)
+}
+
+// setupRateLimits reads RATE_LIMIT, RATE_LIMIT_ROUTES and DAILY_QUOTA from our environment. It returns
+// nil when none are set. Idle clients are pruned from the rate limiter with goBackground until stop is closed.
+func setupRateLimits(logger log.Logger, repo quotaRepository, stop <-chan struct{}, goBackground func(func())) (mux.MiddlewareFunc, error) {
+	var limiter *rateLimiter
+	var defaultLimit *rateLimit
+	if v := os.Getenv("RATE_LIMIT"); v != "" {
+		limit, err := parseRateLimit(v)
+		if err != nil {
+			return nil, fmt.Errorf("RATE_LIMIT: %v", err)
+		}
+		defaultLimit = &limit
+	}
+	routes, err := parseRouteRateLimits(os.Getenv("RATE_LIMIT_ROUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("RATE_LIMIT_ROUTES: %v", err)
+	}
+	if defaultLimit != nil || len(routes) > 0 {
+		limiter = newRateLimiter(defaultLimit, routes)
+		goBackground(func() {
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+			for {
+				select {
+				case now := <-ticker.C:
+					limiter.prune(now)
+				case <-stop:
+					return
+				}
+			}
+		})
+		logger.Log("ratelimit", fmt.Sprintf("rate limiting clients with %d route limits (default=%v)", len(routes), defaultLimit != nil))
+	}
+
+	var quota *dailyQuota
+	if v := os.Getenv("DAILY_QUOTA"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("DAILY_QUOTA: invalid quota %q", v)
+		}
+		quota = &dailyQuota{limit: n, repo: repo}
+		logger.Log("ratelimit", fmt.Sprintf("limiting clients to %d requests per day", n))
+	}
+
+	if limiter == nil && quota == nil {
+		return nil, nil
+	}
+	return rateLimitMiddleware(logger, limiter, quota), nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRateLimit__parse(t *testing.T) {
+	if limit, err := parseRateLimit("0.5:10"); err != nil || limit.rate != 0.5 || limit.burst != 10 {
+		t.Errorf("limit=%#v err=%v", limit, err)
+	}
+	for _, str := range []string{"", "5", "0:10", "5:0", "a:b"} {
+		if _, err := parseRateLimit(str); err == nil {
+			t.Errorf("%q: expected error", str)
+		}
+	}
+
+	routes, err := parseRouteRateLimits("get /search=2:10, POST /subjects=1:5")
+	if err != nil || len(routes) != 2 || routes["GET /search"].burst != 10 || routes["POST /subjects"].rate != 1 {
+		t.Errorf("routes=%#v err=%v", routes, err)
+	}
+	for _, str := range []string{"/search=2:10", "GET /search", "GET /search=2"} {
+		if _, err := parseRouteRateLimits(str); err == nil {
+			t.Errorf("%q: expected error", str)
+		}
+	}
+}
+
+func TestRateLimit__rateLimiter(t *testing.T) {
+	limiter := newRateLimiter(&rateLimit{rate: 10, burst: 2}, map[string]rateLimit{"GET /search": {rate: 1, burst: 1}})
+	now := time.Now()
+
+	if ok, _ := limiter.allow("GET /search", "ip:1", now); !ok {
+		t.Error("expected first search to be allowed")
+	}
+	ok, wait := limiter.allow("GET /search", "ip:1", now)
+	if ok || wait != time.Second {
+		t.Errorf("ok=%v wait=%v", ok, wait)
+	}
+	if ok, _ := limiter.allow("GET /search", "ip:2", now); !ok {
+		t.Error("expected another client to be allowed")
+	}
+	if ok, _ := limiter.allow("GET /search", "ip:1", now.Add(time.Second)); !ok {
+		t.Error("expected a refilled token")
+	}
+
+	// other routes share the default limit
+	if ok, _ := limiter.allow("GET /sdn/{sdnId}", "ip:1", now); !ok {
+		t.Error("expected request to be allowed")
+	}
+	if ok, _ := limiter.allow("GET /downloads", "ip:1", now); !ok {
+		t.Error("expected request to be allowed")
+	}
+	if ok, wait := limiter.allow("GET /sdn/{sdnId}", "ip:1", now); ok || wait != 100*time.Millisecond {
+		t.Errorf("ok=%v wait=%v", ok, wait)
+	}
+
+	// idle buckets are pruned once refilled
+	limiter.prune(now.Add(time.Hour))
+	if len(limiter.buckets) != 0 {
+		t.Errorf("buckets=%#v", limiter.buckets)
+	}
+}
+
+func TestRateLimit__middleware(t *testing.T) {
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	repo := &sqliteQuotaRepository{db.db}
+
+	router := mux.NewRouter()
+	addPingRoute(router)
+	router.Methods("GET").Path("/search").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	limiter := newRateLimiter(nil, map[string]rateLimit{"GET /search": {rate: 0.001, burst: 2}})
+	keys, err := newAPIKeyAuthenticator([]*authIdentity{{Key: "abc", UserID: "batch", Roles: []role{roleScreening}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.Use(authMiddleware(nil, []authenticator{keys, anonymousAuthenticator{}}))
+	router.Use(rateLimitMiddleware(nil, limiter, &dailyQuota{limit: 3, repo: repo}))
+
+	search := func(apiKey, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/search?q=foo", nil)
+		req.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			req.Header.Set("X-Api-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := search("", "10.0.0.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("bogus status code: %d", w.Code)
+		}
+	}
+	w := search("", "10.0.0.1:4321")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1000" {
+		t.Errorf("bogus response: %d Retry-After=%q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// authenticated users are limited separately from their IP address
+	if w := search("abc", "10.0.0.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+	// made up API keys are rejected before they're counted
+	if w := search("random", "10.0.0.1:1234"); w.Code != http.StatusUnauthorized {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+	if w := search("", "10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+
+	// /ping isn't limited
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/ping", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("bogus status code: %d", w.Code)
+		}
+	}
+
+	// the daily quota counts every request made that day
+	quota := &dailyQuota{limit: 3, repo: repo}
+	now := time.Date(2019, time.June, 1, 23, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if ok, _ := quota.allow(nil, "key:def", now); !ok {
+			t.Fatalf("request %d: expected to be allowed", i)
+		}
+	}
+	if ok, wait := quota.allow(nil, "key:def", now); ok || wait != time.Hour {
+		t.Errorf("ok=%v wait=%v", ok, wait)
+	}
+	if ok, _ := quota.allow(nil, "key:def", now.Add(time.Hour)); !ok {
+		t.Error("expected the quota to reset the next day")
+	}
+}
+
+// anonymousAuthenticator lets requests without credentials through as a screening identity without a user
+type anonymousAuthenticator struct{}
+
+func (anonymousAuthenticator) authenticate(r *http.Request) (*authIdentity, error) {
+	return &authIdentity{Roles: []role{roleScreening}}, nil
+}
+
+func TestRateLimit__quotaRepository(t *testing.T) {
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	repo := &sqliteQuotaRepository{db.db}
+
+	for i := 1; i <= 3; i++ {
+		if n, err := repo.incrementQuota("ip:10.0.0.1", "2019-06-01"); n != i || err != nil {
+			t.Errorf("n=%d err=%v", n, err)
+		}
+	}
+	if n, err := repo.incrementQuota("ip:10.0.0.1", "2019-06-02"); n != 1 || err != nil {
+		t.Errorf("n=%d err=%v", n, err)
+	}
+	if err := repo.deleteQuotasBefore("2019-06-02"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := repo.incrementQuota("ip:10.0.0.1", "2019-06-01"); n != 1 || err != nil {
+		t.Errorf("n=%d err=%v", n, err)
+	}
+}
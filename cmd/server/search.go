@@ -5,6 +5,8 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -40,6 +42,27 @@ type searcher struct {
 	ELs          []*EL
 	sync.RWMutex // protects all above fields
 
+	// whitelistRepo holds false positives left out of results, it's optional
+	whitelistRepo whitelistRepository
+
+	// whitelistMu protects wl, the valid entries of whitelistRepo kept in memory (see loadWhitelist)
+	whitelistMu sync.Mutex
+	wl          *whitelist
+
+	// downloadRepo persists the manifest of our last download so lists which haven't changed aren't downloaded
+	// and parsed again after restarting, it's optional
+	downloadRepo downloadRepository
+
+	// refreshMu is held while refreshing data and manifest describes the files our data was parsed from
+	refreshMu sync.Mutex
+	manifest  *ofac.Manifest
+
+	// offline is where lists are read from instead of downloading them, it's optional
+	offline *offlineData
+
+	// guardrails reject refreshes which look broken, it's optional
+	guardrails *refreshGuardrails
+
 	logger log.Logger
 }
 
@@ -487,6 +510,7 @@ func precomputeAlts(alts []*ofac.AlternateIdentity) []*Alt {
 // DP is a BIS Denied Person wrapped with precomputed search metadata
 type DP struct {
 	DeniedPerson *ofac.DPL
+	id           string
 	match        float64
 	name         string
 }
@@ -495,9 +519,11 @@ type DP struct {
 func (d DP) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
 		*ofac.DPL
-		Match float64 `json:"match"`
+		EntityID string  `json:"entityID"`
+		Match    float64 `json:"match"`
 	}{
 		d.DeniedPerson,
+		d.id,
 		d.match,
 	})
 }
@@ -507,12 +533,31 @@ func precomputeDPs(persons []*ofac.DPL) []*DP {
 	for i := range persons {
 		out[i] = &DP{
 			DeniedPerson: persons[i],
+			id:           deniedPersonID(persons[i]),
 			name:         precompute(reorderSDNName(persons[i].Name, "individual")),
 		}
 	}
 	return out
 }
 
+// deniedPersonID identifies a denied person, who has no ID on the list. Names aren't unique, so it's a hash
+// of their name, address and the date their denial took effect, which stays the same when it's updated.
+func deniedPersonID(dp *ofac.DPL) string {
+	return listRecordID(dp.Name, dp.StreetAddress, dp.City, dp.State, dp.Country, dp.PostalCode, dp.EffectiveDate)
+}
+
+// bisEntityID identifies a BIS entity, which has no ID on the list, by a hash of its name, addresses and
+// start date.
+func bisEntityID(el *ofac.EL) string {
+	return listRecordID(append([]string{el.Name, el.StartDate}, el.Addresses...)...)
+}
+
+// listRecordID returns a short hex encoded SHA-256 of fields
+func listRecordID(fields ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	return hex.EncodeToString(sum[:16])
+}
+
 type SSI struct {
 	SectoralSanction *ofac.SSI
 	match            float64
@@ -542,6 +587,7 @@ func precomputeSSIs(ssis []*ofac.SSI) []*SSI {
 
 type EL struct {
 	Entity *ofac.EL
+	id     string
 	match  float64
 	name   string
 }
@@ -549,9 +595,11 @@ type EL struct {
 func (e EL) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
 		*ofac.EL
-		Match float64 `json:"match"`
+		EntityID string  `json:"entityID"`
+		Match    float64 `json:"match"`
 	}{
 		e.Entity,
+		e.id,
 		e.match,
 	})
 }
@@ -561,6 +609,7 @@ func precomputeELs(els []*ofac.EL) []*EL {
 	for i, el := range els {
 		out[i] = &EL{
 			Entity: el,
+			id:     bisEntityID(el),
 			name:   precompute(el.Name),
 		}
 	}
@@ -6,6 +6,9 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -20,8 +23,21 @@ var (
 	watchResearchBatchSize = 100
 )
 
+// WatchChangeType describes why a webhook was sent for a watch
+type WatchChangeType string
+
+const (
+	// WatchNewMatch is sent the first time an entity matches a watch
+	WatchNewMatch WatchChangeType = "new"
+	// WatchUpdated is sent when the watched entity has changed since the last notification
+	WatchUpdated WatchChangeType = "updated"
+	// WatchDelisted is sent when the watched entity has been removed from the sanctions list
+	WatchDelisted WatchChangeType = "delisted"
+)
+
 func init() {
 	watchResearchBatchSize = readWebhookBatchSize(os.Getenv("WEBHOOK_BATCH_SIZE"))
+	watchResearchWorkers = readWatchResearchWorkers(os.Getenv("WATCH_RESEARCH_WORKERS"))
 }
 
 func readWebhookBatchSize(str string) int {
@@ -35,100 +51,472 @@ func readWebhookBatchSize(str string) int {
 	return watchResearchBatchSize
 }
 
-// spawnResearching will block and select on updates for when to re-inspect all watches setup.
+// spawnResearching will block and select on updates for when to re-inspect all watches setup, until stop is closed.
 // Since watches are used to post OFAC data via webhooks they are used as catalysts in other systems.
-func (s *searcher) spawnResearching(logger log.Logger, companyRepo companyRepository, custRepo customerRepository, watchRepo watchRepository, webhookRepo webhookRepository, updates chan *downloadStats) {
+//
+// Watches are re-searched by a pool of watchResearchWorkers goroutines and each run's progress is
+// saved in runRepo. Every subject is re-screened once the watches are done. A run is interrupted when ctx
+// is done, and a run which was interrupted by shutting down is resumed before waiting on updates.
+func (s *searcher) spawnResearching(ctx context.Context, stop <-chan struct{}, logger log.Logger, companyRepo companyRepository, custRepo customerRepository, watchRepo watchRepository, webhookRepo webhookRepository, runRepo watchRunRepository, subjectRepo subjectRepository, updates chan *downloadStats) {
+	if run := s.resumableWatchRun(runRepo); run != nil {
+		s.logger.Log("search", fmt.Sprintf("async: resuming interrupted watch run %s", run.ID))
+		s.researchWatchRun(ctx, logger, run, companyRepo, custRepo, watchRepo, webhookRepo, runRepo, subjectRepo)
+	}
 	for {
 		select {
-		case <-updates:
+		case stats := <-updates:
+			if n, err := s.invalidateWhitelist(time.Now()); err != nil {
+				s.logger.Log("search", fmt.Sprintf("async: problem invalidating whitelist: %v", err))
+			} else if n > 0 {
+				s.logger.Log("search", fmt.Sprintf("async: invalidated %d whitelist entries whose record changed", n))
+			}
+
 			s.logger.Log("search", "async: starting re-search of watches")
-			cursor := watchRepo.getWatchesCursor(logger, watchResearchBatchSize)
-			for {
-				watches, _ := cursor.Next()
-				if len(watches) == 0 {
-					break
-				}
-				for i := range watches {
-					var body *bytes.Buffer
-					var err error
-
-					// Perform a query (ID watches) or search (name watches) and encode the model in JSON for calling the webhook.
-					switch {
-					case watches[i].customerID != "":
-						s.logger.Log("search", fmt.Sprintf("async: watch %s for customer %s found", watches[i].id, watches[i].customerID))
-						body, err = getCustomerBody(s, watches[i].id, watches[i].customerID, 1.0, custRepo)
-
-					case watches[i].customerName != "":
-						s.logger.Log("search", fmt.Sprintf("async: name watch '%s' for customer %s found", watches[i].customerName, watches[i].id))
-						sdns := s.TopSDNs(5, watches[i].customerName)
-						for i := range sdns {
-							if strings.EqualFold(sdns[i].SDNType, "individual") {
-								body, err = getCustomerBody(s, watches[i].id, sdns[i].EntityID, sdns[i].match, custRepo)
-								break
-							}
-						}
-
-					case watches[i].companyID != "":
-						s.logger.Log("search", fmt.Sprintf("async: watch %s for company %s found", watches[i].id, watches[i].companyID))
-						body, err = getCompanyBody(s, watches[i].id, watches[i].companyID, 1.0, companyRepo)
-
-					case watches[i].companyName != "":
-						s.logger.Log("search", fmt.Sprintf("async: name watch '%s' for company %s found", watches[i].companyName, watches[i].id))
-						sdns := s.TopSDNs(5, watches[i].companyName)
-						for i := range sdns {
-							if !strings.EqualFold(sdns[i].SDNType, "individual") {
-								body, err = getCompanyBody(s, watches[i].id, sdns[i].EntityID, sdns[i].match, companyRepo)
-								break
-							}
-						}
-					}
-					if err != nil {
-						s.logger.Log("search", fmt.Sprintf("async: watch %s: %v", watches[i].id, err))
-						continue // skip to next watch since we failed somewhere
-					}
-
-					// Send HTTP webhook
-					now := time.Now()
-					status, err := callWebhook(watches[i].id, body, watches[i].webhook, watches[i].authToken)
-					if err != nil {
-						s.logger.Log("search", fmt.Errorf("async: problem writing watch (%s) webhook status: %v", watches[i].id, err))
-					}
-					if err := webhookRepo.recordWebhook(watches[i].id, now, status); err != nil {
-						s.logger.Log("search", fmt.Errorf("async: problem writing watch (%s) webhook status: %v", watches[i].id, err))
-					}
-				}
+			run, err := runRepo.startWatchRun(stats.Timestamp)
+			if err != nil {
+				s.logger.Log("search", fmt.Sprintf("async: problem starting watch run: %v", err))
+				continue
 			}
+			s.researchWatchRun(ctx, logger, run, companyRepo, custRepo, watchRepo, webhookRepo, runRepo, subjectRepo)
 
+		case <-stop:
+			return
 		}
 	}
 }
 
-// getCustomerBody returns the JSON encoded form of a given customer by their EntityID
-func getCustomerBody(s *searcher, watchID string, customerID string, match float64, repo customerRepository) (*bytes.Buffer, error) {
-	customer, _ := getCustomerByID(customerID, s, repo)
+// resumableWatchRun returns the newest run which was interrupted (or still running when we stopped) with its
+// progress reset, since every watch is re-searched again. Older unfinished runs are marked as superseded.
+//
+// Watches already re-searched before the interruption aren't notified again as their last notification is
+// compared to what's found.
+func (s *searcher) resumableWatchRun(runRepo watchRunRepository) *watchRun {
+	runs, err := runRepo.getUnfinishedWatchRuns()
+	if err != nil {
+		s.logger.Log("search", fmt.Sprintf("async: problem reading unfinished watch runs: %v", err))
+		return nil
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, run := range runs[1:] {
+		run.Status, run.UpdatedAt = watchRunSuperseded, now
+		if err := runRepo.updateWatchRun(run); err != nil {
+			s.logger.Log("search", fmt.Sprintf("async: problem superseding watch run %s: %v", run.ID, err))
+		}
+	}
+	run := runs[0]
+	run.Status, run.UpdatedAt = watchRunRunning, now
+	run.Started, run.Processed, run.Failed = 0, 0, 0
+	if err := runRepo.updateWatchRun(run); err != nil {
+		s.logger.Log("search", fmt.Sprintf("async: problem resuming watch run %s: %v", run.ID, err))
+		return nil
+	}
+	return run
+}
+
+// researchWatchRun re-searches every watch for run and then re-screens every subject. When ctx is done
+// the run is saved as interrupted and subjects aren't re-screened.
+func (s *searcher) researchWatchRun(ctx context.Context, logger log.Logger, run *watchRun, companyRepo companyRepository, custRepo customerRepository, watchRepo watchRepository, webhookRepo webhookRepository, runRepo watchRunRepository, subjectRepo subjectRepository) {
+	progress := &watchRunProgress{
+		logger:    s.logger,
+		repo:      runRepo,
+		saveEvery: watchResearchBatchSize,
+		run:       run,
+	}
+	cursor := watchRepo.getWatchesCursor(logger, watchResearchBatchSize)
+	err := researchWatches(ctx, cursor, watchResearchWorkers, progress, func(w watch) error {
+		err := s.researchWatch(ctx, w, run.DownloadedAt, companyRepo, custRepo, webhookRepo)
+		if err != nil {
+			s.logger.Log("search", fmt.Sprintf("async: watch %s: %v", w.id, err))
+		}
+		return err
+	})
+	if ctx.Err() != nil {
+		progress.interrupt()
+		s.logger.Log("search", fmt.Sprintf("async: interrupted watch run %s: processed=%d failed=%d", run.ID, run.Processed, run.Failed))
+		return
+	}
+	if err != nil {
+		s.logger.Log("search", fmt.Sprintf("async: problem reading watches for run %s: %v", run.ID, err))
+	}
+	progress.finish()
+	s.logger.Log("search", fmt.Sprintf("async: finished watch run %s: processed=%d failed=%d", run.ID, run.Processed, run.Failed))
+
+	screening, err := s.rescreenSubjects(subjectRepo)
+	if err != nil {
+		s.logger.Log("search", fmt.Sprintf("async: problem re-screening subjects: %v", err))
+	}
+	s.logger.Log("search", fmt.Sprintf("async: re-screened %d subjects: opened=%d reopened=%d cases", screening.Screened, screening.Opened, screening.Reopened))
+}
+
+// researchWatch performs a query (ID watches) or search (name watches) for the given watch and calls
+// its webhook only if the watched entity is a new match, has changed or was delisted since the last
+// notification delivered for this watch. downloadedAt is the timestamp of the data refresh which
+// triggered the re-search.
+func (s *searcher) researchWatch(ctx context.Context, w watch, downloadedAt time.Time, companyRepo companyRepository, custRepo customerRepository, webhookRepo webhookRepository) error {
+	if w.customerName != "" || w.companyName != "" {
+		return s.researchNameWatch(ctx, w, downloadedAt, webhookRepo)
+	}
+
+	last, err := webhookRepo.getLastNotification(w.id)
+	if err != nil {
+		return fmt.Errorf("problem reading last notification: %v", err)
+	}
+
+	entityID, entity, err := s.findWatchedEntity(w, companyRepo, custRepo)
+	if err != nil {
+		return err
+	}
+
+	var next watchNotification
+	if entity == nil {
+		// Nothing matched the watch, so only notify if the last entity we sent has been removed from the list.
+		if last == nil || last.entityID == "" || s.FindSDN(last.entityID) != nil {
+			return nil
+		}
+		s.logger.Log("search", fmt.Sprintf("async: watch %s entity %s was delisted", w.id, last.entityID))
+		entity, err = delistedEntity(w, last)
+		if err != nil {
+			return err
+		}
+		next.changeType = WatchDelisted
+	} else {
+		payload, hash, err := marshalWatchEntity(entity)
+		if err != nil {
+			return err
+		}
+		change, notify := watchChange(last, entityID, hash)
+		if !notify {
+			s.logger.Log("search", fmt.Sprintf("async: watch %s unchanged, skipping notification", w.id))
+			return nil
+		}
+		next = watchNotification{
+			entityID:   entityID,
+			hash:       hash,
+			payload:    payload,
+			changeType: change,
+		}
+	}
+
+	body, err := encodeWatchBody(w.id, entity, next.changeType, downloadedAt)
+	if err != nil {
+		return err
+	}
+
+	return s.deliverWatch(ctx, w, body, webhookRepo, func() error {
+		next.notifiedAt = time.Now()
+		if err := webhookRepo.recordNotification(w.id, &next); err != nil {
+			return fmt.Errorf("problem recording notification: %v", err)
+		}
+		return nil
+	})
+}
+
+// deliverWatch stores body in the webhook outbox, runs record to save what was sent and then makes the
+// first delivery attempt. Failed attempts are retried from the outbox with backoff, so the notification
+// isn't sent again on the next refresh.
+func (s *searcher) deliverWatch(ctx context.Context, w watch, body *bytes.Buffer, webhookRepo webhookRepository, record func() error) error {
+	d := newWebhookDelivery(w, body)
+	if err := webhookRepo.enqueueDelivery(d); err != nil {
+		return fmt.Errorf("problem queueing webhook delivery: %v", err)
+	}
+	if err := record(); err != nil {
+		return err
+	}
+	if err := attemptWebhookDelivery(ctx, s.logger, webhookRepo, d); err != nil {
+		return fmt.Errorf("problem calling webhook (delivery %s will be retried): %v", d.ID, err)
+	}
+	return nil
+}
+
+// researchNameWatch searches the watch's selected lists for every record at or above its minimum match
+// percentage. The webhook is only called when a record crosses that threshold which hasn't already been
+// delivered, but the payload contains all qualifying hits.
+func (s *searcher) researchNameWatch(ctx context.Context, w watch, downloadedAt time.Time, webhookRepo webhookRepository) error {
+	known, err := webhookRepo.getNameWatchHits(w.id)
+	if err != nil {
+		return fmt.Errorf("problem reading name watch hits: %v", err)
+	}
+
+	hits := s.findNameWatchHits(w)
+	current := hits.keys()
+	if !containsNewHits(known, current) {
+		s.logger.Log("search", fmt.Sprintf("async: name watch %s has no new matches, skipping notification", w.id))
+		if len(current) != len(known) {
+			// Forget hits which fell below minMatch so they're sent again if they cross it later.
+			return webhookRepo.replaceNameWatchHits(w.id, current)
+		}
+		return nil
+	}
+
+	payload, hash, err := marshalWatchEntity(hits)
+	if err != nil {
+		return err
+	}
+	body, err := encodeWatchBody(w.id, hits, WatchNewMatch, downloadedAt)
+	if err != nil {
+		return err
+	}
+	return s.deliverWatch(ctx, w, body, webhookRepo, func() error {
+		next := &watchNotification{
+			hash:       hash,
+			payload:    payload,
+			changeType: WatchNewMatch,
+			notifiedAt: time.Now(),
+		}
+		if err := webhookRepo.recordNotification(w.id, next); err != nil {
+			return fmt.Errorf("problem recording notification: %v", err)
+		}
+		return webhookRepo.replaceNameWatchHits(w.id, current)
+	})
+}
+
+// nameWatchHits holds every search result at or above a name watch's minimum match percentage.
+type nameWatchHits struct {
+	Name              string  `json:"name"`
+	MinMatch          float64 `json:"minMatch"`
+	SDNs              []SDN   `json:"SDNs"`
+	AltNames          []Alt   `json:"altNames"`
+	DeniedPersons     []DP    `json:"deniedPersons"`
+	SectoralSanctions []SSI   `json:"sectoralSanctions"`
+	BISEntities       []EL    `json:"bisEntities"`
+}
+
+// keys returns an identifier for each hit which is stable across data refreshes.
+func (h *nameWatchHits) keys() []string {
+	var out []string
+	for i := range h.SDNs {
+		out = append(out, sdnList+":"+h.SDNs[i].EntityID)
+	}
+	for i := range h.AltNames {
+		out = append(out, altNameList+":"+h.AltNames[i].AlternateIdentity.AlternateID)
+	}
+	for i := range h.DeniedPersons {
+		out = append(out, dplList+":"+h.DeniedPersons[i].id)
+	}
+	for i := range h.SectoralSanctions {
+		out = append(out, ssiList+":"+h.SectoralSanctions[i].SectoralSanction.EntityID)
+	}
+	for i := range h.BISEntities {
+		out = append(out, elList+":"+h.BISEntities[i].id)
+	}
+	return out
+}
+
+func containsNewHits(known, current []string) bool {
+	seen := make(map[string]bool, len(known))
+	for i := range known {
+		seen[known[i]] = true
+	}
+	for i := range current {
+		if !seen[current[i]] {
+			return true
+		}
+	}
+	return false
+}
+
+// findNameWatchHits searches each list selected by a name watch. Customer name watches only return
+// individuals and company name watches only return non-individuals when the list records an entity's type.
+// Records whitelisted for the watch's name are left out.
+func (s *searcher) findNameWatchHits(w watch) *nameWatchHits {
+	name, individual := w.customerName, true
+	if w.companyName != "" {
+		name, individual = w.companyName, false
+	}
+	minMatch := w.nameMinMatch()
+	hits := &nameWatchHits{
+		Name:     name,
+		MinMatch: minMatch,
+	}
+	limit := hardResultsLimit
+	wl := s.loadWhitelist()
+	whitelisted := func(list, entityID string, record interface{}) bool {
+		return wl.find("", name, list, entityID, recordHash(record)) != nil
+	}
+
+	if w.searchesList(sdnList) {
+		sdns := s.TopSDNs(limit, name)
+		for i := range sdns {
+			if whitelisted(sdnList, sdns[i].EntityID, sdns[i].SDN) {
+				continue
+			}
+			if sdns[i].match >= minMatch && isIndividual(sdns[i].SDNType) == individual {
+				hits.SDNs = append(hits.SDNs, sdns[i])
+			}
+		}
+	}
+	if w.searchesList(altNameList) {
+		alts := s.TopAltNames(limit, name)
+		for i := range alts {
+			if alts[i].match < minMatch || whitelisted(altNameList, alts[i].AlternateIdentity.AlternateID, alts[i].AlternateIdentity) {
+				continue
+			}
+			if sdn := s.FindSDN(alts[i].AlternateIdentity.EntityID); sdn != nil && isIndividual(sdn.SDNType) != individual {
+				continue
+			}
+			hits.AltNames = append(hits.AltNames, alts[i])
+		}
+	}
+	if w.searchesList(dplList) {
+		dps := s.TopDPs(limit, name)
+		for i := range dps {
+			if dps[i].match >= minMatch && !whitelisted(dplList, dps[i].id, dps[i].DeniedPerson) {
+				hits.DeniedPersons = append(hits.DeniedPersons, dps[i])
+			}
+		}
+	}
+	if w.searchesList(ssiList) {
+		ssis := s.TopSSIs(limit, name)
+		for i := range ssis {
+			if ssis[i].match >= minMatch && isIndividual(ssis[i].SectoralSanction.Type) == individual && !whitelisted(ssiList, ssis[i].SectoralSanction.EntityID, ssis[i].SectoralSanction) {
+				hits.SectoralSanctions = append(hits.SectoralSanctions, ssis[i])
+			}
+		}
+	}
+	if w.searchesList(elList) {
+		els := s.TopELs(limit, name)
+		for i := range els {
+			if els[i].match >= minMatch && !whitelisted(elList, els[i].id, els[i].Entity) {
+				hits.BISEntities = append(hits.BISEntities, els[i])
+			}
+		}
+	}
+	return hits
+}
+
+func isIndividual(tpe string) bool {
+	return strings.EqualFold(tpe, "individual")
+}
+
+// findWatchedEntity returns the Customer or Company (and its EntityID) currently matching an ID watch.
+// A nil entity is returned if nothing matches the watch.
+func (s *searcher) findWatchedEntity(w watch, companyRepo companyRepository, custRepo customerRepository) (string, interface{}, error) {
+	switch {
+	case w.customerID != "":
+		s.logger.Log("search", fmt.Sprintf("async: watch %s for customer %s found", w.id, w.customerID))
+		return s.findWatchedCustomer(w.customerID, 1.0, custRepo)
+
+	case w.companyID != "":
+		s.logger.Log("search", fmt.Sprintf("async: watch %s for company %s found", w.id, w.companyID))
+		return s.findWatchedCompany(w.companyID, 1.0, companyRepo)
+	}
+	return "", nil, nil
+}
+
+func (s *searcher) findWatchedCustomer(customerID string, match float64, repo customerRepository) (string, interface{}, error) {
+	if s.FindSDN(customerID) == nil {
+		return "", nil, nil // not on the list (anymore)
+	}
+	customer, err := getWatchedCustomer(s, customerID, match, repo)
 	if customer == nil {
-		return nil, fmt.Errorf("async: watch %s customer %v not found", watchID, customerID)
+		return "", nil, err
+	}
+	return customerID, customer, nil
+}
+
+func (s *searcher) findWatchedCompany(companyID string, match float64, repo companyRepository) (string, interface{}, error) {
+	if s.FindSDN(companyID) == nil {
+		return "", nil, nil // not on the list (anymore)
+	}
+	company, err := getWatchedCompany(s, companyID, match, repo)
+	if company == nil {
+		return "", nil, err
+	}
+	return companyID, company, nil
+}
+
+// watchChange compares a watched entity against the last delivered notification and returns
+// the type of change along with whether a notification should be sent.
+func watchChange(last *watchNotification, entityID string, hash string) (WatchChangeType, bool) {
+	switch {
+	case last == nil || last.entityID != entityID:
+		return WatchNewMatch, true
+	case last.hash != hash:
+		return WatchUpdated, true
+	}
+	return "", false
+}
+
+// delistedEntity returns the last-known Customer or Company delivered for a watch whose entity is no
+// longer on the list.
+func delistedEntity(w watch, last *watchNotification) (interface{}, error) {
+	var entity interface{}
+	if w.customerID != "" {
+		entity = &Customer{ID: last.entityID}
+	} else {
+		entity = &Company{ID: last.entityID}
+	}
+	if len(last.payload) > 0 {
+		if err := json.Unmarshal(last.payload, entity); err != nil {
+			return nil, fmt.Errorf("problem reading last-known record for %s: %v", last.entityID, err)
+		}
+	}
+	return entity, nil
+}
+
+// marshalWatchEntity returns an entity's JSON representation along with its SHA-256 hash (hex encoded).
+func marshalWatchEntity(entity interface{}) ([]byte, string, error) {
+	bs, err := json.Marshal(entity)
+	if err != nil {
+		return nil, "", fmt.Errorf("problem encoding watch entity: %v", err)
+	}
+	sum := sha256.Sum256(bs)
+	return bs, hex.EncodeToString(sum[:]), nil
+}
+
+// encodeWatchBody returns the JSON encoded form of a Customer, Company or name watch hits along with the type of change
+// which triggered the webhook and the timestamp of the data download it was found in.
+func encodeWatchBody(watchID string, entity interface{}, change WatchChangeType, downloadedAt time.Time) (*bytes.Buffer, error) {
+	var body interface{}
+	switch v := entity.(type) {
+	case *Customer:
+		body = struct {
+			*Customer
+			ChangeType   WatchChangeType `json:"changeType"`
+			DownloadedAt time.Time       `json:"downloadedAt"`
+		}{v, change, downloadedAt}
+	case *Company:
+		body = struct {
+			*Company
+			ChangeType   WatchChangeType `json:"changeType"`
+			DownloadedAt time.Time       `json:"downloadedAt"`
+		}{v, change, downloadedAt}
+	case *nameWatchHits:
+		body = struct {
+			*nameWatchHits
+			ChangeType   WatchChangeType `json:"changeType"`
+			DownloadedAt time.Time       `json:"downloadedAt"`
+		}{v, change, downloadedAt}
+	default:
+		return nil, fmt.Errorf("unknown watch %s entity %T", watchID, entity)
 	}
-	customer.Match = match
 
 	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(customer); err != nil {
-		return nil, fmt.Errorf("problem creating JSON for customer watch %s: %v", watchID, err)
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return nil, fmt.Errorf("problem creating JSON for watch %s: %v", watchID, err)
 	}
 	return &buf, nil
 }
 
-// getCompanyBody returns the JSON encoded form of a given customer by their EntityID
-func getCompanyBody(s *searcher, watchID string, companyID string, match float64, repo companyRepository) (*bytes.Buffer, error) {
+// getWatchedCustomer returns a given customer by their EntityID with the match percentage set
+func getWatchedCustomer(s *searcher, customerID string, match float64, repo customerRepository) (*Customer, error) {
+	customer, _ := getCustomerByID(customerID, s, repo)
+	if customer == nil {
+		return nil, fmt.Errorf("async: customer %v not found", customerID)
+	}
+	customer.Match = match
+	return customer, nil
+}
+
+// getWatchedCompany returns a given company by their EntityID with the match percentage set
+func getWatchedCompany(s *searcher, companyID string, match float64, repo companyRepository) (*Company, error) {
 	company, _ := getCompanyByID(companyID, s, repo)
 	if company == nil {
-		return nil, fmt.Errorf("async: watch %s company %v not found", watchID, companyID)
+		return nil, fmt.Errorf("async: company %v not found", companyID)
 	}
 	company.Match = match
-
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(company); err != nil {
-		return nil, fmt.Errorf("problem creating JSON for company watch %s: %v", watchID, err)
-	}
-	return &buf, nil
+	return company, nil
 }
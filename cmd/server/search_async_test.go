@@ -5,8 +5,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/cardonator/ofac"
+	"github.com/moov-io/base"
+
+	"github.com/go-kit/kit/log"
 )
 
 func TestSearchAsync_batchSize(t *testing.T) {
@@ -19,62 +29,360 @@ func TestSearchAsync_batchSize(t *testing.T) {
 	}
 }
 
-func TestSearchAsync_getCompanyBody(t *testing.T) {
+func TestSearchAsync_getWatchedCompany(t *testing.T) {
 	repo := createTestCompanyRepository(t)
 	defer repo.close()
 
-	body, err := getCompanyBody(companySearcher, "watchID", "21206", 1.0, repo)
+	company, err := getWatchedCompany(companySearcher, "21206", 1.0, repo)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if body == nil {
-		t.Error("empty body")
+	body, err := encodeWatchBody("watchID", company, WatchNewMatch, time.Now())
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	var company Company
-	if err := json.NewDecoder(body).Decode(&company); err != nil {
+	var payload struct {
+		Company
+		ChangeType WatchChangeType `json:"changeType"`
+	}
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
 		t.Error(err)
 	}
-	if company.ID == "" {
-		t.Errorf("empty company: %#v", company)
+	if payload.ID == "" {
+		t.Errorf("empty company: %#v", payload)
+	}
+	if (1.0 - payload.Match) > 0.001 {
+		t.Errorf("unexpected company.Match=%.2f", payload.Match)
 	}
-	if (1.0 - company.Match) > 0.001 {
-		t.Errorf("unexpected company.Match=%.2f", company.Match)
+	if payload.ChangeType != WatchNewMatch {
+		t.Errorf("unexpected changeType=%q", payload.ChangeType)
 	}
 
 	// Company not found
-	body, err = getCompanyBody(companySearcher, "watchID", "", 0.0, repo)
-	if err == nil || body != nil {
-		t.Fatal("expected error and no body")
+	company, err = getWatchedCompany(companySearcher, "", 0.0, repo)
+	if err == nil || company != nil {
+		t.Fatal("expected error and no company")
 	}
 }
 
-func TestSearchAsync_getCustomerBody(t *testing.T) {
+func TestSearchAsync_getWatchedCustomer(t *testing.T) {
 	repo := createTestCustomerRepository(t)
 	defer repo.close()
 
-	body, err := getCustomerBody(customerSearcher, "watchID", "306", 0.91, repo)
+	customer, err := getWatchedCustomer(customerSearcher, "306", 0.91, repo)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if body == nil {
-		t.Error("empty body")
+	body, err := encodeWatchBody("watchID", customer, WatchUpdated, time.Now())
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	var customer Customer
-	if err := json.NewDecoder(body).Decode(&customer); err != nil {
+	var payload struct {
+		Customer
+		ChangeType WatchChangeType `json:"changeType"`
+	}
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
 		t.Error(err)
 	}
-	if customer.ID == "" {
-		t.Errorf("empty customer: %#v", customer)
+	if payload.ID == "" {
+		t.Errorf("empty customer: %#v", payload)
+	}
+	if (0.91 - payload.Match) > 0.001 {
+		t.Errorf("unexpected customer.Match=%.2f", payload.Match)
 	}
-	if (0.91 - customer.Match) > 0.001 {
-		t.Errorf("unexpected customer.Match=%.2f", customer.Match)
+	if payload.ChangeType != WatchUpdated {
+		t.Errorf("unexpected changeType=%q", payload.ChangeType)
 	}
 
 	// Customer not found
-	body, err = getCustomerBody(customerSearcher, "watchID", "", 0.0, repo)
-	if err == nil || body != nil {
-		t.Fatal("expected error and no body")
+	customer, err = getWatchedCustomer(customerSearcher, "", 0.0, repo)
+	if err == nil || customer != nil {
+		t.Fatal("expected error and no customer")
+	}
+}
+
+func TestSearchAsync_encodeWatchBody(t *testing.T) {
+	if _, err := encodeWatchBody("watchID", "other", WatchNewMatch, time.Now()); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSearchAsync_watchChange(t *testing.T) {
+	cases := []struct {
+		last     *watchNotification
+		entityID string
+		hash     string
+		change   WatchChangeType
+		notify   bool
+	}{
+		{nil, "306", "abc", WatchNewMatch, true},
+		{&watchNotification{entityID: "306", hash: "abc"}, "306", "abc", "", false},
+		{&watchNotification{entityID: "306", hash: "abc"}, "306", "def", WatchUpdated, true},
+		{&watchNotification{entityID: "306", hash: "abc"}, "21206", "abc", WatchNewMatch, true},
+		{&watchNotification{changeType: WatchDelisted}, "306", "abc", WatchNewMatch, true},
+	}
+	for i := range cases {
+		change, notify := watchChange(cases[i].last, cases[i].entityID, cases[i].hash)
+		if change != cases[i].change || notify != cases[i].notify {
+			t.Errorf("#%d: change=%q notify=%v", i, change, notify)
+		}
+	}
+}
+
+func TestSearchAsync_researchWatchUnchanged(t *testing.T) {
+	custRepo := createTestCustomerRepository(t)
+	defer custRepo.close()
+
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	webhookRepo := &sqliteWebhookRepository{db.db}
+
+	s := &searcher{SDNs: customerSearcher.SDNs, logger: log.NewNopLogger()}
+	w := watch{id: base.ID(), customerID: "306", webhook: "https://localhost/ofac"}
+
+	customer, err := getWatchedCustomer(s, "306", 1.0, custRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, hash, err := marshalWatchEntity(customer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := webhookRepo.recordNotification(w.id, &watchNotification{entityID: "306", hash: hash, changeType: WatchNewMatch, notifiedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The Customer is unchanged so no webhook should be attempted
+	if err := s.researchWatch(context.Background(), w, time.Now(), nil, custRepo, webhookRepo); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := db.db.QueryRow(`select count(*) from webhook_stats where watch_id = ?`, w.id).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected no webhook attempts, got %d", count)
+	}
+
+	// A watch for an unknown entity which was never delivered is skipped
+	w = watch{id: base.ID(), customerID: "999", webhook: "https://localhost/ofac"}
+	if err := s.researchWatch(context.Background(), w, time.Now(), nil, custRepo, webhookRepo); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchAsync_researchWatchDelisted(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+
+	var payload struct {
+		Customer
+		ChangeType   WatchChangeType `json:"changeType"`
+		DownloadedAt time.Time       `json:"downloadedAt"`
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	trustTestServer(t, server)
+
+	custRepo := createTestCustomerRepository(t)
+	defer custRepo.close()
+
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	webhookRepo := &sqliteWebhookRepository{db.db}
+
+	// Deliver the initial notification
+	s := &searcher{SDNs: customerSearcher.SDNs, logger: log.NewNopLogger()}
+	w := watch{id: base.ID(), customerID: "306", webhook: server.URL}
+	if err := s.researchWatch(context.Background(), w, time.Now(), nil, custRepo, webhookRepo); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ID != "306" || payload.ChangeType != WatchNewMatch {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+
+	// Remove the SDN from our data and expect a delisted notification with the last-known record
+	s.SDNs = nil
+	downloadedAt := time.Now().Add(12 * time.Hour)
+	payload.Customer, payload.ChangeType = Customer{}, ""
+	if err := s.researchWatch(context.Background(), w, downloadedAt, nil, custRepo, webhookRepo); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ID != "306" || payload.ChangeType != WatchDelisted {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+	if payload.SDN == nil || payload.SDN.SDNName != "BANCO NACIONAL DE CUBA" {
+		t.Errorf("missing last-known SDN: %#v", payload.SDN)
+	}
+	if !payload.DownloadedAt.Equal(downloadedAt) {
+		t.Errorf("downloadedAt=%v expected %v", payload.DownloadedAt, downloadedAt)
+	}
+
+	// No further notifications are sent once delisted
+	payload.ChangeType = ""
+	if err := s.researchWatch(context.Background(), w, downloadedAt, nil, custRepo, webhookRepo); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ChangeType != "" {
+		t.Errorf("unexpected notification: %#v", payload)
+	}
+}
+
+func TestSearchAsync_delistedEntity(t *testing.T) {
+	entity, err := delistedEntity(watch{companyID: "21206"}, &watchNotification{entityID: "21206"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if company, ok := entity.(*Company); !ok || company.ID != "21206" {
+		t.Errorf("unexpected entity: %#v", entity)
+	}
+
+	// bad payload
+	if _, err := delistedEntity(watch{customerName: "foo"}, &watchNotification{payload: []byte("{")}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSearchAsync_findNameWatchHits(t *testing.T) {
+	s := &searcher{
+		SDNs: precomputeSDNs([]*ofac.SDN{
+			{EntityID: "2676", SDNName: "AL ZAWAHIRI, Dr. Ayman", SDNType: "individual"},
+			{EntityID: "21206", SDNName: "AL-HISN", Program: "SYRIA"},
+		}),
+		Alts: precomputeAlts([]*ofac.AlternateIdentity{
+			{EntityID: "21206", AlternateID: "33627", AlternateType: "aka", AlternateName: "AL-HISN FIRM"},
+		}),
+		DPs: dplSearcher.DPs,
+	}
+
+	// company name watch over all lists
+	hits := s.findNameWatchHits(watch{companyName: "al hisn", minMatch: 0.80})
+	if len(hits.SDNs) != 1 || hits.SDNs[0].EntityID != "21206" {
+		t.Errorf("unexpected SDNs: %#v", hits.SDNs)
+	}
+	if len(hits.AltNames) != 1 {
+		t.Errorf("unexpected alt names: %#v", hits.AltNames)
+	}
+	if keys := hits.keys(); len(keys) != 2 || keys[0] != "sdn:21206" || keys[1] != "alt:33627" {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	// customer name watch shouldn't return the company
+	hits = s.findNameWatchHits(watch{customerName: "al hisn", minMatch: 0.80})
+	if len(hits.SDNs) != 0 || len(hits.AltNames) != 0 {
+		t.Errorf("unexpected hits: %#v", hits)
+	}
+
+	// only search the DPL
+	hits = s.findNameWatchHits(watch{customerName: "AL NASER WINGS", minMatch: 0.50, lists: []string{dplList}})
+	if len(hits.SDNs) != 0 || len(hits.DeniedPersons) == 0 {
+		t.Errorf("unexpected hits: %#v", hits)
+	}
+
+	// nothing above the threshold
+	hits = s.findNameWatchHits(watch{companyName: "al hisn trading", minMatch: 0.99, lists: []string{sdnList}})
+	if len(hits.SDNs) != 0 {
+		t.Errorf("unexpected hits: %#v", hits)
+	}
+
+	// denied persons sharing a name are different hits
+	s.DPs = precomputeDPs([]*ofac.DPL{
+		{Name: "JOHN SMITH", City: "MIAMI", EffectiveDate: "01/01/2019"},
+		{Name: "JOHN SMITH", City: "DALLAS", EffectiveDate: "06/01/2019"},
+	})
+	hits = s.findNameWatchHits(watch{customerName: "john smith", minMatch: 0.90, lists: []string{dplList}})
+	if keys := hits.keys(); len(keys) != 2 || keys[0] == keys[1] || !strings.HasPrefix(keys[0], "dpl:") {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+}
+
+func TestSearchAsync_containsNewHits(t *testing.T) {
+	if containsNewHits([]string{"sdn:1"}, nil) {
+		t.Error("expected no new hits")
+	}
+	if containsNewHits([]string{"sdn:1", "alt:2"}, []string{"alt:2"}) {
+		t.Error("expected no new hits")
+	}
+	if !containsNewHits([]string{"sdn:1"}, []string{"sdn:1", "el:foo"}) {
+		t.Error("expected new hits")
+	}
+}
+
+func TestSearchAsync_researchNameWatch(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+
+	var payload struct {
+		nameWatchHits
+		ChangeType WatchChangeType `json:"changeType"`
+	}
+	calls := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	trustTestServer(t, server)
+
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	webhookRepo := &sqliteWebhookRepository{db.db}
+
+	s := &searcher{SDNs: sdnSearcher.SDNs, logger: log.NewNopLogger()}
+	w := watch{id: base.ID(), customerName: "Nayif Hawatma", minMatch: 0.90, lists: []string{sdnList}, webhook: server.URL}
+
+	// first search finds a new match
+	if err := s.researchWatch(context.Background(), w, time.Now(), nil, nil, webhookRepo); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || payload.ChangeType != WatchNewMatch || len(payload.SDNs) != 1 {
+		t.Fatalf("calls=%d unexpected payload: %#v", calls, payload)
+	}
+
+	// nothing new, so no webhook
+	if err := s.researchWatch(context.Background(), w, time.Now(), nil, nil, webhookRepo); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("unexpected webhook, calls=%d", calls)
+	}
+
+	// the match falls below the threshold and then crosses it again
+	s.SDNs = nil
+	if err := s.researchWatch(context.Background(), w, time.Now(), nil, nil, webhookRepo); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := webhookRepo.getNameWatchHits(w.id); len(keys) != 0 {
+		t.Errorf("unexpected hits: %v", keys)
+	}
+	s.SDNs = sdnSearcher.SDNs
+	if err := s.researchWatch(context.Background(), w, time.Now(), nil, nil, webhookRepo); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("expected webhook, calls=%d", calls)
 	}
 }
@@ -98,6 +98,9 @@ type searchResponse struct {
 	DeniedPersons     []DP      `json:"deniedPersons"`
 	SectoralSanctions []SSI     `json:"sectoralSanctions"`
 	BISEntities       []EL      `json:"bisEntities"`
+
+	// Whitelisted holds the whitelist entries of results left out as false positives
+	Whitelisted []*WhitelistEntry `json:"whitelisted,omitempty"`
 }
 
 func searchByAddress(logger log.Logger, searcher *searcher, req addressSearchRequest) http.HandlerFunc {
@@ -161,6 +164,8 @@ func searchViaQ(logger log.Logger, searcher *searcher, name string) http.Handler
 			SectoralSanctions: searcher.TopSSIs(limit, name),
 			BISEntities:       searcher.TopELs(limit, name),
 		}
+		response.suppressWhitelisted(searcher.loadWhitelist(), name)
+
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
 		if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -184,14 +189,17 @@ func searchByName(logger log.Logger, searcher *searcher, nameSlug string) http.H
 		ssis := searcher.TopSSIs(limit, nameSlug)
 		els := searcher.TopELs(limit, nameSlug)
 
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.WriteHeader(http.StatusOK)
-		err := json.NewEncoder(w).Encode(&searchResponse{
+		response := &searchResponse{
 			SDNs:              sdns,
 			DeniedPersons:     dps,
 			SectoralSanctions: ssis,
 			BISEntities:       els,
-		})
+		}
+		response.suppressWhitelisted(searcher.loadWhitelist(), nameSlug)
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		err := json.NewEncoder(w).Encode(response)
 		if err != nil {
 			moovhttp.Problem(w, err)
 			return
@@ -210,11 +218,14 @@ func searchByAltName(logger log.Logger, searcher *searcher, altSlug string) http
 		limit := extractSearchLimit(r)
 		alts := searcher.TopAltNames(limit, altSlug)
 
+		response := &searchResponse{
+			AltNames: alts,
+		}
+		response.suppressWhitelisted(searcher.loadWhitelist(), altSlug)
+
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
-		err := json.NewEncoder(w).Encode(&searchResponse{
-			AltNames: alts,
-		})
+		err := json.NewEncoder(w).Encode(response)
 		if err != nil {
 			moovhttp.Problem(w, err)
 			return
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net/http/httptest"
@@ -240,7 +241,7 @@ func TestSearch_liveData(t *testing.T) {
 	searcher := &searcher{
 		logger: log.NewNopLogger(),
 	}
-	if stats, err := searcher.refreshData(); err != nil {
+	if stats, err := searcher.refreshData(context.Background()); err != nil {
 		t.Fatal(err)
 	} else {
 		searcher.logger.Log("liveData", fmt.Sprintf("stats: %#v", stats))
@@ -15,23 +15,106 @@ import (
 )
 
 var (
-	// migrations holds all our SQL migrations to be done (in order)
-	migrations = []string{
-		// Customer tables
-		`create table if not exists customer_name_watches(id primary key, name, webhook, auth_token, created_at datetime, deleted_at datetime);`,
-		`create table if not exists customer_status(customer_id, user_id, note, status, created_at datetime, deleted_at datetime);`,
-		`create table if not exists customer_watches(id primary key, customer_id, webhook, auth_token, created_at datetime, deleted_at datetime);`,
-
-		// Company status
-		`create table if not exists company_name_watches(id primary key, name, webhook, auth_token, created_at datetime, deleted_at datetime);`,
-		`create table if not exists company_status(company_id, user_id, note, status, created_at datetime, deleted_at datetime);`,
-		`create table if not exists company_watches(id primary key, company_id, webhook, auth_token, created_at datetime, deleted_at datetime);`,
-
-		// OFAC download stats
-		`create table if not exists ofac_download_stats(downloaded_at datetime, sdns, alt_names, addresses, denied_persons, sectoral_sanctions, bis_entities);`,
-
-		// Webhook stats
-		`create table if not exists webhook_stats(watch_id string, attempted_at datetime, status);`,
+	// sqliteMigrations holds our numbered schema migrations (applied in order) for sqlite
+	sqliteMigrations = []migration{
+		{
+			version:     1,
+			description: "create tables",
+			up: []string{
+				// Customer tables
+				`create table if not exists customer_name_watches(id primary key, name, webhook, auth_token, created_at datetime, deleted_at datetime);`,
+				`create table if not exists customer_status(customer_id, user_id, note, status, created_at datetime, deleted_at datetime);`,
+				`create table if not exists customer_watches(id primary key, customer_id, webhook, auth_token, created_at datetime, deleted_at datetime);`,
+
+				// Company status
+				`create table if not exists company_name_watches(id primary key, name, webhook, auth_token, created_at datetime, deleted_at datetime);`,
+				`create table if not exists company_status(company_id, user_id, note, status, created_at datetime, deleted_at datetime);`,
+				`create table if not exists company_watches(id primary key, company_id, webhook, auth_token, created_at datetime, deleted_at datetime);`,
+
+				// Name watch match criteria
+				`create table if not exists name_watch_options(watch_id primary key, min_match, lists, created_at datetime);`,
+				`create table if not exists name_watch_hits(watch_id, hit_key, created_at datetime);`,
+
+				// Secrets used to sign webhook deliveries
+				`create table if not exists watch_signing_secrets(watch_id primary key, secret, created_at datetime);`,
+
+				// Notifier (webhook, kafka, nats or file) picked by a watch
+				`create table if not exists watch_notifiers(watch_id primary key, notifier, created_at datetime);`,
+
+				// OFAC download stats
+				`create table if not exists ofac_download_stats(downloaded_at datetime, sdns, alt_names, addresses, denied_persons, sectoral_sanctions, bis_entities);`,
+
+				// Webhook stats
+				`create table if not exists webhook_stats(watch_id string, attempted_at datetime, status);`,
+
+				// Progress of re-searching every watch after a data refresh
+				`create table if not exists watch_runs(run_id primary key, downloaded_at datetime, status, started, processed, failed, started_at datetime, updated_at datetime, finished_at datetime);`,
+
+				// Last delivered notification per watch
+				`create table if not exists watch_notifications(watch_id primary key, entity_id, payload_hash, payload, change_type, notified_at datetime);`,
+
+				// Webhook outbox, holding deliveries until they succeed or are dead-lettered
+				`create table if not exists webhook_deliveries(delivery_id primary key, watch_id, webhook, auth_token, body, status, attempts, last_status, last_error, next_attempt_at datetime, created_at datetime, updated_at datetime);`,
+			},
+			down: dropTables(),
+		},
+		{
+			version:     2,
+			description: "index company_id, customer_id, created_at and deleted_at",
+			up:          createIndexes(),
+			down:        dropIndexes(sqliteDatabase),
+		},
+		{
+			version:     3,
+			description: "hash chain company and customer statuses",
+			up:          addStatusHashes(),
+			down:        dropStatusHashes(sqliteDatabase),
+		},
+		{
+			version:     4,
+			description: "approve and expire status overrides",
+			up:          addStatusOverrides(sqliteDatabase),
+			down:        dropStatusOverrides(sqliteDatabase),
+		},
+		{
+			version:     5,
+			description: "create subjects and cases",
+			up: append([]string{
+				`create table if not exists subjects(subject_id primary key, name, dob, addresses, created_at datetime, updated_at datetime, screened_at datetime);`,
+				`create table if not exists cases(case_id primary key, subject_id, list, entity_id, name, match_score, status, created_at datetime, updated_at datetime);`,
+			}, createCaseIndexes()...),
+			down: dropSubjectTables(),
+		},
+		{
+			version:     6,
+			description: "assign, comment on and reopen cases",
+			up:          addCaseWorkflow(sqliteDatabase),
+			down:        dropCaseWorkflow(sqliteDatabase),
+		},
+		{
+			version:     7,
+			description: "whitelist false positives",
+			up:          addWhitelistTable(sqliteDatabase),
+			down:        dropWhitelistTable(),
+		},
+		{
+			version:     8,
+			description: "count daily requests for quotas",
+			up:          addQuotaTable(),
+			down:        dropQuotaTable(),
+		},
+		{
+			version:     9,
+			description: "record download sources and unchanged refreshes",
+			up:          addDownloadSources(sqliteDatabase),
+			down:        dropDownloadSources(sqliteDatabase),
+		},
+		{
+			version:     10,
+			description: "record why refreshes were rejected",
+			up:          addDownloadReasons(),
+			down:        dropDownloadReasons(sqliteDatabase),
+		},
 	}
 )
 
@@ -60,8 +143,7 @@ func createSqliteConnection(logger log.Logger, path string) (*sql.DB, error) {
 	return db, nil
 }
 
-// migrate runs our database migrations (defined at the top of this file)
-// over a sqlite database it creates first.
+// migrate applies every migration (defined at the top of this file) to a sqlite database.
 // To configure where on disk the sqlite db is set SQLITE_DB_PATH.
 //
 // You use db like any other database/sql driver.
@@ -69,22 +151,5 @@ func createSqliteConnection(logger log.Logger, path string) (*sql.DB, error) {
 // https://github.com/mattn/go-sqlite3/blob/master/_example/simple/simple.go
 // https://astaxie.gitbooks.io/build-web-application-with-golang/en/05.3.html
 func migrate(logger log.Logger, db *sql.DB) error {
-	if logger != nil {
-		logger.Log("sqlite", "starting database migrations")
-	}
-	for i := range migrations {
-		row := migrations[i]
-		res, err := db.Exec(row)
-		if err != nil {
-			return fmt.Errorf("migration #%d [%s...] had problem: %v", i, row[:40], err)
-		}
-		n, err := res.RowsAffected()
-		if err == nil && logger != nil {
-			logger.Log("sqlite", fmt.Sprintf("migration #%d [%s...] changed %d rows", i, row[:40], n))
-		}
-	}
-	if logger != nil {
-		logger.Log("sqlite", "finished migrations")
-	}
-	return nil
+	return migrateUp(logger, sqliteDatabase, db, sqliteMigrations, 0)
 }
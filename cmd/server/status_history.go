@@ -0,0 +1,308 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/go-kit/kit/log"
+)
+
+// StatusChange is one change of a company or customer's status, as recorded in its audit trail.
+//
+// Each change is hash chained to the change before it: Hash covers the change's fields along with
+// PrevHash, which is the Hash of the previous change (or empty for the first change).
+type StatusChange struct {
+	UserID    string    `json:"userID"`
+	Note      string    `json:"note"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	PrevHash  string    `json:"prevHash,omitempty"`
+	Hash      string    `json:"hash,omitempty"`
+
+	// OverrideID, State, ApprovedBy and ExpiresAt track an override's approval and expiry, see status_overrides.go
+	OverrideID string      `json:"overrideID,omitempty"`
+	State      StatusState `json:"state,omitempty"`
+	ApprovedBy string      `json:"approvedBy,omitempty"`
+	ExpiresAt  *time.Time  `json:"expiresAt,omitempty"`
+}
+
+// StatusHistory is every status change of a company or customer, oldest first.
+type StatusHistory struct {
+	Changes []StatusChange `json:"changes"`
+
+	// Verified is true when every hash matches its change and links to the change before it.
+	Verified bool `json:"verified"`
+}
+
+// statusHash returns the SHA-256 (hex encoded) of a status change for entityID which follows prevHash.
+func statusHash(prevHash, entityID string, change StatusChange) string {
+	// encoding the fields as a JSON array keeps their boundaries unambiguous
+	fields := []string{
+		prevHash, entityID, change.UserID, change.Note, change.Status, change.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	// approval fields are only hashed when set, so changes from before overrides needed approval still verify
+	if change.OverrideID != "" || change.State != "" || change.ApprovedBy != "" || change.ExpiresAt != nil {
+		var expiresAt string
+		if change.ExpiresAt != nil {
+			expiresAt = change.ExpiresAt.UTC().Format(time.RFC3339Nano)
+		}
+		fields = append(fields, change.OverrideID, string(change.State), change.ApprovedBy, expiresAt)
+	}
+	bs, _ := json.Marshal(fields)
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyStatusChain checks changes (oldest first) form an unbroken hash chain. Changes recorded before
+// statuses were hash chained have no hash, and are only allowed before the first hashed change.
+func verifyStatusChain(entityID string, changes []StatusChange) bool {
+	prevHash, chained := "", false
+	for i := range changes {
+		if changes[i].Hash == "" {
+			if chained {
+				return false // an unhashed change was added after the chain started
+			}
+			continue
+		}
+		chained = true
+		if changes[i].PrevHash != prevHash || changes[i].Hash != statusHash(prevHash, entityID, changes[i]) {
+			return false
+		}
+		prevHash = changes[i].Hash
+	}
+	return true
+}
+
+// appendStatus adds a hash chained status change for entityID to table (company_status or customer_status),
+// where idColumn holds the entity's ID.
+func appendStatus(db *sql.DB, table, idColumn, entityID string, change StatusChange) error {
+	_, err := appendStatusAfter(db, table, idColumn, entityID, func(*StatusChange) (*StatusChange, error) {
+		return &change, nil
+	})
+	return err
+}
+
+// appendStatusAfter adds the status change returned by next, which is called with entityID's newest change
+// (or nil) read in the same transaction, so next can check what its change follows. An error from next is
+// returned as is.
+//
+// A unique index on (idColumn, prev_hash) prevents two changes from following the same change, so the
+// append is retried (calling next again) when another change for entityID was appended first.
+func appendStatusAfter(db *sql.DB, table, idColumn, entityID string, next func(latest *StatusChange) (*StatusChange, error)) (*StatusChange, error) {
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		var change *StatusChange
+		var retry bool
+		change, retry, err = appendStatusOnce(db, table, idColumn, entityID, next)
+		if err == nil || !retry {
+			return change, err
+		}
+	}
+	return nil, fmt.Errorf("problem appending %s status for %s: %v", table, entityID, err)
+}
+
+// appendStatusOnce returns retry when the change conflicted with another one appended first
+func appendStatusOnce(db *sql.DB, table, idColumn, entityID string, next func(latest *StatusChange) (*StatusChange, error)) (*StatusChange, bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+
+	query := `select ` + statusColumns + ` from ` + table + ` where ` + idColumn + ` = ? order by created_at desc limit 1;`
+	changes, err := queryStatusChanges(tx, query, entityID)
+	if err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	var latest *StatusChange
+	if len(changes) > 0 {
+		latest = &changes[0]
+	}
+	change, err := next(latest)
+	if err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+
+	query = `select hash from ` + table + ` where ` + idColumn + ` = ? and hash is not null order by created_at desc limit 1;`
+	var prevHash string
+	if err := tx.QueryRow(query, entityID).Scan(&prevHash); err != nil && err != sql.ErrNoRows {
+		tx.Rollback()
+		return nil, false, err
+	}
+
+	query = `insert into ` + table + ` (` + idColumn + `, user_id, note, status, created_at, prev_hash, hash, override_id, state, approved_by, expires_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	if _, err := tx.Exec(query, entityID, change.UserID, change.Note, change.Status, change.CreatedAt, prevHash, statusHash(prevHash, entityID, *change), nullIfEmpty(change.OverrideID), nullIfEmpty(string(change.State)), nullIfEmpty(change.ApprovedBy), change.ExpiresAt); err != nil {
+		tx.Rollback()
+		return nil, isUniqueViolation(err), err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, isUniqueViolation(err), err
+	}
+	return change, false, nil
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// statusColumns are read from company_status or customer_status by queryStatusChanges
+const statusColumns = `user_id, note, status, created_at, coalesce(prev_hash, ''), coalesce(hash, ''), coalesce(override_id, ''), coalesce(state, ''), coalesce(approved_by, ''), expires_at`
+
+// readStatusHistory returns every status change for entityID from table, oldest first.
+func readStatusHistory(db *sql.DB, table, idColumn, entityID string) ([]StatusChange, error) {
+	query := `select ` + statusColumns + ` from ` + table + ` where ` + idColumn + ` = ? order by created_at asc;`
+	return queryStatusChanges(db, query, entityID)
+}
+
+func queryStatusChanges(db queryer, query string, args ...interface{}) ([]StatusChange, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []StatusChange
+	for rows.Next() {
+		var change StatusChange
+		var note sql.NullString
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&change.UserID, &note, &change.Status, &change.CreatedAt, &change.PrevHash, &change.Hash, &change.OverrideID, &change.State, &change.ApprovedBy, &expiresAt); err != nil {
+			return nil, fmt.Errorf("queryStatusChanges: %v", err)
+		}
+		change.Note = note.String
+		if expiresAt.Valid {
+			change.ExpiresAt = &expiresAt.Time
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// addStatusHashes is the migration which adds hash chaining to company and customer statuses
+func addStatusHashes() []string {
+	var out []string
+	for _, t := range []struct{ table, idColumn string }{{"company_status", "company_id"}, {"customer_status", "customer_id"}} {
+		out = append(out,
+			fmt.Sprintf(`alter table %s add column prev_hash varchar(64);`, t.table),
+			fmt.Sprintf(`alter table %s add column hash varchar(64);`, t.table),
+			fmt.Sprintf(`create unique index idx_%s_chain on %s (%s, prev_hash);`, t.table, t.table, t.idColumn),
+		)
+	}
+	return out
+}
+
+// dropStatusHashes reverts addStatusHashes. sqlite can't drop columns, so its tables are copied without them.
+func dropStatusHashes(databaseType string) []string {
+	var out []string
+	for _, t := range []struct{ table, idColumn string }{{"company_status", "company_id"}, {"customer_status", "customer_id"}} {
+		switch databaseType {
+		case sqliteDatabase:
+			out = append(out, fmt.Sprintf(`drop index if exists idx_%s_chain;`, t.table))
+			out = append(out, sqliteCopyStatusTable(t.table, t.idColumn, []string{"user_id", "note", "status", "created_at datetime", "deleted_at datetime"}, false)...)
+		case mysqlDatabase:
+			out = append(out,
+				fmt.Sprintf(`drop index idx_%s_chain on %s;`, t.table, t.table),
+				fmt.Sprintf(`alter table %s drop column prev_hash, drop column hash;`, t.table),
+			)
+		default:
+			out = append(out,
+				fmt.Sprintf(`drop index if exists idx_%s_chain;`, t.table),
+				fmt.Sprintf(`alter table %s drop column prev_hash, drop column hash;`, t.table),
+			)
+		}
+	}
+	return out
+}
+
+// sqliteCopyStatusTable replaces a status table with a copy which only has columns (given as definitions),
+// as sqlite can't drop columns. Copying a table loses its indexes, so they're created again.
+func sqliteCopyStatusTable(table, idColumn string, columns []string, chained bool) []string {
+	names := []string{idColumn}
+	for i := range columns {
+		names = append(names, strings.Fields(columns[i])[0])
+	}
+	out := []string{
+		fmt.Sprintf(`create table %s_copy(%s, %s);`, table, idColumn, strings.Join(columns, ", ")),
+		fmt.Sprintf(`insert into %s_copy select %s from %s;`, table, strings.Join(names, ", "), table),
+		fmt.Sprintf(`drop table %s;`, table),
+		fmt.Sprintf(`alter table %s_copy rename to %s;`, table, table),
+	}
+	for _, idx := range schemaIndexes {
+		if idx.table != table {
+			continue
+		}
+		for _, column := range idx.columns {
+			out = append(out, fmt.Sprintf(`create index %s on %s (%s);`, indexName(table, column), table, column))
+		}
+	}
+	if chained {
+		out = append(out, fmt.Sprintf(`create unique index idx_%s_chain on %s (%s, prev_hash);`, table, table, idColumn))
+	}
+	return out
+}
+
+func getCompanyStatusHistory(logger log.Logger, companyRepo companyRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		companyID := getCompanyID(w, r)
+		if companyID == "" {
+			return
+		}
+		changes, err := companyRepo.getCompanyStatusHistory(companyID)
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		writeStatusHistory(logger, w, "company", companyID, changes)
+	}
+}
+
+func getCustomerStatusHistory(logger log.Logger, customerRepo customerRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		customerID := getCustomerID(w, r)
+		if customerID == "" {
+			return
+		}
+		changes, err := customerRepo.getCustomerStatusHistory(customerID)
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		writeStatusHistory(logger, w, "customer", customerID, changes)
+	}
+}
+
+func writeStatusHistory(logger log.Logger, w http.ResponseWriter, entity, entityID string, changes []StatusChange) {
+	history := StatusHistory{
+		Changes:  changes,
+		Verified: verifyStatusChain(entityID, changes),
+	}
+	if history.Changes == nil {
+		history.Changes = []StatusChange{}
+	}
+	if !history.Verified && logger != nil {
+		logger.Log("status", fmt.Sprintf("status history of %s %s failed verification", entity, entityID))
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
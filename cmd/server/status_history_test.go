@@ -0,0 +1,185 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestStatusHistory__verifyStatusChain(t *testing.T) {
+	now := time.Now()
+	chain := func(changes ...StatusChange) []StatusChange {
+		prevHash := ""
+		for i := range changes {
+			changes[i].PrevHash = prevHash
+			changes[i].Hash = statusHash(prevHash, "foo", changes[i])
+			prevHash = changes[i].Hash
+		}
+		return changes
+	}
+	changes := chain(
+		StatusChange{UserID: "a", Status: "unsafe", CreatedAt: now},
+		StatusChange{UserID: "b", Note: "false positive", Status: "exception", CreatedAt: now.Add(time.Second)},
+		StatusChange{UserID: "c", Status: "unsafe", CreatedAt: now.Add(2 * time.Second)},
+	)
+	if !verifyStatusChain("foo", changes) {
+		t.Fatal("expected valid chain")
+	}
+	if verifyStatusChain("bar", changes) {
+		t.Error("chain is for another entity")
+	}
+	if !verifyStatusChain("foo", nil) {
+		t.Error("empty history is valid")
+	}
+
+	// edited change
+	edited := append([]StatusChange(nil), changes...)
+	edited[1].Note = "oops"
+	if verifyStatusChain("foo", edited) {
+		t.Error("expected edited change to fail")
+	}
+
+	// removed change
+	if verifyStatusChain("foo", []StatusChange{changes[0], changes[2]}) {
+		t.Error("expected removed change to fail")
+	}
+
+	// changes from before hash chaining
+	legacy := StatusChange{UserID: "z", Status: "unsafe", CreatedAt: now.Add(-time.Hour)}
+	if !verifyStatusChain("foo", append([]StatusChange{legacy}, changes...)) {
+		t.Error("expected unhashed changes before the chain to pass")
+	}
+	if verifyStatusChain("foo", append(append([]StatusChange(nil), changes...), legacy)) {
+		t.Error("expected unhashed change after the chain to fail")
+	}
+}
+
+func TestStatusHistory__repository(t *testing.T) {
+	repo := createTestCompanyRepository(t)
+	defer repo.close()
+
+	for _, status := range []CompanyBlockStatus{CompanyUnsafe, CompanyException, CompanyUnsafe} {
+		if err := repo.upsertCompanyStatus("foo", &CompanyStatus{UserID: "user", Status: status, CreatedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	changes, err := repo.getCompanyStatusHistory("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 || changes[0].PrevHash != "" || changes[1].PrevHash != changes[0].Hash {
+		t.Fatalf("unexpected changes: %#v", changes)
+	}
+	if !verifyStatusChain("foo", changes) {
+		t.Error("expected valid chain")
+	}
+
+	// a second change following the same change is rejected
+	query := `insert into company_status (company_id, user_id, note, status, created_at, prev_hash, hash) values (?, ?, ?, ?, ?, ?, ?);`
+	if _, err := repo.db.Exec(query, "foo", "user", "", "unsafe", time.Now(), changes[0].Hash, "fork"); !isUniqueViolation(err) {
+		t.Errorf("expected unique index conflict: %v", err)
+	}
+
+	// appends are checked against the newest change, and only retried after a conflict
+	calls := 0
+	_, err = appendStatusAfter(repo.db, "company_status", "company_id", "foo", func(latest *StatusChange) (*StatusChange, error) {
+		calls++
+		if latest == nil || latest.Hash != changes[2].Hash {
+			t.Errorf("unexpected latest change: %#v", latest)
+		}
+		return nil, errOverrideNotPending
+	})
+	if err != errOverrideNotPending || calls != 1 {
+		t.Errorf("calls=%d err=%v", calls, err)
+	}
+
+	// other companies have their own chain
+	if changes, err := repo.getCompanyStatusHistory("bar"); err != nil || len(changes) != 0 {
+		t.Errorf("changes=%#v err=%v", changes, err)
+	}
+}
+
+func TestStatusHistory__companyRoute(t *testing.T) {
+	companyRepo := createTestCompanyRepository(t)
+	defer companyRepo.close()
+	watchRepo := createTestWatchRepository(t)
+	defer watchRepo.close()
+
+	router := mux.NewRouter()
+	addCompanyRoutes(nil, router, companySearcher, companyRepo, watchRepo)
+
+	for _, status := range []CompanyBlockStatus{CompanyUnsafe, CompanyException} {
+		if err := companyRepo.upsertCompanyStatus("21206", &CompanyStatus{UserID: "user", Note: "checked", Status: status, CreatedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	history := readTestStatusHistory(t, router, "/companies/21206/status/history")
+	if !history.Verified || len(history.Changes) != 2 {
+		t.Fatalf("unexpected history: %#v", history)
+	}
+	if c := history.Changes[1]; c.UserID != "user" || c.Note != "checked" || c.Status != "exception" || c.CreatedAt.IsZero() {
+		t.Errorf("unexpected change: %#v", c)
+	}
+
+	// tampering with a change fails verification
+	if _, err := companyRepo.db.Exec(`update company_status set note = 'edited' where company_id = ?;`, "21206"); err != nil {
+		t.Fatal(err)
+	}
+	if history := readTestStatusHistory(t, router, "/companies/21206/status/history"); history.Verified {
+		t.Errorf("expected tampered history to fail: %#v", history)
+	}
+}
+
+func TestStatusHistory__customerRoute(t *testing.T) {
+	customerRepo := createTestCustomerRepository(t)
+	defer customerRepo.close()
+	watchRepo := createTestWatchRepository(t)
+	defer watchRepo.close()
+
+	router := mux.NewRouter()
+	addCustomerRoutes(nil, router, customerSearcher, customerRepo, watchRepo)
+
+	// no changes
+	history := readTestStatusHistory(t, router, "/customers/306/status/history")
+	if !history.Verified || history.Changes == nil || len(history.Changes) != 0 {
+		t.Errorf("unexpected history: %#v", history)
+	}
+
+	if err := customerRepo.upsertCustomerStatus("306", &CustomerStatus{UserID: "user", Status: CustomerUnsafe, CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	history = readTestStatusHistory(t, router, "/customers/306/status/history")
+	if !history.Verified || len(history.Changes) != 1 || history.Changes[0].Hash == "" {
+		t.Errorf("unexpected history: %#v", history)
+	}
+}
+
+func readTestStatusHistory(t *testing.T, router *mux.Router, path string) StatusHistory {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("x-user-id", "test")
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	var history StatusHistory
+	if err := json.NewDecoder(w.Body).Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+	return history
+}
@@ -0,0 +1,329 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/moov-io/base"
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+// StatusState is where a status override is in its approval.
+//
+// Exception overrides are pending until they're approved by a second user, unsafe overrides are approved
+// when they're made. Approved overrides are applied until they expire or are replaced by a later override.
+type StatusState string
+
+const (
+	StatusPending  StatusState = "pending"
+	StatusApproved StatusState = "approved"
+	StatusExpired  StatusState = "expired"
+)
+
+var (
+	// statusExceptionExpiry is the longest an exception override is applied for
+	statusExceptionExpiry = 30 * 24 * time.Hour
+
+	// statusExpiryInterval is how often expired overrides are reverted
+	statusExpiryInterval = 1 * time.Minute
+
+	errNoOverrideID       = errors.New("no overrideID found")
+	errOverrideNotFound   = errors.New("status override not found")
+	errOverrideNotPending = errors.New("status override isn't pending, it was already approved or replaced by a later change")
+	errOverrideExpired    = errors.New("status override has expired")
+	errSelfApproval       = errors.New("status override must be approved by someone other than its requester")
+)
+
+func init() {
+	statusExceptionExpiry = readWebhookDuration(os.Getenv("STATUS_EXCEPTION_EXPIRY"), statusExceptionExpiry)
+	statusExpiryInterval = readWebhookDuration(os.Getenv("STATUS_EXPIRY_INTERVAL"), statusExpiryInterval)
+}
+
+// newStatusOverride returns the change requested by userID. Exceptions are pending (until approved) and
+// expire after statusExceptionExpiry unless expiresAt is sooner.
+func newStatusOverride(userID, note, status string, exception bool, expiresAt *time.Time, now time.Time) (StatusChange, error) {
+	now = now.Truncate(time.Microsecond)
+	change := StatusChange{
+		UserID:     userID,
+		Note:       note,
+		Status:     status,
+		CreatedAt:  now,
+		OverrideID: base.ID(),
+		State:      StatusApproved,
+	}
+	if expiresAt != nil && !expiresAt.IsZero() {
+		if !expiresAt.After(now) {
+			return change, errors.New("expiresAt must be in the future")
+		}
+		// sqlite compares times as strings, so keep them in one time zone
+		t := expiresAt.Local().Truncate(time.Microsecond)
+		change.ExpiresAt = &t
+	}
+	if exception {
+		change.State = StatusPending
+		latest := now.Add(statusExceptionExpiry).Local()
+		if change.ExpiresAt == nil {
+			change.ExpiresAt = &latest
+		}
+		if change.ExpiresAt.After(latest) {
+			return change, fmt.Errorf("exceptions expire within %v", statusExceptionExpiry)
+		}
+	}
+	return change, nil
+}
+
+// readCurrentStatus returns the status in effect for entityID from table: its newest approved change, unless
+// that change has expired.
+func readCurrentStatus(db *sql.DB, table, idColumn, entityID string, now time.Time) (*StatusChange, error) {
+	query := `select ` + statusColumns + ` from ` + table + ` where ` + idColumn + ` = ? and deleted_at is null and (state is null or state in (?, ?))
+order by created_at desc limit 1;`
+	changes, err := queryStatusChanges(db, query, entityID, StatusApproved, StatusExpired)
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+	current := changes[0]
+	if current.State == StatusExpired || (current.ExpiresAt != nil && !current.ExpiresAt.After(now)) {
+		return nil, nil
+	}
+	return &current, nil
+}
+
+// approveStatus approves the pending override overrideID of entityID on behalf of approverID, who must not be
+// the override's requester. Only the entity's newest change can be approved.
+func approveStatus(db *sql.DB, table, idColumn, entityID, overrideID, approverID string, now time.Time) (*StatusChange, error) {
+	query := `select ` + statusColumns + ` from ` + table + ` where ` + idColumn + ` = ? and override_id = ? limit 1;`
+	changes, err := queryStatusChanges(db, query, entityID, overrideID)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, errOverrideNotFound
+	}
+
+	// The override is checked in the transaction appending its approval, so it can't be approved twice or
+	// after a later change
+	return appendStatusAfter(db, table, idColumn, entityID, func(latest *StatusChange) (*StatusChange, error) {
+		if latest == nil || latest.OverrideID != overrideID || latest.State != StatusPending {
+			return nil, errOverrideNotPending
+		}
+		if latest.UserID == approverID {
+			return nil, errSelfApproval
+		}
+		if latest.ExpiresAt != nil && !latest.ExpiresAt.After(now) {
+			return nil, errOverrideExpired
+		}
+		approved := *latest
+		approved.State = StatusApproved
+		approved.ApprovedBy = approverID
+		approved.CreatedAt = now.Truncate(time.Microsecond)
+		approved.PrevHash, approved.Hash = "", ""
+		return &approved, nil
+	})
+}
+
+// expireStatuses reverts each override in table which is in effect but expired at or before now, by
+// appending an expired change. It returns how many overrides were reverted.
+func expireStatuses(db *sql.DB, table, idColumn string, now time.Time) (int, error) {
+	query := `select ` + idColumn + ` from ` + table + ` s where s.state = ? and s.expires_at <= ?
+and not exists (select 1 from ` + table + ` later where later.` + idColumn + ` = s.` + idColumn + ` and later.created_at > s.created_at and (later.state is null or later.state in (?, ?)));`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(StatusApproved, now, StatusApproved, StatusExpired)
+	if err != nil {
+		return 0, err
+	}
+	var entityIDs []string
+	for rows.Next() {
+		var entityID string
+		if err := rows.Scan(&entityID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("expireStatuses: %v", err)
+		}
+		entityIDs = append(entityIDs, entityID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, entityID := range entityIDs {
+		current, err := readCurrentStatus(db, table, idColumn, entityID, time.Time{})
+		if err != nil {
+			return expired, err
+		}
+		if current == nil || current.ExpiresAt == nil || current.ExpiresAt.After(now) {
+			continue
+		}
+		change := *current
+		change.State = StatusExpired
+		change.CreatedAt = now.Truncate(time.Microsecond)
+		change.PrevHash, change.Hash = "", ""
+		if err := appendStatus(db, table, idColumn, entityID, change); err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// spawnStatusExpiry reverts expired company and customer overrides every interval until stop is closed.
+func spawnStatusExpiry(stop <-chan struct{}, logger log.Logger, interval time.Duration, companyRepo companyRepository, custRepo customerRepository) {
+	for {
+		select {
+		case <-time.After(interval):
+			expireStatusOverrides(logger, companyRepo, custRepo, time.Now())
+		case <-stop:
+			return
+		}
+	}
+}
+
+func expireStatusOverrides(logger log.Logger, companyRepo companyRepository, custRepo customerRepository, now time.Time) {
+	logf := func(format string, args ...interface{}) {
+		if logger != nil {
+			logger.Log("status", fmt.Sprintf(format, args...))
+		}
+	}
+	if n, err := companyRepo.expireCompanyStatuses(now); err != nil {
+		logf("problem expiring company statuses: %v", err)
+	} else if n > 0 {
+		logf("reverted %d expired company statuses", n)
+	}
+	if n, err := custRepo.expireCustomerStatuses(now); err != nil {
+		logf("problem expiring customer statuses: %v", err)
+	} else if n > 0 {
+		logf("reverted %d expired customer statuses", n)
+	}
+}
+
+// addStatusOverrides is the migration which adds approval and expiry to company and customer statuses
+func addStatusOverrides(databaseType string) []string {
+	timestamp := map[string]string{sqliteDatabase: "datetime", postgresDatabase: "timestamptz", mysqlDatabase: "datetime(6)"}[databaseType]
+
+	var out []string
+	for _, table := range []string{"company_status", "customer_status"} {
+		out = append(out,
+			fmt.Sprintf(`alter table %s add column override_id varchar(64);`, table),
+			fmt.Sprintf(`alter table %s add column state varchar(16);`, table),
+			fmt.Sprintf(`alter table %s add column approved_by text;`, table),
+			fmt.Sprintf(`alter table %s add column expires_at %s;`, table, timestamp),
+		)
+	}
+	return out
+}
+
+// dropStatusOverrides reverts addStatusOverrides
+func dropStatusOverrides(databaseType string) []string {
+	var out []string
+	for _, t := range []struct{ table, idColumn string }{{"company_status", "company_id"}, {"customer_status", "customer_id"}} {
+		if databaseType == sqliteDatabase {
+			columns := []string{"user_id", "note", "status", "created_at datetime", "deleted_at datetime", "prev_hash varchar(64)", "hash varchar(64)"}
+			out = append(out, sqliteCopyStatusTable(t.table, t.idColumn, columns, true)...)
+			continue
+		}
+		out = append(out, fmt.Sprintf(`alter table %s drop column override_id, drop column state, drop column approved_by, drop column expires_at;`, t.table))
+	}
+	return out
+}
+
+func getOverrideID(w http.ResponseWriter, r *http.Request) string {
+	v, ok := mux.Vars(r)["overrideID"]
+	if !ok || v == "" {
+		moovhttp.Problem(w, errNoOverrideID)
+		return ""
+	}
+	return v
+}
+
+// writeStatusOverrideError responds with the HTTP status matching err from approving an override
+func writeStatusOverrideError(w http.ResponseWriter, err error) {
+	switch err {
+	case errOverrideNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case errSelfApproval:
+		writeStatusOverrideProblem(w, http.StatusForbidden, err)
+	case errOverrideNotPending, errOverrideExpired:
+		writeStatusOverrideProblem(w, http.StatusConflict, err)
+	default:
+		moovhttp.Problem(w, err)
+	}
+}
+
+func writeStatusOverrideProblem(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func approveCompanyStatus(logger log.Logger, companyRepo companyRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		companyID := getCompanyID(w, r)
+		if companyID == "" {
+			return
+		}
+		overrideID := getOverrideID(w, r)
+		if overrideID == "" {
+			return
+		}
+		userID := moovhttp.GetUserId(r)
+		if userID == "" {
+			moovhttp.Problem(w, errNoUserID)
+			return
+		}
+		status, err := companyRepo.approveCompanyStatus(companyID, overrideID, userID)
+		if err != nil {
+			writeStatusOverrideError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(status)
+	}
+}
+
+func approveCustomerStatus(logger log.Logger, custRepo customerRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		customerID := getCustomerID(w, r)
+		if customerID == "" {
+			return
+		}
+		overrideID := getOverrideID(w, r)
+		if overrideID == "" {
+			return
+		}
+		userID := moovhttp.GetUserId(r)
+		if userID == "" {
+			moovhttp.Problem(w, errNoUserID)
+			return
+		}
+		status, err := custRepo.approveCustomerStatus(customerID, overrideID, userID)
+		if err != nil {
+			writeStatusOverrideError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(status)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestStatusOverrides__newStatusOverride(t *testing.T) {
+	now := time.Now()
+
+	change, err := newStatusOverride("user", "", "unsafe", false, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.State != StatusApproved || change.OverrideID == "" || change.ExpiresAt != nil {
+		t.Errorf("unexpected change: %#v", change)
+	}
+
+	change, err = newStatusOverride("user", "", "exception", true, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.State != StatusPending || change.ExpiresAt == nil || !change.ExpiresAt.Equal(now.Truncate(time.Microsecond).Add(statusExceptionExpiry)) {
+		t.Errorf("unexpected change: %#v", change)
+	}
+
+	soon := now.Add(time.Hour)
+	if change, err := newStatusOverride("user", "", "exception", true, &soon, now); err != nil || !change.ExpiresAt.Equal(soon.Truncate(time.Microsecond)) {
+		t.Errorf("change=%#v err=%v", change, err)
+	}
+
+	past := now.Add(-1 * time.Hour)
+	if _, err := newStatusOverride("user", "", "unsafe", false, &past, now); err == nil {
+		t.Error("expected error")
+	}
+	later := now.Add(statusExceptionExpiry + time.Hour)
+	if _, err := newStatusOverride("user", "", "exception", true, &later, now); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestStatusOverrides__companyApproval(t *testing.T) {
+	companyRepo := createTestCompanyRepository(t)
+	defer companyRepo.close()
+	watchRepo := createTestWatchRepository(t)
+	defer watchRepo.close()
+
+	router := mux.NewRouter()
+	addCompanyRoutes(nil, router, companySearcher, companyRepo, watchRepo)
+
+	// request an exception
+	w := serveStatusOverride(router, "PUT", "/companies/foo", "alice", `{"status": "exception", "notes": "false positive"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	var pending CompanyStatus
+	if err := json.NewDecoder(w.Body).Decode(&pending); err != nil {
+		t.Fatal(err)
+	}
+	if pending.State != StatusPending || pending.OverrideID == "" || pending.ExpiresAt == nil {
+		t.Fatalf("unexpected status: %#v", pending)
+	}
+
+	// pending overrides aren't applied
+	if status, err := companyRepo.getCompanyStatus("foo"); err != nil || status != nil {
+		t.Errorf("status=%#v err=%v", status, err)
+	}
+
+	approvePath := fmt.Sprintf("/companies/foo/status/%s/approve", pending.OverrideID)
+	if w := serveStatusOverride(router, "POST", approvePath, "alice", ""); w.Code != http.StatusForbidden {
+		t.Errorf("self approval: bogus status code: %d", w.Code)
+	}
+	if w := serveStatusOverride(router, "POST", "/companies/foo/status/missing/approve", "bob", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown override: bogus status code: %d", w.Code)
+	}
+	w = serveStatusOverride(router, "POST", approvePath, "bob", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveStatusOverride(router, "POST", approvePath, "carol", ""); w.Code != http.StatusConflict {
+		t.Errorf("second approval: bogus status code: %d", w.Code)
+	}
+
+	status, err := companyRepo.getCompanyStatus("foo")
+	if err != nil || status == nil {
+		t.Fatalf("status=%#v err=%v", status, err)
+	}
+	if status.Status != CompanyException || status.UserID != "alice" || status.ApprovedBy != "bob" || status.State != StatusApproved || status.Note != "false positive" {
+		t.Errorf("unexpected status: %#v", status)
+	}
+
+	// each step is in the audit trail
+	changes, err := companyRepo.getCompanyStatusHistory("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].State != StatusPending || changes[1].State != StatusApproved || !verifyStatusChain("foo", changes) {
+		t.Errorf("unexpected history: %#v", changes)
+	}
+}
+
+func TestStatusOverrides__replacedBeforeApproval(t *testing.T) {
+	companyRepo := createTestCompanyRepository(t)
+	defer companyRepo.close()
+	watchRepo := createTestWatchRepository(t)
+	defer watchRepo.close()
+
+	router := mux.NewRouter()
+	addCompanyRoutes(nil, router, companySearcher, companyRepo, watchRepo)
+
+	w := serveStatusOverride(router, "PUT", "/companies/foo", "alice", `{"status": "exception"}`)
+	var pending CompanyStatus
+	json.NewDecoder(w.Body).Decode(&pending)
+
+	// unsafe overrides are applied without approval
+	time.Sleep(2 * time.Millisecond)
+	if w := serveStatusOverride(router, "PUT", "/companies/foo", "carol", `{"status": "unsafe"}`); w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d", w.Code)
+	}
+	if status, _ := companyRepo.getCompanyStatus("foo"); status == nil || status.Status != CompanyUnsafe {
+		t.Errorf("unexpected status: %#v", status)
+	}
+
+	// the exception was replaced, so it can't be approved
+	approvePath := fmt.Sprintf("/companies/foo/status/%s/approve", pending.OverrideID)
+	if w := serveStatusOverride(router, "POST", approvePath, "bob", ""); w.Code != http.StatusConflict {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+}
+
+func TestStatusOverrides__expiry(t *testing.T) {
+	companyRepo := createTestCompanyRepository(t)
+	defer companyRepo.close()
+	custRepo := &sqliteCustomerRepository{companyRepo.db}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Minute).Truncate(time.Microsecond)
+	status := &CompanyStatus{UserID: "alice", Status: CompanyException, CreatedAt: now, OverrideID: "override", State: StatusApproved, ApprovedBy: "bob", ExpiresAt: &expiresAt}
+	if err := companyRepo.upsertCompanyStatus("foo", status); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := companyRepo.getCompanyStatus("foo"); status == nil {
+		t.Fatal("expected status")
+	}
+
+	// nothing has expired yet
+	if n, err := companyRepo.expireCompanyStatuses(now); err != nil || n != 0 {
+		t.Errorf("n=%d err=%v", n, err)
+	}
+
+	later := now.Add(2 * time.Minute)
+	expireStatusOverrides(nil, companyRepo, custRepo, later)
+	if status, err := companyRepo.getCompanyStatus("foo"); err != nil || status != nil {
+		t.Errorf("status=%#v err=%v", status, err)
+	}
+	if n, err := companyRepo.expireCompanyStatuses(later); err != nil || n != 0 {
+		t.Errorf("n=%d err=%v", n, err)
+	}
+
+	changes, _ := companyRepo.getCompanyStatusHistory("foo")
+	if len(changes) != 2 || changes[1].State != StatusExpired || !verifyStatusChain("foo", changes) {
+		t.Errorf("unexpected history: %#v", changes)
+	}
+}
+
+func TestStatusOverrides__customerApproval(t *testing.T) {
+	custRepo := createTestCustomerRepository(t)
+	defer custRepo.close()
+	watchRepo := createTestWatchRepository(t)
+	defer watchRepo.close()
+
+	router := mux.NewRouter()
+	addCustomerRoutes(nil, router, customerSearcher, custRepo, watchRepo)
+
+	w := serveStatusOverride(router, "PUT", "/customers/foo", "alice", `{"status": "exception"}`)
+	var pending CustomerStatus
+	if err := json.NewDecoder(w.Body).Decode(&pending); err != nil {
+		t.Fatal(err)
+	}
+	if pending.State != StatusPending {
+		t.Fatalf("unexpected status: %#v", pending)
+	}
+	w = serveStatusOverride(router, "POST", fmt.Sprintf("/customers/foo/status/%s/approve", pending.OverrideID), "bob", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	if status, _ := custRepo.getCustomerStatus("foo"); status == nil || status.Status != CustomerException || status.ApprovedBy != "bob" {
+		t.Errorf("unexpected status: %#v", status)
+	}
+}
+
+func serveStatusOverride(router *mux.Router, method, path, userID, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("x-user-id", userID)
+	router.ServeHTTP(w, req)
+	w.Flush()
+	return w
+}
@@ -0,0 +1,367 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base"
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+var (
+	// subjectMinMatch is the lowest match percentage which opens a case for a subject
+	subjectMinMatch = defaultNameWatchMinMatch
+
+	// subjectScreeningBatchSize is how many subjects are read at once when re-screening every subject
+	subjectScreeningBatchSize = 100
+
+	errNoSubjectID   = errors.New("no subjectID found")
+	errNoSubjectName = errors.New("subject has no name")
+)
+
+func init() {
+	subjectMinMatch = readSubjectMinMatch(os.Getenv("SUBJECT_MIN_MATCH"))
+}
+
+func readSubjectMinMatch(str string) float64 {
+	if n, _ := strconv.ParseFloat(str, 64); n > 0 && n <= 1 {
+		return n
+	}
+	return subjectMinMatch
+}
+
+// Subject is one of our own customer records which is screened against the sanctions lists when it's
+// created and after every data refresh. Matches at or above subjectMinMatch are saved as Cases.
+//
+// Only the Name is screened. DOB and Addresses are informational, kept for analysts reviewing the
+// subject's cases, and don't qualify or rule out hits.
+type Subject struct {
+	ID        string           `json:"subjectID"`
+	Name      string           `json:"name"`
+	DOB       string           `json:"dob,omitempty"`
+	Addresses []SubjectAddress `json:"addresses,omitempty"`
+
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	ScreenedAt *time.Time `json:"screenedAt,omitempty"`
+}
+
+// SubjectAddress is a postal address of a Subject
+type SubjectAddress struct {
+	Address    string `json:"address,omitempty"`
+	City       string `json:"city,omitempty"`
+	State      string `json:"state,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+func (s *Subject) validate() error {
+	if s.ID == "" {
+		return errNoSubjectID
+	}
+	if strings.TrimSpace(s.Name) == "" {
+		return errNoSubjectName
+	}
+	if s.DOB != "" {
+		if _, err := time.Parse("2006-01-02", s.DOB); err != nil {
+			return fmt.Errorf("invalid dob %q, expected YYYY-MM-DD", s.DOB)
+		}
+	}
+	return nil
+}
+
+// dropSubjectTables reverts the migration which created subjects and cases
+func dropSubjectTables() []string {
+	return []string{
+		`drop table if exists cases;`,
+		`drop table if exists subjects;`,
+	}
+}
+
+// subjectResponse is a Subject along with every Case opened for it
+type subjectResponse struct {
+	*Subject
+	Cases []*Case `json:"cases"`
+}
+
+// subjectRepository holds our subjects and the cases opened from screening them.
+type subjectRepository interface {
+	getSubject(subjectID string) (*Subject, error)
+	upsertSubject(subject *Subject) error
+
+	// getSubjects returns up to limit subjects, ordered by ID, whose ID sorts after afterID.
+	// It's used to page through every subject.
+	getSubjects(afterID string, limit int) ([]*Subject, error)
+
+	// markSubjectScreened records when a subject was last screened.
+	markSubjectScreened(subjectID string, screenedAt time.Time) error
+
+	getCase(caseID string) (*Case, error)
+	getCases(filter caseFilter, now time.Time) ([]*Case, error)
+	getSubjectCases(subjectID string) ([]*Case, error)
+	updateCase(caseID string, update caseUpdate, now time.Time) (*Case, error)
+
+	getCaseComments(caseID string) ([]*CaseComment, error)
+	addCaseComment(caseID string, comment *CaseComment) error
+
+	// openCases saves each case which its subject doesn't already have for the same list and EntityID,
+	// returning the cases which were saved. Existing cases whose record has changed since they were
+	// opened are returned as reopened, and cleared cases among them are set back to open.
+	openCases(cases []*Case, now time.Time) (opened []*Case, reopened []*Case, err error)
+
+	close() error
+}
+
+type sqliteSubjectRepository struct {
+	db *sql.DB
+}
+
+func (r *sqliteSubjectRepository) close() error {
+	return r.db.Close()
+}
+
+const subjectColumns = `subject_id, name, dob, addresses, created_at, updated_at, screened_at`
+
+func (r *sqliteSubjectRepository) getSubject(subjectID string) (*Subject, error) {
+	subjects, err := r.querySubjects(`select `+subjectColumns+` from subjects where subject_id = ? limit 1;`, subjectID)
+	if err != nil || len(subjects) == 0 {
+		return nil, err
+	}
+	return subjects[0], nil
+}
+
+func (r *sqliteSubjectRepository) getSubjects(afterID string, limit int) ([]*Subject, error) {
+	return r.querySubjects(`select `+subjectColumns+` from subjects where subject_id > ? order by subject_id limit ?;`, afterID, limit)
+}
+
+func (r *sqliteSubjectRepository) querySubjects(query string, args ...interface{}) ([]*Subject, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subjects []*Subject
+	for rows.Next() {
+		var subject Subject
+		var dob, addresses sql.NullString
+		if err := rows.Scan(&subject.ID, &subject.Name, &dob, &addresses, &subject.CreatedAt, &subject.UpdatedAt, &subject.ScreenedAt); err != nil {
+			return nil, err
+		}
+		subject.DOB = dob.String
+		if addresses.String != "" {
+			if err := json.Unmarshal([]byte(addresses.String), &subject.Addresses); err != nil {
+				return nil, fmt.Errorf("problem reading subject %s addresses: %v", subject.ID, err)
+			}
+		}
+		subjects = append(subjects, &subject)
+	}
+	return subjects, rows.Err()
+}
+
+// upsertSubject saves subject, keeping its original CreatedAt if it already exists.
+func (r *sqliteSubjectRepository) upsertSubject(subject *Subject) error {
+	addresses, err := json.Marshal(subject.Addresses)
+	if err != nil {
+		return err
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	res, err := tx.Exec(`update subjects set name = ?, dob = ?, addresses = ?, updated_at = ? where subject_id = ?;`, subject.Name, subject.DOB, string(addresses), subject.UpdatedAt, subject.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		query := `insert into subjects (subject_id, name, dob, addresses, created_at, updated_at) values (?, ?, ?, ?, ?, ?);`
+		if _, err := tx.Exec(query, subject.ID, subject.Name, subject.DOB, string(addresses), subject.CreatedAt, subject.UpdatedAt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *sqliteSubjectRepository) markSubjectScreened(subjectID string, screenedAt time.Time) error {
+	_, err := r.db.Exec(`update subjects set screened_at = ? where subject_id = ?;`, screenedAt, subjectID)
+	return err
+}
+
+// findSubjectHits searches every list for subject's name and returns an open Case for each record
+// matching at or above minMatch, unless it's whitelisted for the subject.
+func (s *searcher) findSubjectHits(subject *Subject, minMatch float64, wl *whitelist, now time.Time) []*Case {
+	var cases []*Case
+	hit := func(list, entityID, name string, match float64, record interface{}) {
+		if match < minMatch {
+			return
+		}
+		hash := recordHash(record)
+		if wl.find(subject.ID, subject.Name, list, entityID, hash) != nil {
+			return
+		}
+		cases = append(cases, &Case{
+			ID:         base.ID(),
+			SubjectID:  subject.ID,
+			List:       list,
+			EntityID:   entityID,
+			Name:       name,
+			Match:      match,
+			Status:     CaseOpen,
+			RecordHash: hash,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+	}
+	name, limit := subject.Name, hardResultsLimit
+
+	for _, sdn := range s.TopSDNs(limit, name) {
+		hit(sdnList, sdn.EntityID, sdn.SDNName, sdn.match, sdn.SDN)
+	}
+	for _, alt := range s.TopAltNames(limit, name) {
+		hit(altNameList, alt.AlternateIdentity.AlternateID, alt.AlternateIdentity.AlternateName, alt.match, alt.AlternateIdentity)
+	}
+	for _, dp := range s.TopDPs(limit, name) {
+		hit(dplList, dp.id, dp.DeniedPerson.Name, dp.match, dp.DeniedPerson)
+	}
+	for _, ssi := range s.TopSSIs(limit, name) {
+		hit(ssiList, ssi.SectoralSanction.EntityID, ssi.SectoralSanction.Name, ssi.match, ssi.SectoralSanction)
+	}
+	for _, el := range s.TopELs(limit, name) {
+		hit(elList, el.id, el.Entity.Name, el.match, el.Entity)
+	}
+	return cases
+}
+
+// screenSubject opens a case for each new hit on subject and reopens cases whose record has changed.
+// Hits whitelisted in wl are skipped.
+func (s *searcher) screenSubject(subject *Subject, repo subjectRepository, wl *whitelist, now time.Time) ([]*Case, []*Case, error) {
+	opened, reopened, err := repo.openCases(s.findSubjectHits(subject, subjectMinMatch, wl, now), now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("problem opening cases for subject %s: %v", subject.ID, err)
+	}
+	if err := repo.markSubjectScreened(subject.ID, now); err != nil {
+		return opened, reopened, fmt.Errorf("problem marking subject %s screened: %v", subject.ID, err)
+	}
+	return opened, reopened, nil
+}
+
+// subjectScreening counts the subjects screened after a refresh and the cases it opened or reopened
+type subjectScreening struct {
+	Screened, Opened, Reopened int
+}
+
+// rescreenSubjects screens every subject against the current data, which is called after each refresh.
+func (s *searcher) rescreenSubjects(repo subjectRepository) (subjectScreening, error) {
+	var result subjectScreening
+	wl := s.loadWhitelist()
+	afterID := ""
+	for {
+		subjects, err := repo.getSubjects(afterID, subjectScreeningBatchSize)
+		if err != nil {
+			return result, fmt.Errorf("problem reading subjects: %v", err)
+		}
+		for i := range subjects {
+			opened, reopened, err := s.screenSubject(subjects[i], repo, wl, time.Now())
+			if err != nil {
+				return result, err
+			}
+			result.Screened++
+			result.Opened += len(opened)
+			result.Reopened += len(reopened)
+		}
+		if len(subjects) < subjectScreeningBatchSize {
+			return result, nil
+		}
+		afterID = subjects[len(subjects)-1].ID
+	}
+}
+
+func addSubjectRoutes(logger log.Logger, r *mux.Router, searcher *searcher, repo subjectRepository) {
+	r.Methods("POST").Path("/subjects").HandlerFunc(createSubject(logger, searcher, repo))
+	r.Methods("GET").Path("/subjects/{subjectID}").HandlerFunc(getSubject(logger, repo))
+}
+
+func getSubjectID(w http.ResponseWriter, r *http.Request) string {
+	v, ok := mux.Vars(r)["subjectID"]
+	if !ok || v == "" {
+		moovhttp.Problem(w, errNoSubjectID)
+		return ""
+	}
+	return v
+}
+
+// createSubject saves (or replaces) a subject from our own records and screens it.
+func createSubject(logger log.Logger, searcher *searcher, repo subjectRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		var subject Subject
+		if err := json.NewDecoder(r.Body).Decode(&subject); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if err := subject.validate(); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		now := time.Now()
+		subject.CreatedAt, subject.UpdatedAt, subject.ScreenedAt = now, now, nil
+		if err := repo.upsertSubject(&subject); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if _, _, err := searcher.screenSubject(&subject, repo, searcher.loadWhitelist(), now); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		writeSubject(w, subject.ID, repo)
+	}
+}
+
+func getSubject(logger log.Logger, repo subjectRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		subjectID := getSubjectID(w, r)
+		if subjectID == "" {
+			return
+		}
+		writeSubject(w, subjectID, repo)
+	}
+}
+
+func writeSubject(w http.ResponseWriter, subjectID string, repo subjectRepository) {
+	subject, err := repo.getSubject(subjectID)
+	if err != nil {
+		moovhttp.Problem(w, err)
+		return
+	}
+	if subject == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	cases, err := repo.getSubjectCases(subjectID)
+	if err != nil {
+		moovhttp.Problem(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(subjectResponse{subject, cases})
+}
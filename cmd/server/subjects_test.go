@@ -0,0 +1,166 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func createTestSubjectRepository(t *testing.T) *sqliteSubjectRepository {
+	t.Helper()
+
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &sqliteSubjectRepository{db.db}
+}
+
+func serveSubjects(router *mux.Router, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	router.ServeHTTP(w, req)
+	w.Flush()
+	return w
+}
+
+func TestSubjects__readSubjectMinMatch(t *testing.T) {
+	if n := readSubjectMinMatch("0.8"); n != 0.8 {
+		t.Errorf("got %.2f", n)
+	}
+	for _, str := range []string{"", "0", "1.5", "abc"} {
+		if n := readSubjectMinMatch(str); n != subjectMinMatch {
+			t.Errorf("%q: got %.2f", str, n)
+		}
+	}
+}
+
+func TestSubjects__validate(t *testing.T) {
+	good := Subject{ID: "1", Name: "Jane Smith", DOB: "1970-01-31"}
+	if err := good.validate(); err != nil {
+		t.Error(err)
+	}
+	bad := []Subject{
+		{Name: "Jane Smith"},
+		{ID: "1", Name: " "},
+		{ID: "1", Name: "Jane Smith", DOB: "01/31/1970"},
+	}
+	for i := range bad {
+		if err := bad[i].validate(); err == nil {
+			t.Errorf("expected error: %#v", bad[i])
+		}
+	}
+}
+
+func TestSubjects__screening(t *testing.T) {
+	repo := createTestSubjectRepository(t)
+	defer repo.close()
+
+	router := mux.NewRouter()
+	addSubjectRoutes(nil, router, customerSearcher, repo)
+	addCaseRoutes(nil, router, repo)
+
+	body := `{"subjectID": "cust-1", "name": "Banco Nacional de Cuba", "dob": "1970-01-31", "addresses": [{"city": "Tokyo", "country": "Japan"}]}`
+	w := serveSubjects(router, "POST", "/subjects", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	var resp subjectResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ID != "cust-1" || resp.DOB != "1970-01-31" || len(resp.Addresses) != 1 || resp.ScreenedAt == nil {
+		t.Errorf("unexpected subject: %#v", resp.Subject)
+	}
+	var hit *Case
+	for _, c := range resp.Cases {
+		if c.List == sdnList && c.EntityID == "306" {
+			hit = c
+		}
+	}
+	if hit == nil || hit.Status != CaseOpen || hit.Match < subjectMinMatch {
+		t.Fatalf("expected an open SDN case: %#v", resp.Cases)
+	}
+
+	// re-screening (or saving the subject again) doesn't open a second case for a record
+	if screening, err := customerSearcher.rescreenSubjects(repo); err != nil || screening.Screened != 1 || screening.Opened != 0 || screening.Reopened != 0 {
+		t.Errorf("screening=%#v err=%v", screening, err)
+	}
+	if w := serveSubjects(router, "POST", "/subjects", body); w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	cases, err := repo.getSubjectCases("cust-1")
+	if err != nil || len(cases) != len(resp.Cases) {
+		t.Errorf("cases=%#v err=%v", cases, err)
+	}
+
+	// clear the case
+	w = serveSubjects(router, "PUT", fmt.Sprintf("/cases/%s", hit.ID), `{"status": "cleared"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	w = serveSubjects(router, "GET", fmt.Sprintf("/cases/%s", hit.ID), "")
+	var updated Case
+	if err := json.NewDecoder(w.Body).Decode(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != CaseCleared {
+		t.Errorf("unexpected case: %#v", updated)
+	}
+
+	if w := serveSubjects(router, "PUT", fmt.Sprintf("/cases/%s", hit.ID), `{"status": "closed"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+	if w := serveSubjects(router, "PUT", "/cases/missing", `{"status": "open"}`); w.Code != http.StatusNotFound {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+}
+
+func TestSubjects__errors(t *testing.T) {
+	repo := createTestSubjectRepository(t)
+	defer repo.close()
+
+	router := mux.NewRouter()
+	addSubjectRoutes(nil, router, customerSearcher, repo)
+
+	if w := serveSubjects(router, "POST", "/subjects", `{"name": "Jane Smith"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+	if w := serveSubjects(router, "POST", "/subjects", `{`); w.Code != http.StatusBadRequest {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+	if w := serveSubjects(router, "GET", "/subjects/missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+}
+
+func TestSubjects__rescreenPages(t *testing.T) {
+	repo := createTestSubjectRepository(t)
+	defer repo.close()
+
+	defer func(n int) { subjectScreeningBatchSize = n }(subjectScreeningBatchSize)
+	subjectScreeningBatchSize = 2
+
+	for i := 0; i < 5; i++ {
+		subject := &Subject{ID: fmt.Sprintf("s%d", i), Name: "Jane Smith"}
+		if err := repo.upsertSubject(subject); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if screening, err := customerSearcher.rescreenSubjects(repo); err != nil || screening.Screened != 5 {
+		t.Errorf("screening=%#v err=%v", screening, err)
+	}
+	subject, err := repo.getSubject("s4")
+	if err != nil || subject == nil || subject.ScreenedAt == nil {
+		t.Errorf("subject=%#v err=%v", subject, err)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moov-io/base/admin"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+var (
+	// tlsReloadInterval is how often certificate files are checked for changes
+	tlsReloadInterval = 30 * time.Second
+)
+
+// tlsFiles are the PEM files our HTTP servers are served with
+type tlsFiles struct {
+	certFile, keyFile string
+
+	// clientCAFile holds the CAs client certificates are verified against for mTLS
+	clientCAFile string
+	clientAuth   tls.ClientAuthType
+}
+
+// readTLSFiles reads our TLS settings from the environment. It returns nil when HTTPS_CERT_FILE and
+// HTTPS_KEY_FILE aren't set, so the servers listen for plain HTTP.
+func readTLSFiles() (*tlsFiles, error) {
+	files := &tlsFiles{
+		certFile:     os.Getenv("HTTPS_CERT_FILE"),
+		keyFile:      os.Getenv("HTTPS_KEY_FILE"),
+		clientCAFile: os.Getenv("HTTPS_CLIENT_CA_FILE"),
+		clientAuth:   tls.NoClientCert,
+	}
+	if files.certFile == "" && files.keyFile == "" {
+		if files.clientCAFile != "" {
+			return nil, errors.New("HTTPS_CLIENT_CA_FILE needs HTTPS_CERT_FILE and HTTPS_KEY_FILE")
+		}
+		return nil, nil
+	}
+	if files.certFile == "" || files.keyFile == "" {
+		return nil, errors.New("both HTTPS_CERT_FILE and HTTPS_KEY_FILE are needed")
+	}
+	if files.clientCAFile != "" {
+		switch v := strings.ToLower(os.Getenv("HTTPS_CLIENT_AUTH")); v {
+		case "", "require":
+			files.clientAuth = tls.RequireAndVerifyClientCert
+		case "verify_if_given":
+			files.clientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return nil, fmt.Errorf("unknown HTTPS_CLIENT_AUTH %q, expected require or verify_if_given", v)
+		}
+	}
+	return files, nil
+}
+
+// reloadingTLS serves the certificate (and client CAs) from tlsFiles, reading them again whenever
+// one of the files changes so a renewed certificate is used without restarting.
+type reloadingTLS struct {
+	files  tlsFiles
+	logger log.Logger
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes []time.Time
+}
+
+func newReloadingTLS(logger log.Logger, files tlsFiles) (*reloadingTLS, error) {
+	t := &reloadingTLS{files: files, logger: logger}
+	if _, err := t.reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *reloadingTLS) paths() []string {
+	if t.files.clientCAFile == "" {
+		return []string{t.files.certFile, t.files.keyFile}
+	}
+	return []string{t.files.certFile, t.files.keyFile, t.files.clientCAFile}
+}
+
+// reload reads the files again if any have changed and returns true if they were read. The previous
+// certificate is kept when the new files can't be read.
+func (t *reloadingTLS) reload() (bool, error) {
+	var modTimes []time.Time
+	for _, path := range t.paths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+
+	t.mu.RLock()
+	unchanged := len(t.modTimes) == len(modTimes)
+	for i := 0; unchanged && i < len(modTimes); i++ {
+		unchanged = t.modTimes[i].Equal(modTimes[i])
+	}
+	t.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(t.files.certFile, t.files.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("problem loading certificate: %v", err)
+	}
+	var pool *x509.CertPool
+	if t.files.clientCAFile != "" {
+		bs, err := ioutil.ReadFile(t.files.clientCAFile)
+		if err != nil {
+			return false, err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bs) {
+			return false, fmt.Errorf("no certificates found in %s", t.files.clientCAFile)
+		}
+	}
+
+	t.mu.Lock()
+	t.cert, t.clientCA, t.modTimes = &cert, pool, modTimes
+	t.mu.Unlock()
+	return true, nil
+}
+
+// watch reloads the files every interval until stop is closed
+func (t *reloadingTLS) watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			reloaded, err := t.reload()
+			if t.logger == nil {
+				continue
+			}
+			if err != nil {
+				t.logger.Log("tls", fmt.Sprintf("problem reloading certificate, still serving the previous one: %v", err))
+			} else if reloaded {
+				t.logger.Log("tls", fmt.Sprintf("reloaded certificate from %s", t.files.certFile))
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// config returns the TLS config for a server, which reads the latest certificate and client CAs on
+// each handshake.
+func (t *reloadingTLS) config() *tls.Config {
+	base := &tls.Config{
+		PreferServerCipherSuites: true,
+		MinVersion:               tls.VersionTLS12,
+	}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		t.mu.RLock()
+		defer t.mu.RUnlock()
+
+		cfg := base.Clone()
+		cfg.GetConfigForClient = nil
+		cfg.Certificates = []tls.Certificate{*t.cert}
+		cfg.ClientCAs = t.clientCA
+		cfg.ClientAuth = t.files.clientAuth
+		return cfg, nil
+	}
+	return base
+}
+
+// listen serves srv over TLS when t is set and plain HTTP otherwise
+func listen(srv *http.Server, t *reloadingTLS) error {
+	if t == nil {
+		return srv.ListenAndServe()
+	}
+	srv.TLSConfig = t.config()
+	return srv.ListenAndServeTLS("", "")
+}
+
+// adminHTTPServer serves Prometheus metrics, pprof, the /live and /ready checks and our admin routes.
+// It replaces admin.Server from moov-io/base, which can't be served over TLS.
+type adminHTTPServer struct {
+	router *mux.Router
+	svc    *http.Server
+}
+
+func newAdminServer(addr string) *adminHTTPServer {
+	timeout, _ := time.ParseDuration("45s")
+
+	router := mux.NewRouter()
+	router.NotFoundHandler = admin.Handler() // metrics and pprof
+
+	s := &adminHTTPServer{
+		router: router,
+		svc: &http.Server{
+			Addr:         addr,
+			Handler:      router,
+			ReadTimeout:  timeout,
+			WriteTimeout: timeout,
+			IdleTimeout:  timeout,
+		},
+	}
+	healthy := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	s.AddHandler("/live", healthy)
+	s.AddHandler("/ready", healthy)
+	return s
+}
+
+// BindAddr returns the server's bind address, in Go's format so :9090 is valid.
+func (s *adminHTTPServer) BindAddr() string {
+	return s.svc.Addr
+}
+
+// AddHandler adds a route to the admin server
+func (s *adminHTTPServer) AddHandler(path string, hf http.HandlerFunc) {
+	s.router.HandleFunc(path, hf)
+}
+
+// Listen serves the admin routes, over TLS if t is set. This call blocks until the server is shutdown.
+func (s *adminHTTPServer) Listen(t *reloadingTLS) error {
+	return listen(s.svc, t)
+}
+
+// Shutdown stops the server, waiting for in-flight requests until ctx is done.
+func (s *adminHTTPServer) Shutdown(ctx context.Context) error {
+	return s.svc.Shutdown(ctx)
+}
@@ -0,0 +1,208 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCertificate creates a certificate for commonName signed by parent (or self-signed)
+func testCertificate(t *testing.T, commonName string, parent *x509.Certificate, parentKey *rsa.PrivateKey) (*x509.Certificate, *rsa.PrivateKey, []byte, []byte) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		tpl.IsCA, tpl.BasicConstraintsValid = true, true
+		parent, parentKey = tpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return cert, key, certPEM, keyPEM
+}
+
+func writeTestFile(t *testing.T, path string, contents []byte, modTime time.Time) {
+	t.Helper()
+
+	if err := ioutil.WriteFile(path, contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLS__readTLSFiles(t *testing.T) {
+	setenv := func(cert, key, ca, auth string) {
+		os.Setenv("HTTPS_CERT_FILE", cert)
+		os.Setenv("HTTPS_KEY_FILE", key)
+		os.Setenv("HTTPS_CLIENT_CA_FILE", ca)
+		os.Setenv("HTTPS_CLIENT_AUTH", auth)
+	}
+	defer setenv("", "", "", "")
+
+	setenv("", "", "", "")
+	if files, err := readTLSFiles(); files != nil || err != nil {
+		t.Errorf("files=%#v err=%v", files, err)
+	}
+	setenv("cert.pem", "key.pem", "ca.pem", "")
+	if files, err := readTLSFiles(); err != nil || files.clientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("files=%#v err=%v", files, err)
+	}
+	setenv("cert.pem", "key.pem", "ca.pem", "verify_if_given")
+	if files, err := readTLSFiles(); err != nil || files.clientAuth != tls.VerifyClientCertIfGiven {
+		t.Errorf("files=%#v err=%v", files, err)
+	}
+
+	for _, env := range [][]string{{"cert.pem", "", "", ""}, {"", "", "ca.pem", ""}, {"cert.pem", "key.pem", "ca.pem", "sometimes"}} {
+		setenv(env[0], env[1], env[2], env[3])
+		if _, err := readTLSFiles(); err == nil {
+			t.Errorf("%v: expected error", env)
+		}
+	}
+}
+
+func TestTLS__mTLSAndReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ofac-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, caKey, caPEM, _ := testCertificate(t, "ofac test CA", nil, nil)
+	_, _, certPEM, keyPEM := testCertificate(t, "ofac-1", ca, caKey)
+	_, _, clientCertPEM, clientKeyPEM := testCertificate(t, "batch.example.com", ca, caKey)
+
+	files := tlsFiles{
+		certFile:     filepath.Join(dir, "cert.pem"),
+		keyFile:      filepath.Join(dir, "key.pem"),
+		clientCAFile: filepath.Join(dir, "ca.pem"),
+		clientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	past := time.Now().Add(-time.Minute)
+	writeTestFile(t, files.certFile, certPEM, past)
+	writeTestFile(t, files.keyFile, keyPEM, past)
+	writeTestFile(t, files.clientCAFile, caPEM, past)
+
+	serverTLS, err := newReloadingTLS(nil, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// serve the client's certificate through the mTLS authenticator
+	a, _ := newMTLSAuthenticator([]*authIdentity{{CommonName: "batch.example.com", Roles: []role{roleScreening}}})
+	router := authRouter(a)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: router, TLSConfig: serverTLS.config()}
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(certs []tls.Certificate) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs},
+		}}
+		return client.Get("https://" + ln.Addr().String() + "/search")
+	}
+	servedCommonName := func() string {
+		resp, err := get([]tls.Certificate{clientCert})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("bogus status code: %d", resp.StatusCode)
+		}
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	if cn := servedCommonName(); cn != "ofac-1" {
+		t.Errorf("served %q", cn)
+	}
+	if _, err := get(nil); err == nil {
+		t.Error("expected error without a client certificate")
+	}
+
+	// nothing changed
+	if reloaded, err := serverTLS.reload(); reloaded || err != nil {
+		t.Errorf("reloaded=%v err=%v", reloaded, err)
+	}
+
+	// a renewed certificate is served without restarting
+	_, _, certPEM, keyPEM = testCertificate(t, "ofac-2", ca, caKey)
+	writeTestFile(t, files.certFile, certPEM, time.Now())
+	writeTestFile(t, files.keyFile, keyPEM, time.Now())
+	if reloaded, err := serverTLS.reload(); !reloaded || err != nil {
+		t.Errorf("reloaded=%v err=%v", reloaded, err)
+	}
+	if cn := servedCommonName(); cn != "ofac-2" {
+		t.Errorf("served %q", cn)
+	}
+
+	// a broken certificate keeps the previous one
+	writeTestFile(t, files.certFile, []byte("not a certificate"), time.Now().Add(time.Minute))
+	if _, err := serverTLS.reload(); err == nil {
+		t.Error("expected error")
+	}
+	if cn := servedCommonName(); cn != "ofac-2" {
+		t.Errorf("served %q", cn)
+	}
+}
+
+func TestTLS__adminServer(t *testing.T) {
+	s := newAdminServer(":0")
+	s.AddHandler("/foo", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	for path, status := range map[string]int{"/live": http.StatusOK, "/ready": http.StatusOK, "/metrics": http.StatusOK, "/foo": http.StatusTeapot} {
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != status {
+			t.Errorf("%s: bogus status code: %d", path, w.Code)
+		}
+	}
+	if s.BindAddr() != ":0" {
+		t.Errorf("BindAddr=%q", s.BindAddr())
+	}
+}
@@ -5,9 +5,13 @@
 package main
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/moov-io/base"
@@ -19,8 +23,22 @@ import (
 
 var (
 	errNoWatchID = errors.New("no watchID found")
+
+	// defaultNameWatchMinMatch is the match percentage used for name watches which don't specify minMatch
+	defaultNameWatchMinMatch = 0.90
+)
+
+// Sanctions lists which name watches can search over
+const (
+	sdnList     = "sdn"
+	altNameList = "alt"
+	dplList     = "dpl"
+	ssiList     = "ssi"
+	elList      = "el"
 )
 
+var nameWatchLists = []string{sdnList, altNameList, dplList, ssiList, elList}
+
 func getWatchID(w http.ResponseWriter, r *http.Request) string {
 	v, ok := mux.Vars(r)["watchID"]
 	if !ok || v == "" {
@@ -33,6 +51,83 @@ func getWatchID(w http.ResponseWriter, r *http.Request) string {
 type watchRequest struct {
 	AuthToken string `json:"authToken"`
 	Webhook   string `json:"webhook"`
+
+	// SigningSecret is used to sign each webhook delivery. One is generated if it's left empty.
+	SigningSecret string `json:"signingSecret,omitempty"`
+
+	// Notifier is how notifications are sent (webhook, kafka, nats or file). The default notifier is used if empty.
+	Notifier string `json:"notifier,omitempty"`
+
+	// MinMatch and Lists are only used for name watches
+	MinMatch float64  `json:"minMatch,omitempty"`
+	Lists    []string `json:"lists,omitempty"`
+}
+
+// minSigningSecretLength is the shortest signing secret accepted from clients
+const minSigningSecretLength = 16
+
+// validateNotification checks the watch's notifier is configured. Watches notified with webhooks
+// also need an authToken and HTTPS webhook, which is normalized.
+func (req *watchRequest) validateNotification() error {
+	notifier := req.Notifier
+	if notifier == "" {
+		notifier = defaultNotifier
+	}
+	if _, err := findNotifier(notifier); err != nil {
+		return err
+	}
+	if notifier != webhookNotifierName && req.Webhook == "" {
+		return nil
+	}
+	if req.AuthToken == "" {
+		return errNoAuthToken
+	}
+	webhook, err := validateWebhook(req.Webhook)
+	if err != nil {
+		return err
+	}
+	req.Webhook = webhook
+	return nil
+}
+
+// setSigningSecret checks a client provided SigningSecret or generates a random one.
+func (req *watchRequest) setSigningSecret() error {
+	if req.SigningSecret != "" {
+		if len(req.SigningSecret) < minSigningSecretLength {
+			return fmt.Errorf("signingSecret must be at least %d characters", minSigningSecretLength)
+		}
+		return nil
+	}
+	bs := make([]byte, 32)
+	if _, err := rand.Read(bs); err != nil {
+		return fmt.Errorf("problem generating signing secret: %v", err)
+	}
+	req.SigningSecret = hex.EncodeToString(bs)
+	return nil
+}
+
+// validateNameWatch checks the name watch specific fields of a watchRequest and normalizes Lists.
+func (req *watchRequest) validateNameWatch() error {
+	if req.MinMatch < 0 || req.MinMatch > 1 {
+		return fmt.Errorf("minMatch must be between 0 and 1, got %.2f", req.MinMatch)
+	}
+	for i := range req.Lists {
+		list := strings.ToLower(strings.TrimSpace(req.Lists[i]))
+		if !nameWatchList(list) {
+			return fmt.Errorf("unknown list %q, expected one of: %s", req.Lists[i], strings.Join(nameWatchLists, ", "))
+		}
+		req.Lists[i] = list
+	}
+	return nil
+}
+
+func nameWatchList(list string) bool {
+	for i := range nameWatchLists {
+		if nameWatchLists[i] == list {
+			return true
+		}
+	}
+	return false
 }
 
 // watchRepository holds information about each company and/or customer that another service wants notifications
@@ -43,15 +138,21 @@ type watchRepository interface {
 
 	// Company watches
 	addCompanyWatch(companyID string, params watchRequest) (string, error)
-	addCompanyNameWatch(name string, webhook string, authToken string) (string, error)
+	addCompanyNameWatch(name string, params watchRequest) (string, error)
 	removeCompanyWatch(companyID string, watchID string) error
 	removeCompanyNameWatch(watchID string) error
 
 	// Customer watches
 	addCustomerWatch(customerID string, params watchRequest) (string, error)
-	addCustomerNameWatch(name string, webhook string, authToken string) (string, error)
+	addCustomerNameWatch(name string, params watchRequest) (string, error)
 	removeCustomerWatch(customerID string, watchID string) error
 	removeCustomerNameWatch(watchID string) error
+
+	// Watch management, all watch types are listed together
+	listWatches(filter watchFilter) ([]*Watch, error)
+	getWatch(watchID string) (*Watch, error)
+	updateWatch(watchID string, update watchUpdate) error
+	getWatchDeliveries(watchID string, since time.Time, limit, offset int) ([]WatchDelivery, error)
 }
 
 type sqliteWatchRepository struct {
@@ -77,17 +178,26 @@ func (r *sqliteWatchRepository) addCompanyWatch(companyID string, params watchRe
 	if companyID == "" {
 		return "", errNoCompanyID
 	}
-	id := base.ID()
-
-	query := `insert or ignore into company_watches (id, company_id, webhook, auth_token, created_at) values (?, ?, ?, ?, ?)`
-	stmt, err := r.db.Prepare(query)
+	tx, err := r.db.Begin()
 	if err != nil {
 		return "", err
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec(id, companyID, params.Webhook, params.AuthToken, time.Now())
-	if err != nil {
+	id, now := base.ID(), time.Now()
+	query := `insert into company_watches (id, company_id, webhook, auth_token, created_at) values (?, ?, ?, ?, ?)`
+	if _, err := tx.Exec(query, id, companyID, params.Webhook, params.AuthToken, now); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if err := addSigningSecret(tx, id, params.SigningSecret, now); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if err := addWatchNotifier(tx, id, params.Notifier, now); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
 		return "", err
 	}
 	return id, nil
@@ -99,30 +209,12 @@ func (r *sqliteWatchRepository) removeCompanyWatch(companyID string, watchID str
 	}
 
 	query := `update company_watches set deleted_at = ? where company_id = ? and id = ? and deleted_at is null`
-	stmt, err := r.db.Prepare(query)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec(time.Now(), companyID, watchID)
-	return err
+	return r.removeWatch(watchID, query, time.Now(), companyID, watchID)
 }
 
-func (r *sqliteWatchRepository) addCompanyNameWatch(name string, webhook string, authToken string) (string, error) {
-	query := `insert or ignore into company_name_watches (id, name, webhook, auth_token, created_at) values (?, ?, ?, ?, ?);`
-	stmt, err := r.db.Prepare(query)
-	if err != nil {
-		return "", err
-	}
-	defer stmt.Close()
-
-	id := base.ID()
-	_, err = stmt.Exec(id, name, webhook, authToken, time.Now())
-	if err != nil {
-		return "", err
-	}
-	return id, nil
+func (r *sqliteWatchRepository) addCompanyNameWatch(name string, params watchRequest) (string, error) {
+	query := `insert into company_name_watches (id, name, webhook, auth_token, created_at) values (?, ?, ?, ?, ?);`
+	return r.addNameWatch(query, name, params)
 }
 
 func (r *sqliteWatchRepository) removeCompanyNameWatch(watchID string) error {
@@ -131,14 +223,7 @@ func (r *sqliteWatchRepository) removeCompanyNameWatch(watchID string) error {
 	}
 
 	query := `update company_name_watches set deleted_at = ? where id = ? and deleted_at is null`
-	stmt, err := r.db.Prepare(query)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec(time.Now(), watchID)
-	return err
+	return r.removeWatch(watchID, query, time.Now(), watchID)
 }
 
 // Customer methods
@@ -147,17 +232,26 @@ func (r *sqliteWatchRepository) addCustomerWatch(customerID string, params watch
 	if customerID == "" {
 		return "", errNoCustomerID
 	}
-	id := base.ID()
-
-	query := `insert or ignore into customer_watches (id, customer_id, webhook, auth_token, created_at) values (?, ?, ?, ?, ?)`
-	stmt, err := r.db.Prepare(query)
+	tx, err := r.db.Begin()
 	if err != nil {
 		return "", err
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec(id, customerID, params.Webhook, params.AuthToken, time.Now())
-	if err != nil {
+	id, now := base.ID(), time.Now()
+	query := `insert into customer_watches (id, customer_id, webhook, auth_token, created_at) values (?, ?, ?, ?, ?)`
+	if _, err := tx.Exec(query, id, customerID, params.Webhook, params.AuthToken, now); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if err := addSigningSecret(tx, id, params.SigningSecret, now); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if err := addWatchNotifier(tx, id, params.Notifier, now); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
 		return "", err
 	}
 	return id, nil
@@ -169,235 +263,371 @@ func (r *sqliteWatchRepository) removeCustomerWatch(customerID string, watchID s
 	}
 
 	query := `update customer_watches set deleted_at = ? where customer_id = ? and id = ? and deleted_at is null`
-	stmt, err := r.db.Prepare(query)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
+	return r.removeWatch(watchID, query, time.Now(), customerID, watchID)
+}
 
-	_, err = stmt.Exec(time.Now(), customerID, watchID)
-	return err
+func (r *sqliteWatchRepository) addCustomerNameWatch(name string, params watchRequest) (string, error) {
+	query := `insert into customer_name_watches (id, name, webhook, auth_token, created_at) values (?, ?, ?, ?, ?);`
+	return r.addNameWatch(query, name, params)
 }
 
-func (r *sqliteWatchRepository) addCustomerNameWatch(name string, webhook string, authToken string) (string, error) {
-	query := `insert or ignore into customer_name_watches (id, name, webhook, auth_token, created_at) values (?, ?, ?, ?, ?);`
-	stmt, err := r.db.Prepare(query)
+var nameWatchOptionsColumns = []string{"watch_id", "min_match", "lists", "created_at"}
+
+// addNameWatch inserts a customer or company name watch (with query) along with its match criteria.
+func (r *sqliteWatchRepository) addNameWatch(query string, name string, params watchRequest) (string, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
 		return "", err
 	}
-	defer stmt.Close()
 
-	id := base.ID()
-	_, err = stmt.Exec(id, name, webhook, authToken, time.Now())
-	if err != nil {
+	id, now := base.ID(), time.Now()
+	if _, err := tx.Exec(query, id, name, params.Webhook, params.AuthToken, now); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if err := replaceRow(tx, "name_watch_options", "watch_id", nameWatchOptionsColumns, id, params.MinMatch, strings.Join(params.Lists, ","), now); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if err := addSigningSecret(tx, id, params.SigningSecret, now); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if err := addWatchNotifier(tx, id, params.Notifier, now); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
 		return "", err
 	}
 	return id, nil
 }
 
-func (r *sqliteWatchRepository) removeCustomerNameWatch(watchID string) error {
-	if watchID == "" {
-		return errNoWatchID
-	}
-
-	query := `update customer_name_watches set deleted_at = ? where id = ? and deleted_at is null`
-	stmt, err := r.db.Prepare(query)
-	if err != nil {
-		return err
+// addSigningSecret stores the secret used to sign a watch's webhook deliveries.
+func addSigningSecret(tx *sql.Tx, watchID string, secret string, now time.Time) error {
+	if secret == "" {
+		return nil
 	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec(time.Now(), watchID)
-	return err
+	return replaceRow(tx, "watch_signing_secrets", "watch_id", []string{"watch_id", "secret", "created_at"}, watchID, secret, now)
 }
 
-type watch struct {
-	id                       string
-	customerID, customerName string
-	companyID, companyName   string
-	webhook                  string
-	authToken                string
+// addWatchNotifier stores the notifier a watch picked, watches without one use the default notifier.
+func addWatchNotifier(tx *sql.Tx, watchID string, notifier string, now time.Time) error {
+	if notifier == "" {
+		return nil
+	}
+	return replaceRow(tx, "watch_notifiers", "watch_id", []string{"watch_id", "notifier", "created_at"}, watchID, notifier, now)
 }
 
-type watchCursor struct {
-	batchSize int
-	db        *sql.DB
-
-	logger log.Logger
+func (r *sqliteWatchRepository) removeCustomerNameWatch(watchID string) error {
+	if watchID == "" {
+		return errNoWatchID
+	}
 
-	// '*NewerThan' values represent the minimum (oldest) created_at value to return in the batch.
-	//
-	// These values start at "zero time" (an empty time.Time) and progresses towards time.Now()
-	// with each batch by being set to the batch's newest time.
-	customerNewerThan, customerNameNewerThan time.Time
-	companyNewerThan, companyNameNewerThan   time.Time
+	query := `update customer_name_watches set deleted_at = ? where id = ? and deleted_at is null`
+	return r.removeWatch(watchID, query, time.Now(), watchID)
 }
 
-// Next returns a batch of watches that will be sent off to their respective webhook URL.
-func (cur *watchCursor) Next() ([]watch, error) {
-	var watches []watch
-	limit := cur.batchSize / 4 // 4 SQL queries
-	if cur.batchSize < 4 {
-		limit = 1 // return one if batchSize is invalid
-	}
-
-	// Companies
-	companyWatches, err := cur.getCompanyBatch(limit)
+// removeWatch soft deletes watchID with query and, in the same transaction, removes its webhook deliveries
+// which weren't delivered so they aren't retried or replayed.
+func (r *sqliteWatchRepository) removeWatch(watchID string, query string, args ...interface{}) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		cur.logger.Log("watchCursor", "problem reading company watches", "error", err)
+		return err
 	}
-	watches = append(watches, companyWatches...)
-
-	companyNameWatches, err := cur.getCompanyNameBatch(limit)
+	res, err := tx.Exec(query, args...)
 	if err != nil {
-		cur.logger.Log("watchCursor", "problem reading company name watches", "error", err)
+		tx.Rollback()
+		return err
 	}
-	watches = append(watches, companyNameWatches...)
-
-	// Customers
-	customerWatches, err := cur.getCustomerBatch(limit)
-	if err != nil {
-		cur.logger.Log("watchCursor", "problem reading customer watches", "error", err)
+	if n, _ := res.RowsAffected(); n > 0 {
+		if _, err := tx.Exec(`delete from webhook_deliveries where watch_id = ? and status != ?;`, watchID, deliveryDelivered); err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
-	watches = append(watches, customerWatches...)
+	return tx.Commit()
+}
 
-	customerNameWatches, err := cur.getCustomerNameBatch(limit)
-	if err != nil {
-		cur.logger.Log("watchCursor", "problem reading customer name watches", "error", err)
-	}
-	watches = append(watches, customerNameWatches...)
+// allWatchesQuery selects every active watch with its type. Only one of entity_id and name is set.
+const allWatchesQuery = `select id, 'company' as watch_type, company_id as entity_id, '' as name, webhook, auth_token, created_at from company_watches where deleted_at is null
+union all select id, 'companyName', '', name, webhook, auth_token, created_at from company_name_watches where deleted_at is null
+union all select id, 'customer', customer_id, '', webhook, auth_token, created_at from customer_watches where deleted_at is null
+union all select id, 'customerName', '', name, webhook, auth_token, created_at from customer_name_watches where deleted_at is null`
+
+// watchColumns are selected from allWatchesQuery (as w) joined with name_watch_options (as o) and watch_notifiers (as n)
+const watchColumns = `w.id, w.watch_type, w.entity_id, w.name, w.webhook, coalesce(n.notifier, ''), w.created_at, coalesce(o.min_match, 0), coalesce(o.lists, '')
+from (` + allWatchesQuery + `) as w
+left join name_watch_options as o on w.id = o.watch_id
+left join watch_notifiers as n on w.id = n.watch_id`
+
+func (r *sqliteWatchRepository) listWatches(filter watchFilter) ([]*Watch, error) {
+	where, args := filter.where()
+	query := `select ` + watchColumns + where + `
+order by w.created_at desc, w.id desc limit ? offset ?;`
+	args = append(args, filter.limit, filter.offset)
+	return r.queryWatches(query, args...)
+}
 
-	return watches, nil
+func (r *sqliteWatchRepository) getWatch(watchID string) (*Watch, error) {
+	if watchID == "" {
+		return nil, errNoWatchID
+	}
+	query := `select ` + watchColumns + ` where w.id = ? limit 1;`
+	watches, err := r.queryWatches(query, watchID)
+	if err != nil || len(watches) == 0 {
+		return nil, err
+	}
+	return watches[0], nil
 }
 
-func (cur *watchCursor) getCompanyBatch(limit int) ([]watch, error) {
-	query := `select id, company_id, webhook, auth_token, created_at from company_watches where created_at > ? and deleted_at is null order by created_at asc limit ?`
-	stmt, err := cur.db.Prepare(query)
+func (r *sqliteWatchRepository) queryWatches(query string, args ...interface{}) ([]*Watch, error) {
+	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query(cur.companyNewerThan, limit)
+	rows, err := stmt.Query(args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	max := cur.companyNewerThan
-
-	var watches []watch
+	var watches []*Watch
 	for rows.Next() {
-		var createdAt time.Time
-		var watch watch
-		if err := rows.Scan(&watch.id, &watch.companyID, &watch.webhook, &watch.authToken, &createdAt); err == nil {
-			watches = append(watches, watch)
+		var w Watch
+		var entityID, lists string
+		if err := rows.Scan(&w.ID, &w.Type, &entityID, &w.Name, &w.Webhook, &w.Notifier, &w.CreatedAt, &w.MinMatch, &lists); err != nil {
+			return nil, fmt.Errorf("queryWatches: %v", err)
 		}
-		if createdAt.After(max) {
-			// advance max to newest time
-			max = createdAt
+		switch w.Type {
+		case companyWatchType:
+			w.CompanyID = entityID
+		case customerWatchType:
+			w.CustomerID = entityID
+		default:
+			w.Lists = splitNameWatchLists(lists)
+			if w.MinMatch <= 0 {
+				w.MinMatch = defaultNameWatchMinMatch
+			}
 		}
+		watches = append(watches, &w)
 	}
-	cur.companyNewerThan = max
-
 	return watches, rows.Err()
 }
 
-func (cur *watchCursor) getCompanyNameBatch(limit int) ([]watch, error) {
-	query := `select id, name, webhook, auth_token, created_at from company_name_watches where created_at > ? and deleted_at is null order by created_at asc limit ?`
-	stmt, err := cur.db.Prepare(query)
+// updateWatch applies the non-nil fields of update to a watch. Callers are expected to validate update first.
+func (r *sqliteWatchRepository) updateWatch(watchID string, update watchUpdate) error {
+	w, err := r.getWatch(watchID)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer stmt.Close()
+	if w == nil {
+		return fmt.Errorf("watch %s not found", watchID)
+	}
+	table := map[string]string{
+		companyWatchType:      "company_watches",
+		companyNameWatchType:  "company_name_watches",
+		customerWatchType:     "customer_watches",
+		customerNameWatchType: "customer_name_watches",
+	}[w.Type]
 
-	rows, err := stmt.Query(cur.companyNameNewerThan, limit)
+	tx, err := r.db.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
-
-	max := cur.companyNameNewerThan
-
-	var watches []watch
-	for rows.Next() {
-		var createdAt time.Time
-		var watch watch
-		if err := rows.Scan(&watch.id, &watch.companyName, &watch.webhook, &watch.authToken, &createdAt); err == nil {
-			watches = append(watches, watch)
+	now := time.Now()
+	// Deliveries copy the webhook and auth token when enqueued, so retries of undelivered
+	// rows are pointed at the updated values as well.
+	if update.Webhook != nil {
+		if _, err := tx.Exec(`update `+table+` set webhook = ? where id = ? and deleted_at is null;`, *update.Webhook, watchID); err != nil {
+			tx.Rollback()
+			return err
 		}
-		if createdAt.After(max) {
-			// advance max to newest time
-			max = createdAt
+		if _, err := tx.Exec(`update webhook_deliveries set webhook = ? where watch_id = ? and status != ?;`, *update.Webhook, watchID, deliveryDelivered); err != nil {
+			tx.Rollback()
+			return err
 		}
 	}
-	cur.companyNameNewerThan = max
-
-	return watches, rows.Err()
+	if update.AuthToken != nil {
+		if _, err := tx.Exec(`update `+table+` set auth_token = ? where id = ? and deleted_at is null;`, *update.AuthToken, watchID); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec(`update webhook_deliveries set auth_token = ? where watch_id = ? and status != ?;`, *update.AuthToken, watchID, deliveryDelivered); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if update.SigningSecret != nil {
+		if err := addSigningSecret(tx, watchID, *update.SigningSecret, now); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if update.Notifier != nil {
+		if err := addWatchNotifier(tx, watchID, *update.Notifier, now); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if update.MinMatch != nil || update.Lists != nil {
+		// Keep a stored zero, which follows defaultNameWatchMinMatch, unless minMatch is being set
+		var minMatch float64
+		if err := tx.QueryRow(`select coalesce(min(min_match), 0) from name_watch_options where watch_id = ?;`, watchID).Scan(&minMatch); err != nil {
+			tx.Rollback()
+			return err
+		}
+		lists := w.Lists
+		if update.MinMatch != nil {
+			minMatch = *update.MinMatch
+		}
+		if update.Lists != nil {
+			lists = update.Lists
+		}
+		if err := replaceRow(tx, "name_watch_options", "watch_id", nameWatchOptionsColumns, watchID, minMatch, strings.Join(lists, ","), now); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
-func (cur *watchCursor) getCustomerBatch(limit int) ([]watch, error) {
-	query := `select id, customer_id, webhook, auth_token, created_at from customer_watches where created_at > ? and deleted_at is null order by created_at asc limit ?`
-	stmt, err := cur.db.Prepare(query)
+func (r *sqliteWatchRepository) getWatchDeliveries(watchID string, since time.Time, limit, offset int) ([]WatchDelivery, error) {
+	query := `select attempted_at, status from webhook_stats where watch_id = ? and attempted_at >= ? order by attempted_at desc limit ? offset ?;`
+	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query(cur.customerNewerThan, limit)
+	rows, err := stmt.Query(watchID, since, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	max := cur.customerNewerThan
-
-	var watches []watch
+	var deliveries []WatchDelivery
 	for rows.Next() {
-		var createdAt time.Time
-		var watch watch
-		if err := rows.Scan(&watch.id, &watch.customerID, &watch.webhook, &watch.authToken, &createdAt); err == nil {
-			watches = append(watches, watch)
+		var d WatchDelivery
+		if err := rows.Scan(&d.AttemptedAt, &d.Status); err != nil {
+			return nil, fmt.Errorf("getWatchDeliveries: %v", err)
 		}
-		if createdAt.After(max) {
-			// advance max to newest time
-			max = createdAt
+		d.Delivered = d.Status >= 200 && d.Status <= 299
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+type watch struct {
+	id                       string
+	customerID, customerName string
+	companyID, companyName   string
+	webhook                  string
+	authToken                string
+	signingSecret            string
+	notifier                 string
+
+	// name watch options
+	minMatch float64
+	lists    []string
+}
+
+// searchesList returns true if a name watch should search over the given sanctions list.
+// Name watches created without any lists search them all.
+func (w watch) searchesList(list string) bool {
+	if len(w.lists) == 0 {
+		return true
+	}
+	for i := range w.lists {
+		if w.lists[i] == list {
+			return true
 		}
 	}
-	cur.customerNewerThan = max
+	return false
+}
 
-	return watches, rows.Err()
+// nameMinMatch returns the minimum match percentage for a name watch.
+func (w watch) nameMinMatch() float64 {
+	if w.minMatch <= 0 {
+		return defaultNameWatchMinMatch
+	}
+	return w.minMatch
+}
+
+func splitNameWatchLists(lists string) []string {
+	if lists == "" {
+		return nil
+	}
+	return strings.Split(lists, ",")
+}
+
+type watchCursor struct {
+	batchSize int
+	db        *sql.DB
+
+	logger log.Logger
+
+	// lastCreatedAt and lastID are the keyset position of the newest watch returned so far.
+	// Watches are read in (created_at, id) order, so watches created at the same time are
+	// still returned exactly once when they're split across batches.
+	//
+	// These start at "zero time" and an empty ID, which sorts before every watch.
+	lastCreatedAt time.Time
+	lastID        string
 }
 
-func (cur *watchCursor) getCustomerNameBatch(limit int) ([]watch, error) {
-	query := `select id, name, webhook, auth_token, created_at from customer_name_watches where created_at > ? and deleted_at is null order by created_at asc limit ?`
-	stmt, err := cur.db.Prepare(query)
+// watchCursorQuery selects the next batch of active watches (of every type) after a keyset position.
+const watchCursorQuery = `select w.id, w.watch_type, w.entity_id, w.name, w.webhook, w.auth_token, coalesce(s.secret, ''), coalesce(n.notifier, ''), w.created_at, coalesce(o.min_match, 0), coalesce(o.lists, '')
+from (` + allWatchesQuery + `) as w
+left join name_watch_options as o on w.id = o.watch_id
+left join watch_signing_secrets as s on w.id = s.watch_id
+left join watch_notifiers as n on w.id = n.watch_id
+where w.created_at > ? or (w.created_at = ? and w.id > ?)
+order by w.created_at asc, w.id asc limit ?;`
+
+// Next returns a batch of watches that will be sent off to their respective webhook URL.
+func (cur *watchCursor) Next() ([]watch, error) {
+	limit := cur.batchSize
+	if limit < 1 {
+		limit = 1 // return one if batchSize is invalid
+	}
+
+	stmt, err := cur.db.Prepare(watchCursorQuery)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query(cur.customerNameNewerThan, limit)
+	rows, err := stmt.Query(cur.lastCreatedAt, cur.lastCreatedAt, cur.lastID, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	max := cur.customerNameNewerThan
-
 	var watches []watch
 	for rows.Next() {
+		var w watch
+		var watchType, entityID, name, lists string
 		var createdAt time.Time
-		var watch watch
-		if err := rows.Scan(&watch.id, &watch.customerName, &watch.webhook, &watch.authToken, &createdAt); err == nil {
-			watches = append(watches, watch)
+		if err := rows.Scan(&w.id, &watchType, &entityID, &name, &w.webhook, &w.authToken, &w.signingSecret, &w.notifier, &createdAt, &w.minMatch, &lists); err != nil {
+			return watches, fmt.Errorf("watchCursor: %v", err)
 		}
-		if createdAt.After(max) {
-			// advance max to newest time
-			max = createdAt
+		switch watchType {
+		case companyWatchType:
+			w.companyID = entityID
+		case companyNameWatchType:
+			w.companyName = name
+		case customerWatchType:
+			w.customerID = entityID
+		case customerNameWatchType:
+			w.customerName = name
 		}
-	}
-	cur.customerNameNewerThan = max
+		w.lists = splitNameWatchLists(lists)
+		watches = append(watches, w)
 
+		cur.lastCreatedAt, cur.lastID = createdAt, w.id
+	}
 	return watches, rows.Err()
 }
@@ -0,0 +1,314 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+// Types of watches
+const (
+	companyWatchType      = "company"
+	companyNameWatchType  = "companyName"
+	customerWatchType     = "customer"
+	customerNameWatchType = "customerName"
+)
+
+var (
+	errUnknownWatchType = fmt.Errorf("unknown watch type, expected one of: %s, %s, %s, %s", companyWatchType, companyNameWatchType, customerWatchType, customerNameWatchType)
+	errNotNameWatch     = errors.New("minMatch and lists can only be updated on name watches")
+	errWatchIDFilter    = fmt.Errorf("companyID and customerID only filter %s and %s watches (respectively)", companyWatchType, customerWatchType)
+)
+
+// Watch is a company or customer watch (by ID or name). Its authToken and signingSecret are never returned.
+type Watch struct {
+	ID         string    `json:"watchID"`
+	Type       string    `json:"type"`
+	CompanyID  string    `json:"companyID,omitempty"`
+	CustomerID string    `json:"customerID,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Webhook    string    `json:"webhook"`
+	Notifier   string    `json:"notifier,omitempty"`
+	MinMatch   float64   `json:"minMatch,omitempty"`
+	Lists      []string  `json:"lists,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func (w *Watch) isNameWatch() bool {
+	return w.Type == companyNameWatchType || w.Type == customerNameWatchType
+}
+
+// WatchDelivery is one attempt at calling a watch's webhook, as recorded in webhook_stats
+type WatchDelivery struct {
+	AttemptedAt time.Time `json:"attemptedAt"`
+	Status      int       `json:"status"`
+	Delivered   bool      `json:"delivered"`
+}
+
+// watchFilter holds the query parameters for listing watches
+type watchFilter struct {
+	watchType string
+	entityID  string // companyID or customerID
+	name      string // case-insensitive substring of name watches
+	webhook   string
+
+	limit, offset int
+}
+
+// where returns the SQL where clause (on allWatchesQuery as w) and arguments for the filter.
+func (f watchFilter) where() (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	if f.watchType != "" {
+		clauses = append(clauses, "w.watch_type = ?")
+		args = append(args, f.watchType)
+	}
+	if f.entityID != "" {
+		clauses = append(clauses, "w.entity_id = ?")
+		args = append(args, f.entityID)
+	}
+	if f.name != "" {
+		clauses = append(clauses, "lower(w.name) like ?")
+		args = append(args, "%"+strings.ToLower(f.name)+"%")
+	}
+	if f.webhook != "" {
+		clauses = append(clauses, "w.webhook = ?")
+		args = append(args, f.webhook)
+	}
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return " where " + strings.Join(clauses, " and "), args
+}
+
+func readWatchFilter(r *http.Request) (watchFilter, error) {
+	q := r.URL.Query()
+	filter := watchFilter{
+		watchType: q.Get("type"),
+		name:      q.Get("name"),
+		webhook:   q.Get("webhook"),
+		limit:     extractSearchLimit(r),
+		offset:    extractOffset(r),
+	}
+	switch filter.watchType {
+	case "", companyWatchType, companyNameWatchType, customerWatchType, customerNameWatchType:
+	default:
+		return filter, errUnknownWatchType
+	}
+	// companyID and customerID share a column, so they're filtered along with the type of watch
+	for param, tpe := range map[string]string{"companyID": companyWatchType, "customerID": customerWatchType} {
+		v := q.Get(param)
+		if v == "" {
+			continue
+		}
+		if filter.watchType != "" && filter.watchType != tpe {
+			return filter, errWatchIDFilter
+		}
+		filter.watchType, filter.entityID = tpe, v
+	}
+	return filter, nil
+}
+
+// extractOffset returns the ?offset= query parameter used for paging through results
+func extractOffset(r *http.Request) int {
+	n, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// watchUpdate is the body of PATCH /watches/{watchID}, only the fields which are set are changed.
+type watchUpdate struct {
+	Webhook       *string  `json:"webhook"`
+	AuthToken     *string  `json:"authToken"`
+	SigningSecret *string  `json:"signingSecret"`
+	Notifier      *string  `json:"notifier"`
+	MinMatch      *float64 `json:"minMatch"`
+	Lists         []string `json:"lists"`
+}
+
+// validate checks and normalizes update against the watch it's being applied to.
+func (update *watchUpdate) validate(w *Watch) error {
+	if update.Webhook != nil {
+		webhook, err := validateWebhook(*update.Webhook)
+		if err != nil {
+			return err
+		}
+		update.Webhook = &webhook
+	}
+	if update.AuthToken != nil && *update.AuthToken == "" {
+		return errNoAuthToken
+	}
+	if update.SigningSecret != nil {
+		req := watchRequest{SigningSecret: *update.SigningSecret}
+		if req.SigningSecret == "" {
+			return errors.New("signingSecret can't be empty")
+		}
+		if err := req.setSigningSecret(); err != nil {
+			return err
+		}
+	}
+	if update.Notifier != nil {
+		if *update.Notifier == "" {
+			return errors.New("notifier can't be empty")
+		}
+		if _, err := findNotifier(*update.Notifier); err != nil {
+			return err
+		}
+		if *update.Notifier == webhookNotifierName && w.Webhook == "" && update.Webhook == nil {
+			return errors.New("a webhook is required to use the webhook notifier")
+		}
+	}
+	if update.MinMatch != nil || update.Lists != nil {
+		if !w.isNameWatch() {
+			return errNotNameWatch
+		}
+		req := watchRequest{Lists: update.Lists}
+		if update.MinMatch != nil {
+			req.MinMatch = *update.MinMatch
+		}
+		if err := req.validateNameWatch(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addWatchRoutes(logger log.Logger, r *mux.Router, repo watchRepository) {
+	r.Methods("GET").Path("/watches").HandlerFunc(listWatches(logger, repo))
+	r.Methods("GET").Path("/watches/{watchID}").HandlerFunc(getWatch(logger, repo))
+	r.Methods("PATCH").Path("/watches/{watchID}").HandlerFunc(updateWatch(logger, repo))
+	r.Methods("GET").Path("/watches/{watchID}/deliveries").HandlerFunc(getWatchDeliveries(logger, repo))
+}
+
+func listWatches(logger log.Logger, repo watchRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		filter, err := readWatchFilter(r)
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		watches, err := repo.listWatches(filter)
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if watches == nil {
+			watches = []*Watch{}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(watches)
+	}
+}
+
+// findWatch reads the watch from the request's path and writes a 404 if it doesn't exist.
+func findWatch(w http.ResponseWriter, r *http.Request, repo watchRepository) *Watch {
+	watchID := getWatchID(w, r)
+	if watchID == "" {
+		return nil
+	}
+	watch, err := repo.getWatch(watchID)
+	if err != nil {
+		moovhttp.Problem(w, err)
+		return nil
+	}
+	if watch == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil
+	}
+	return watch
+}
+
+func getWatch(logger log.Logger, repo watchRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		watch := findWatch(w, r, repo)
+		if watch == nil {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(watch)
+	}
+}
+
+func updateWatch(logger log.Logger, repo watchRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		watch := findWatch(w, r, repo)
+		if watch == nil {
+			return
+		}
+		var update watchUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if err := update.validate(watch); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if err := repo.updateWatch(watch.ID, update); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		watch, err := repo.getWatch(watch.ID)
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(watch)
+	}
+}
+
+func getWatchDeliveries(logger log.Logger, repo watchRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		watch := findWatch(w, r, repo)
+		if watch == nil {
+			return
+		}
+		var since time.Time
+		if v := r.URL.Query().Get("since"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				moovhttp.Problem(w, fmt.Errorf("invalid since: %v", err))
+				return
+			}
+			since = t.Local() // timestamps are stored in local time
+		}
+		deliveries, err := repo.getWatchDeliveries(watch.ID, since, extractSearchLimit(r), extractOffset(r))
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if deliveries == nil {
+			deliveries = []WatchDelivery{}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(deliveries)
+	}
+}
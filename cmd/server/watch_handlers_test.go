@@ -0,0 +1,273 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/gorilla/mux"
+)
+
+func TestWatches_list(t *testing.T) {
+	repo := createTestWatchRepository(t)
+	defer repo.close()
+
+	companyID := base.ID()
+	companyWatchID, _ := repo.addCompanyWatch(companyID, watchRequest{Webhook: "https://moov.io/1", AuthToken: "foo"})
+
+	nameWatchID, _ := repo.addCustomerNameWatch("John Doe", watchRequest{Webhook: "https://moov.io/2", AuthToken: "foo", MinMatch: 0.95, Lists: []string{sdnList}})
+	customerWatchID, _ := repo.addCustomerWatch(base.ID(), watchRequest{Webhook: "https://moov.io/2", AuthToken: "foo"})
+	deletedID, _ := repo.addCompanyNameWatch("Acme", watchRequest{Webhook: "https://moov.io/3", AuthToken: "foo"})
+	if err := repo.removeCompanyNameWatch(deletedID); err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	addWatchRoutes(nil, router, repo)
+
+	list := func(query string) []Watch {
+		t.Helper()
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/watches"+query, nil))
+		w.Flush()
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: bogus status code: %d: %s", query, w.Code, w.Body.String())
+		}
+		var watches []Watch
+		if err := json.NewDecoder(w.Body).Decode(&watches); err != nil {
+			t.Fatal(err)
+		}
+		return watches
+	}
+
+	// newest first, removed watches are hidden
+	watches := list("")
+	if len(watches) != 3 {
+		t.Fatalf("unexpected watches: %#v", watches)
+	}
+	if watches[0].ID != customerWatchID || watches[2].ID != companyWatchID {
+		t.Errorf("unexpected order: %#v", watches)
+	}
+
+	// filters
+	if watches := list("?type=customerName"); len(watches) != 1 || watches[0].ID != nameWatchID {
+		t.Errorf("unexpected watches: %#v", watches)
+	} else if watches[0].Name != "John Doe" || watches[0].MinMatch != 0.95 || len(watches[0].Lists) != 1 {
+		t.Errorf("unexpected name watch: %#v", watches[0])
+	}
+	if watches := list("?companyID=" + companyID); len(watches) != 1 || watches[0].ID != companyWatchID || watches[0].CompanyID != companyID {
+		t.Errorf("unexpected watches: %#v", watches)
+	}
+	if watches := list("?name=john"); len(watches) != 1 || watches[0].ID != nameWatchID {
+		t.Errorf("unexpected watches: %#v", watches)
+	}
+	if watches := list("?webhook=https://moov.io/2"); len(watches) != 2 {
+		t.Errorf("unexpected watches: %#v", watches)
+	}
+
+	// pagination
+	first, second := list("?limit=2"), list("?limit=2&offset=2")
+	if len(first) != 2 || len(second) != 1 || second[0].ID != companyWatchID {
+		t.Errorf("first=%#v second=%#v", first, second)
+	}
+
+	// a customer with the same ID as the company isn't listed
+	sameIDWatchID, _ := repo.addCustomerWatch(companyID, watchRequest{Webhook: "https://moov.io/4", AuthToken: "foo"})
+	if watches := list("?companyID=" + companyID); len(watches) != 1 || watches[0].ID != companyWatchID {
+		t.Errorf("unexpected watches: %#v", watches)
+	}
+	if watches := list("?type=customer&customerID=" + companyID); len(watches) != 1 || watches[0].ID != sameIDWatchID {
+		t.Errorf("unexpected watches: %#v", watches)
+	}
+
+	// unknown type, or an ID of the other type
+	for _, query := range []string{"?type=other", "?type=customer&companyID=" + companyID, "?companyID=" + companyID + "&customerID=" + companyID} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/watches"+query, nil))
+		w.Flush()
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: bogus status code: %d", query, w.Code)
+		}
+	}
+}
+
+func TestWatches_get(t *testing.T) {
+	repo := createTestWatchRepository(t)
+	defer repo.close()
+
+	customerID := base.ID()
+	watchID, _ := repo.addCustomerWatch(customerID, watchRequest{Webhook: "https://moov.io/1", AuthToken: "foo", SigningSecret: "a-long-enough-secret"})
+
+	router := mux.NewRouter()
+	addWatchRoutes(nil, router, repo)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/watches/"+watchID, nil))
+	w.Flush()
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "a-long-enough-secret") || strings.Contains(w.Body.String(), "foo") {
+		t.Errorf("secrets returned: %s", w.Body.String())
+	}
+	var watch Watch
+	if err := json.NewDecoder(w.Body).Decode(&watch); err != nil {
+		t.Fatal(err)
+	}
+	if watch.ID != watchID || watch.Type != customerWatchType || watch.CustomerID != customerID || watch.CreatedAt.IsZero() {
+		t.Errorf("unexpected watch: %#v", watch)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/watches/"+base.ID(), nil))
+	w.Flush()
+	if w.Code != http.StatusNotFound {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+}
+
+func TestWatches_update(t *testing.T) {
+	repo := createTestWatchRepository(t)
+	defer repo.close()
+
+	watchID, _ := repo.addCompanyWatch(base.ID(), watchRequest{Webhook: "https://moov.io/1", AuthToken: "foo"})
+	nameWatchID, _ := repo.addCompanyNameWatch("Acme", watchRequest{Webhook: "https://moov.io/2", AuthToken: "foo"})
+
+	router := mux.NewRouter()
+	addWatchRoutes(nil, router, repo)
+
+	patch := func(watchID string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("PATCH", "/watches/"+watchID, strings.NewReader(body)))
+		w.Flush()
+		return w
+	}
+
+	w := patch(watchID, `{"webhook": "https://moov.io/new", "authToken": "bar", "signingSecret": "another-long-secret"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	var watch Watch
+	if err := json.NewDecoder(w.Body).Decode(&watch); err != nil {
+		t.Fatal(err)
+	}
+	if watch.Webhook != "https://moov.io/new" {
+		t.Errorf("unexpected watch: %#v", watch)
+	}
+	watches, _ := repo.getWatchesCursor(nil, 100).Next()
+	for i := range watches {
+		if watches[i].id == watchID && (watches[i].authToken != "bar" || watches[i].signingSecret != "another-long-secret") {
+			t.Errorf("unexpected watch: %#v", watches[i])
+		}
+	}
+
+	// name watch options, the default minMatch isn't saved when only the lists change
+	if w := patch(nameWatchID, `{"lists": ["sdn"]}`); w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	var minMatch float64
+	if err := repo.db.QueryRow(`select min_match from name_watch_options where watch_id = ?;`, nameWatchID).Scan(&minMatch); err != nil || minMatch != 0 {
+		t.Errorf("min_match=%v err=%v", minMatch, err)
+	}
+	if w := patch(nameWatchID, `{"minMatch": 0.8, "lists": ["DPL"]}`); w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	if watch, _ := repo.getWatch(nameWatchID); watch.MinMatch != 0.8 || len(watch.Lists) != 1 || watch.Lists[0] != dplList || watch.Webhook != "https://moov.io/2" {
+		t.Errorf("unexpected watch: %#v", watch)
+	}
+	if w := patch(nameWatchID, `{"lists": ["sdn", "el"]}`); w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	if watch, _ := repo.getWatch(nameWatchID); watch.MinMatch != 0.8 || len(watch.Lists) != 2 {
+		t.Errorf("unexpected watch: %#v", watch)
+	}
+
+	// invalid updates
+	for _, body := range []string{
+		`{"webhook": "http://moov.io"}`,
+		`{"authToken": ""}`,
+		`{"signingSecret": "short"}`,
+		`{"minMatch": 0.8}`, // not a name watch
+		`not json`,
+	} {
+		if w := patch(watchID, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: bogus status code: %d", body, w.Code)
+		}
+	}
+	if w := patch(nameWatchID, `{"lists": ["other"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+	if w := patch(base.ID(), `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+}
+
+func TestWatches_deliveries(t *testing.T) {
+	repo := createTestWatchRepository(t)
+	defer repo.close()
+	webhookRepo := &sqliteWebhookRepository{repo.db}
+
+	watchID, _ := repo.addCustomerWatch(base.ID(), watchRequest{Webhook: "https://moov.io/1", AuthToken: "foo"})
+	start := time.Now().Add(-1 * time.Hour)
+	for i := 0; i < 5; i++ {
+		status := http.StatusOK
+		if i%2 == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		if err := webhookRepo.recordWebhook(watchID, start.Add(time.Duration(i)*time.Minute), status); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router := mux.NewRouter()
+	addWatchRoutes(nil, router, repo)
+
+	get := func(query string) []WatchDelivery {
+		t.Helper()
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/watches/%s/deliveries%s", watchID, query), nil))
+		w.Flush()
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: bogus status code: %d: %s", query, w.Code, w.Body.String())
+		}
+		var deliveries []WatchDelivery
+		if err := json.NewDecoder(w.Body).Decode(&deliveries); err != nil {
+			t.Fatal(err)
+		}
+		return deliveries
+	}
+
+	deliveries := get("")
+	if len(deliveries) != 5 {
+		t.Fatalf("unexpected deliveries: %#v", deliveries)
+	}
+	if deliveries[0].Status != http.StatusServiceUnavailable || deliveries[0].Delivered || !deliveries[1].Delivered {
+		t.Errorf("unexpected deliveries: %#v", deliveries)
+	}
+	if deliveries := get("?limit=2&offset=4"); len(deliveries) != 1 {
+		t.Errorf("unexpected deliveries: %#v", deliveries)
+	}
+	since := start.Add(150 * time.Second).UTC().Format(time.RFC3339)
+	if deliveries := get("?since=" + since); len(deliveries) != 2 {
+		t.Errorf("unexpected deliveries since %s: %#v", since, deliveries)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/watches/%s/deliveries?since=yesterday", watchID), nil))
+	w.Flush()
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/moov-io/base"
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+const (
+	watchRunsPath = "/watches/runs"
+	watchRunPath  = "/watches/runs/{runID}"
+)
+
+var (
+	// watchResearchWorkers is how many watches are re-searched (and have their webhook called) at once
+	watchResearchWorkers = 10
+
+	errNoRunID = errors.New("no runID found")
+)
+
+func readWatchResearchWorkers(str string) int {
+	if n, _ := strconv.Atoi(str); n > 0 {
+		return n
+	}
+	return watchResearchWorkers
+}
+
+// watchRunStatus is the state of a re-search of every watch
+type watchRunStatus string
+
+const (
+	watchRunRunning  watchRunStatus = "running"
+	watchRunFinished watchRunStatus = "finished"
+
+	// watchRunInterrupted runs were stopped by a shutdown and are resumed on the next start
+	watchRunInterrupted watchRunStatus = "interrupted"
+
+	// watchRunSuperseded runs were interrupted, but a newer run was resumed instead
+	watchRunSuperseded watchRunStatus = "superseded"
+)
+
+// watchRun tracks the progress of re-searching every watch after a data refresh.
+type watchRun struct {
+	ID           string         `json:"runID"`
+	DownloadedAt time.Time      `json:"downloadedAt"`
+	Status       watchRunStatus `json:"status"`
+
+	// Started is how many watches were handed to a worker, Processed how many of those have been
+	// re-searched and Failed how many of the processed watches returned an error.
+	Started   int `json:"started"`
+	Processed int `json:"processed"`
+	Failed    int `json:"failed"`
+
+	StartedAt  time.Time  `json:"startedAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+type watchRunRepository interface {
+	startWatchRun(downloadedAt time.Time) (*watchRun, error)
+	updateWatchRun(run *watchRun) error
+
+	getWatchRun(runID string) (*watchRun, error)
+	getWatchRuns(limit int) ([]*watchRun, error)
+
+	// getUnfinishedWatchRuns returns runs which are running or interrupted, newest first
+	getUnfinishedWatchRuns() ([]*watchRun, error)
+
+	close() error
+}
+
+type sqliteWatchRunRepository struct {
+	db *sql.DB
+}
+
+func (r *sqliteWatchRunRepository) close() error {
+	return r.db.Close()
+}
+
+func (r *sqliteWatchRunRepository) startWatchRun(downloadedAt time.Time) (*watchRun, error) {
+	now := time.Now()
+	run := &watchRun{
+		ID:           base.ID(),
+		DownloadedAt: downloadedAt,
+		Status:       watchRunRunning,
+		StartedAt:    now,
+		UpdatedAt:    now,
+	}
+	query := `insert into watch_runs (run_id, downloaded_at, status, started, processed, failed, started_at, updated_at) values (?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(run.ID, run.DownloadedAt, run.Status, run.Started, run.Processed, run.Failed, run.StartedAt, run.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (r *sqliteWatchRunRepository) updateWatchRun(run *watchRun) error {
+	query := `update watch_runs set status = ?, started = ?, processed = ?, failed = ?, updated_at = ?, finished_at = ? where run_id = ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(run.Status, run.Started, run.Processed, run.Failed, run.UpdatedAt, run.FinishedAt, run.ID)
+	return err
+}
+
+func (r *sqliteWatchRunRepository) getWatchRun(runID string) (*watchRun, error) {
+	runs, err := r.queryWatchRuns(`select run_id, downloaded_at, status, started, processed, failed, started_at, updated_at, finished_at from watch_runs where run_id = ? limit 1;`, runID)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return runs[0], nil
+}
+
+func (r *sqliteWatchRunRepository) getWatchRuns(limit int) ([]*watchRun, error) {
+	return r.queryWatchRuns(`select run_id, downloaded_at, status, started, processed, failed, started_at, updated_at, finished_at from watch_runs order by started_at desc limit ?;`, limit)
+}
+
+func (r *sqliteWatchRunRepository) getUnfinishedWatchRuns() ([]*watchRun, error) {
+	return r.queryWatchRuns(`select run_id, downloaded_at, status, started, processed, failed, started_at, updated_at, finished_at from watch_runs where status in (?, ?) order by started_at desc;`, watchRunRunning, watchRunInterrupted)
+}
+
+func (r *sqliteWatchRunRepository) queryWatchRuns(query string, args ...interface{}) ([]*watchRun, error) {
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*watchRun
+	for rows.Next() {
+		var run watchRun
+		err := rows.Scan(&run.ID, &run.DownloadedAt, &run.Status, &run.Started, &run.Processed, &run.Failed, &run.StartedAt, &run.UpdatedAt, &run.FinishedAt)
+		if err != nil {
+			return nil, fmt.Errorf("queryWatchRuns: %v", err)
+		}
+		runs = append(runs, &run)
+	}
+	return runs, rows.Err()
+}
+
+// watchRunProgress counts the watches re-searched by a run's workers and periodically saves them.
+type watchRunProgress struct {
+	logger log.Logger
+	repo   watchRunRepository
+
+	// saveEvery is how many processed watches go between saving the run
+	saveEvery int
+
+	mu  sync.Mutex
+	run *watchRun
+}
+
+func (p *watchRunProgress) started() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.run.Started++
+}
+
+func (p *watchRunProgress) processed(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.run.Processed++
+	if err != nil {
+		p.run.Failed++
+	}
+	if p.saveEvery > 0 && p.run.Processed%p.saveEvery == 0 {
+		p.save()
+	}
+}
+
+func (p *watchRunProgress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	p.run.Status = watchRunFinished
+	p.run.FinishedAt = &now
+	p.save()
+}
+
+// interrupt saves the run as interrupted, so it's resumed on the next start
+func (p *watchRunProgress) interrupt() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.run.Status = watchRunInterrupted
+	p.save()
+}
+
+// save writes the run to our repository, callers must hold p.mu
+func (p *watchRunProgress) save() {
+	p.run.UpdatedAt = time.Now()
+	if err := p.repo.updateWatchRun(p.run); err != nil && p.logger != nil {
+		p.logger.Log("search", fmt.Sprintf("async: problem saving watch run %s: %v", p.run.ID, err))
+	}
+}
+
+// researchWatches reads every watch from cursor and hands them to a bounded pool of workers which each
+// call research. It returns once every watch has been processed.
+//
+// No more watches are handed out once ctx is done, and ctx's error is returned after the workers have
+// finished the watches they were given.
+func researchWatches(ctx context.Context, cursor *watchCursor, workers int, progress *watchRunProgress, research func(watch) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	queue := make(chan watch, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for w := range queue {
+				progress.processed(research(w))
+			}
+		}()
+	}
+
+	var err error
+read:
+	for {
+		var watches []watch
+		watches, err = cursor.Next()
+		if err != nil || len(watches) == 0 {
+			break
+		}
+		for i := range watches {
+			if err = ctx.Err(); err != nil {
+				break read
+			}
+			progress.started()
+			queue <- watches[i]
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return err
+}
+
+func addWatchRunRoutes(logger log.Logger, adminServer *adminHTTPServer, repo watchRunRepository) {
+	adminServer.AddHandler(watchRunsPath, listWatchRuns(logger, repo))
+	adminServer.AddHandler(watchRunPath, getWatchRunHandler(logger, repo))
+}
+
+// listWatchRuns returns the most recent watch re-search runs, limited by ?limit=
+func listWatchRuns(logger log.Logger, repo watchRunRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		runs, err := repo.getWatchRuns(extractSearchLimit(r))
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if runs == nil {
+			runs = []*watchRun{}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(runs)
+	}
+}
+
+func getWatchRunHandler(logger log.Logger, repo watchRunRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		runID := mux.Vars(r)["runID"]
+		if runID == "" {
+			moovhttp.Problem(w, errNoRunID)
+			return
+		}
+		run, err := repo.getWatchRun(runID)
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if run == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(run)
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+func TestWatchRuns__readWorkers(t *testing.T) {
+	if n := readWatchResearchWorkers(""); n != watchResearchWorkers {
+		t.Errorf("got %d", n)
+	}
+	if n := readWatchResearchWorkers("25"); n != 25 {
+		t.Errorf("got %d", n)
+	}
+	if n := readWatchResearchWorkers("-1"); n != watchResearchWorkers {
+		t.Errorf("got %d", n)
+	}
+}
+
+func TestWatchRuns__repository(t *testing.T) {
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	repo := &sqliteWatchRunRepository{db.db}
+
+	downloadedAt := time.Now().Add(-1 * time.Minute)
+	run, err := repo.startWatchRun(downloadedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.ID == "" || run.Status != watchRunRunning {
+		t.Errorf("unexpected run: %#v", run)
+	}
+
+	run.Started, run.Processed, run.Failed = 10, 8, 1
+	if err := repo.updateWatchRun(run); err != nil {
+		t.Fatal(err)
+	}
+	found, err := repo.getWatchRun(run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Started != 10 || found.Processed != 8 || found.Failed != 1 || found.FinishedAt != nil {
+		t.Errorf("unexpected run: %#v", found)
+	}
+	if !found.DownloadedAt.Equal(downloadedAt) {
+		t.Errorf("downloadedAt=%v expected %v", found.DownloadedAt, downloadedAt)
+	}
+
+	next, _ := repo.startWatchRun(time.Now())
+	runs, err := repo.getWatchRuns(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].ID != next.ID {
+		t.Errorf("unexpected runs: %#v", runs)
+	}
+
+	// missing run
+	if run, err := repo.getWatchRun(base.ID()); run != nil || err != nil {
+		t.Errorf("run=%#v err=%v", run, err)
+	}
+}
+
+func TestWatchRuns__researchWatches(t *testing.T) {
+	watchRepo := createTestWatchRepository(t)
+	defer watchRepo.close()
+	runRepo := &sqliteWatchRunRepository{watchRepo.db}
+
+	for i := 0; i < 50; i++ {
+		if _, err := watchRepo.addCustomerWatch(base.ID(), watchRequest{Webhook: "https://moov.io", AuthToken: "foo"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run, err := runRepo.startWatchRun(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := &watchRunProgress{repo: runRepo, saveEvery: 10, run: run}
+
+	var mu sync.Mutex
+	var active, maxActive int
+	seen := make(map[string]int)
+
+	err = researchWatches(context.Background(), watchRepo.getWatchesCursor(nil, 12), 4, progress, func(w watch) error {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		seen[w.id]++
+		failed := len(seen)%5 == 0
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+		if failed {
+			return errors.New("bad thing")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress.finish()
+
+	if len(seen) != 50 {
+		t.Errorf("researched %d watches", len(seen))
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("watch %s researched %d times", id, n)
+		}
+	}
+	if maxActive < 2 || maxActive > 4 {
+		t.Errorf("maxActive=%d", maxActive)
+	}
+
+	saved, _ := runRepo.getWatchRun(run.ID)
+	if saved.Status != watchRunFinished || saved.FinishedAt == nil {
+		t.Errorf("unexpected run: %#v", saved)
+	}
+	if saved.Started != 50 || saved.Processed != 50 || saved.Failed != 10 {
+		t.Errorf("started=%d processed=%d failed=%d", saved.Started, saved.Processed, saved.Failed)
+	}
+}
+
+func TestWatchRuns__interruptAndResume(t *testing.T) {
+	watchRepo := createTestWatchRepository(t)
+	defer watchRepo.close()
+	runRepo := &sqliteWatchRunRepository{watchRepo.db}
+
+	for i := 0; i < 5; i++ {
+		if _, err := watchRepo.addCustomerWatch(base.ID(), watchRequest{Webhook: "https://moov.io", AuthToken: "foo"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	older, _ := runRepo.startWatchRun(time.Now().Add(-time.Hour))
+	run, err := runRepo.startWatchRun(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := &watchRunProgress{repo: runRepo, run: run}
+
+	// no watches are handed out once we're shutting down
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	researched := 0
+	err = researchWatches(ctx, watchRepo.getWatchesCursor(nil, 2), 2, progress, func(w watch) error {
+		researched++
+		return nil
+	})
+	if err != context.Canceled || researched != 0 {
+		t.Errorf("researched=%d err=%v", researched, err)
+	}
+	progress.interrupt()
+
+	runs, err := runRepo.getUnfinishedWatchRuns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].ID != run.ID || runs[0].Status != watchRunInterrupted {
+		t.Errorf("unexpected runs: %#v", runs)
+	}
+
+	// the newest run is resumed and older ones are superseded
+	s := &searcher{logger: log.NewNopLogger()}
+	resumed := s.resumableWatchRun(runRepo)
+	if resumed == nil || resumed.ID != run.ID || resumed.Status != watchRunRunning {
+		t.Fatalf("unexpected run: %#v", resumed)
+	}
+	if saved, _ := runRepo.getWatchRun(older.ID); saved.Status != watchRunSuperseded {
+		t.Errorf("unexpected run: %#v", saved)
+	}
+	if runs, _ := runRepo.getUnfinishedWatchRuns(); len(runs) != 1 {
+		t.Errorf("unexpected runs: %#v", runs)
+	}
+}
+
+func TestWatchRuns__adminRoutes(t *testing.T) {
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	repo := &sqliteWatchRunRepository{db.db}
+
+	run, err := repo.startWatchRun(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc(watchRunsPath, listWatchRuns(nil, repo))
+	router.HandleFunc(watchRunPath, getWatchRunHandler(nil, repo))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", watchRunsPath, nil))
+	w.Flush()
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	var runs []watchRun
+	if err := json.NewDecoder(w.Body).Decode(&runs); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].ID != run.ID || runs[0].Status != watchRunRunning {
+		t.Errorf("unexpected runs: %#v", runs)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/watches/runs/%s", run.ID), nil))
+	w.Flush()
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/watches/runs/%s", base.ID()), nil))
+	w.Flush()
+	if w.Code != http.StatusNotFound {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", watchRunsPath, nil))
+	w.Flush()
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+}
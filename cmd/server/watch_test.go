@@ -6,6 +6,7 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/moov-io/base"
 
@@ -58,7 +59,7 @@ func TestCompanyNameWatch(t *testing.T) {
 
 	// Add
 	name := base.ID()
-	watchID, err := repo.addCompanyNameWatch(name, "https://moov.io", "authToken")
+	watchID, err := repo.addCompanyNameWatch(name, watchRequest{Webhook: "https://moov.io", AuthToken: "authToken"})
 	if err != nil {
 		t.Errorf("name=%q got error: %v", name, err)
 	}
@@ -104,7 +105,7 @@ func TestCustomerNameWatch(t *testing.T) {
 
 	// Add
 	name := base.ID()
-	watchID, err := repo.addCustomerNameWatch(name, "https://moov.io", "authToken")
+	watchID, err := repo.addCustomerNameWatch(name, watchRequest{Webhook: "https://moov.io", AuthToken: "authToken"})
 	if err != nil {
 		t.Errorf("name=%q got error: %v", name, err)
 	}
@@ -122,7 +123,7 @@ func TestWatchCursor_ID(t *testing.T) {
 	repo := createTestWatchRepository(t)
 	defer repo.close()
 
-	cur := repo.getWatchesCursor(log.NewNopLogger(), 4) // batchSize is divided in 4 parts to equally grab customer, customer name, company, and company name watches
+	cur := repo.getWatchesCursor(log.NewNopLogger(), 2) // watches of every type are returned oldest first
 
 	// insert some watches
 	watchID1, _ := repo.addCustomerWatch(base.ID(), watchRequest{Webhook: "https://moov.io/1"})
@@ -140,8 +141,8 @@ func TestWatchCursor_ID(t *testing.T) {
 			if firstBatch[i].webhook != "https://moov.io/1" || firstBatch[i].customerID == "" {
 				t.Errorf("watch %#v didn't match", firstBatch[i])
 			}
-		case watchID3:
-			if firstBatch[i].webhook != "https://moov.io/3" || firstBatch[i].companyID == "" {
+		case watchID2:
+			if firstBatch[i].webhook != "https://moov.io/2" || firstBatch[i].customerID == "" {
 				t.Errorf("watch %#v didn't match", firstBatch[i])
 			}
 		default:
@@ -154,7 +155,7 @@ func TestWatchCursor_ID(t *testing.T) {
 	if len(secondBatch) != 1 || err != nil {
 		t.Fatalf("len(secondBatch)=%d expected 1, err=%v", len(secondBatch), err)
 	}
-	if secondBatch[0].id != watchID2 || secondBatch[0].webhook != "https://moov.io/2" || secondBatch[0].customerID == "" {
+	if secondBatch[0].id != watchID3 || secondBatch[0].webhook != "https://moov.io/3" || secondBatch[0].companyID == "" {
 		t.Errorf("unknown watch: %v", secondBatch[0])
 	}
 }
@@ -163,12 +164,12 @@ func TestWatchCursor_Names(t *testing.T) {
 	repo := createTestWatchRepository(t)
 	defer repo.close()
 
-	cur := repo.getWatchesCursor(log.NewNopLogger(), 4)
+	cur := repo.getWatchesCursor(log.NewNopLogger(), 2)
 
 	// insert some watches
-	watchID1, _ := repo.addCustomerNameWatch("foo corp", "https://moov.io/1", base.ID())
-	watchID2, _ := repo.addCustomerNameWatch("jane doe", "https://moov.io/2", base.ID())
-	watchID3, _ := repo.addCompanyNameWatch("bar corp", "https://moov.io/3", base.ID())
+	watchID1, _ := repo.addCustomerNameWatch("foo corp", watchRequest{Webhook: "https://moov.io/1", AuthToken: base.ID()})
+	watchID2, _ := repo.addCustomerNameWatch("jane doe", watchRequest{Webhook: "https://moov.io/2", AuthToken: base.ID(), MinMatch: 0.85, Lists: []string{"sdn", "dpl"}})
+	watchID3, _ := repo.addCompanyNameWatch("bar corp", watchRequest{Webhook: "https://moov.io/3", AuthToken: base.ID()})
 
 	// get first batch (should have 2 watches)
 	firstBatch, err := cur.Next()
@@ -184,13 +185,16 @@ func TestWatchCursor_Names(t *testing.T) {
 			if firstBatch[i].customerName != "foo corp" {
 				t.Errorf("watch %#v didn't match", firstBatch[i])
 			}
-		case watchID3:
-			if firstBatch[i].webhook != "https://moov.io/3" {
+		case watchID2:
+			if firstBatch[i].webhook != "https://moov.io/2" {
 				t.Errorf("watch %#v didn't match", firstBatch[i])
 			}
-			if firstBatch[i].companyName != "bar corp" {
+			if firstBatch[i].customerName != "jane doe" {
 				t.Errorf("watch %#v didn't match", firstBatch[i])
 			}
+			if firstBatch[i].minMatch != 0.85 || len(firstBatch[i].lists) != 2 {
+				t.Errorf("unexpected name watch options: %#v", firstBatch[i])
+			}
 		default:
 			t.Errorf("unknown watch: %v", firstBatch[i])
 		}
@@ -201,7 +205,160 @@ func TestWatchCursor_Names(t *testing.T) {
 	if len(secondBatch) != 1 || err != nil {
 		t.Fatalf("len(secondBatch)=%d expected 1, err=%v", len(secondBatch), err)
 	}
-	if secondBatch[0].id != watchID2 || secondBatch[0].webhook != "https://moov.io/2" || secondBatch[0].customerName != "jane doe" {
+	if secondBatch[0].id != watchID3 || secondBatch[0].webhook != "https://moov.io/3" || secondBatch[0].companyName != "bar corp" {
 		t.Errorf("unknown watch: %v", secondBatch[0])
 	}
 }
+
+func TestWatchCursor_sameCreatedAt(t *testing.T) {
+	repo := createTestWatchRepository(t)
+	defer repo.close()
+
+	// Insert thousands of watches across every table which share a handful of created_at values,
+	// so batches end in the middle of watches created at the same time.
+	tx, err := repo.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := []string{
+		`insert into company_watches (id, company_id, webhook, auth_token, created_at) values (?, ?, 'https://moov.io', 'foo', ?);`,
+		`insert into company_name_watches (id, name, webhook, auth_token, created_at) values (?, ?, 'https://moov.io', 'foo', ?);`,
+		`insert into customer_watches (id, customer_id, webhook, auth_token, created_at) values (?, ?, 'https://moov.io', 'foo', ?);`,
+		`insert into customer_name_watches (id, name, webhook, auth_token, created_at) values (?, ?, 'https://moov.io', 'foo', ?);`,
+	}
+	now := time.Now()
+	createdAt := []time.Time{now.Add(-1 * time.Hour), now, now}
+	expected := make(map[string]bool)
+	for i := 0; i < 3000; i++ {
+		watchID := base.ID()
+		if _, err := tx.Exec(queries[i%len(queries)], watchID, base.ID(), createdAt[i%len(createdAt)]); err != nil {
+			t.Fatal(err)
+		}
+		expected[watchID] = true
+	}
+	// removed watches are skipped
+	if _, err := tx.Exec(`insert into customer_watches (id, customer_id, webhook, auth_token, created_at, deleted_at) values (?, ?, 'https://moov.io', 'foo', ?, ?);`, base.ID(), base.ID(), now, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, batchSize := range []int{7, 100, 999, 5000} {
+		seen := make(map[string]bool)
+		cur := repo.getWatchesCursor(log.NewNopLogger(), batchSize)
+		for {
+			watches, err := cur.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(watches) == 0 {
+				break
+			}
+			if len(watches) > batchSize {
+				t.Fatalf("batchSize=%d got %d watches", batchSize, len(watches))
+			}
+			for i := range watches {
+				if seen[watches[i].id] {
+					t.Fatalf("batchSize=%d watch %s returned twice", batchSize, watches[i].id)
+				}
+				if !expected[watches[i].id] {
+					t.Fatalf("batchSize=%d unexpected watch %s", batchSize, watches[i].id)
+				}
+				seen[watches[i].id] = true
+			}
+		}
+		if len(seen) != len(expected) {
+			t.Errorf("batchSize=%d found %d of %d watches", batchSize, len(seen), len(expected))
+		}
+	}
+}
+
+func TestWatch_validateNameWatch(t *testing.T) {
+	req := watchRequest{MinMatch: 0.9, Lists: []string{" SDN", "alt"}}
+	if err := req.validateNameWatch(); err != nil {
+		t.Fatal(err)
+	}
+	if req.Lists[0] != "sdn" {
+		t.Errorf("lists weren't normalized: %v", req.Lists)
+	}
+
+	req = watchRequest{MinMatch: 1.5}
+	if err := req.validateNameWatch(); err == nil {
+		t.Error("expected error")
+	}
+	req = watchRequest{Lists: []string{"other"}}
+	if err := req.validateNameWatch(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestWatch_nameWatchOptions(t *testing.T) {
+	w := watch{}
+	if w.nameMinMatch() != defaultNameWatchMinMatch {
+		t.Errorf("unexpected minMatch: %.2f", w.nameMinMatch())
+	}
+	for i := range nameWatchLists {
+		if !w.searchesList(nameWatchLists[i]) {
+			t.Errorf("expected to search %s", nameWatchLists[i])
+		}
+	}
+
+	w = watch{minMatch: 0.8, lists: splitNameWatchLists("dpl,el")}
+	if w.nameMinMatch() != 0.8 {
+		t.Errorf("unexpected minMatch: %.2f", w.nameMinMatch())
+	}
+	if w.searchesList(sdnList) || !w.searchesList(elList) {
+		t.Errorf("unexpected lists: %v", w.lists)
+	}
+}
+
+func TestWatch_signingSecret(t *testing.T) {
+	req := watchRequest{}
+	if err := req.setSigningSecret(); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.SigningSecret) != 64 {
+		t.Errorf("unexpected secret: %q", req.SigningSecret)
+	}
+
+	req = watchRequest{SigningSecret: "short"}
+	if err := req.setSigningSecret(); err == nil {
+		t.Error("expected error")
+	}
+	req = watchRequest{SigningSecret: "a-long-enough-secret"}
+	if err := req.setSigningSecret(); err != nil || req.SigningSecret != "a-long-enough-secret" {
+		t.Errorf("secret=%q err=%v", req.SigningSecret, err)
+	}
+
+	// the cursor returns each watch's secret
+	repo := createTestWatchRepository(t)
+	defer repo.close()
+
+	watchID, err := repo.addCompanyWatch(base.ID(), watchRequest{Webhook: "https://moov.io/1", SigningSecret: "company-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nameWatchID, err := repo.addCustomerNameWatch("john doe", watchRequest{Webhook: "https://moov.io/2", SigningSecret: "name-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	watches, err := repo.getWatchesCursor(log.NewNopLogger(), 100).Next()
+	if err != nil || len(watches) != 2 {
+		t.Fatalf("watches=%#v err=%v", watches, err)
+	}
+	for i := range watches {
+		switch watches[i].id {
+		case watchID:
+			if watches[i].signingSecret != "company-secret" {
+				t.Errorf("unexpected secret: %#v", watches[i])
+			}
+		case nameWatchID:
+			if watches[i].signingSecret != "name-secret" {
+				t.Errorf("unexpected secret: %#v", watches[i])
+			}
+		default:
+			t.Errorf("unknown watch: %#v", watches[i])
+		}
+	}
+}
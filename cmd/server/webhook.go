@@ -6,10 +6,15 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"go4.org/syncutil"
@@ -36,30 +41,47 @@ var (
 	}
 )
 
-// callWebhook will take `body` as JSON and make a POST request to the provided webhook url.
+// Headers sent with each webhook delivery
+const (
+	webhookSignatureHeader  = "X-Signature"
+	webhookTimestampHeader  = "X-Timestamp"
+	webhookDeliveryIDHeader = "X-Delivery-ID"
+)
+
+// callWebhook will take the delivery's body as JSON and make a POST request to its webhook url.
 // Returned is the HTTP status code.
-func callWebhook(watchID string, body *bytes.Buffer, webhook string, authToken string) (int, error) {
-	webhook, err := validateWebhook(webhook)
+//
+// Every request carries the delivery ID and the unix timestamp of the attempt. When the watch has a
+// signing secret the request is also signed, see signWebhook. The request is cancelled when ctx is done.
+func callWebhook(ctx context.Context, d *webhookDelivery) (int, error) {
+	webhook, err := validateWebhook(d.Webhook)
 	if err != nil {
 		return 0, err
 	}
 
 	// Setup HTTP request
-	req, err := http.NewRequest("POST", webhook, body)
+	req, err := http.NewRequest("POST", webhook, bytes.NewReader(d.Body))
 	if err != nil {
-		return 0, fmt.Errorf("unknown error with watch %s: %v", watchID, err)
+		return 0, fmt.Errorf("unknown error with watch %s: %v", d.WatchID, err)
 	}
-	if authToken != "" {
-		req.Header.Set("Authorization", authToken)
+	if d.authToken != "" {
+		req.Header.Set("Authorization", d.authToken)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookDeliveryIDHeader, d.ID)
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	if d.signingSecret != "" {
+		req.Header.Set(webhookSignatureHeader, signWebhook(d.signingSecret, timestamp, d.Body))
 	}
 
 	// Guard HTTP calls in-flight
 	webhookGate.Start()
 	defer webhookGate.Done()
 
-	resp, err := webhookHTTPClient.Do(req)
+	resp, err := webhookHTTPClient.Do(req.WithContext(ctx))
 	if err != nil {
-		return resp.StatusCode, fmt.Errorf("HTTP problem with watch %s: %v", watchID, err)
+		return 0, fmt.Errorf("HTTP problem with watch %s: %v", d.WatchID, err)
 	}
 	resp.Body.Close()
 	if resp.StatusCode > 299 || resp.StatusCode < 200 {
@@ -68,6 +90,16 @@ func callWebhook(watchID string, body *bytes.Buffer, webhook string, authToken s
 	return resp.StatusCode, nil
 }
 
+// signWebhook returns the X-Signature value for a delivery, which is the hex encoded HMAC-SHA256
+// of the timestamp, a period and the body keyed with the watch's signing secret.
+func signWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
 // validateWebhook performs some basic checks against the incoming webhook and
 // returns a normalized value.
 //
@@ -86,6 +118,36 @@ func validateWebhook(raw string) (string, error) {
 
 type webhookRepository interface {
 	recordWebhook(watchID string, attemptedAt time.Time, status int) error
+
+	// getLastNotification returns the most recently delivered notification for a watch,
+	// or nil if no notification has been delivered.
+	getLastNotification(watchID string) (*watchNotification, error)
+	recordNotification(watchID string, notification *watchNotification) error
+
+	// getNameWatchHits returns the keys of every hit delivered for a name watch which is still above
+	// the watch's minimum match.
+	getNameWatchHits(watchID string) ([]string, error)
+	replaceNameWatchHits(watchID string, keys []string) error
+
+	// enqueueDelivery stores a webhook delivery in the outbox, updateDelivery saves the outcome of each attempt.
+	enqueueDelivery(d *webhookDelivery) error
+	updateDelivery(d *webhookDelivery) error
+	getDelivery(deliveryID string) (*webhookDelivery, error)
+	getDeliveries(status deliveryStatus, limit int) ([]*webhookDelivery, error)
+
+	// getDueDeliveries returns pending deliveries whose next attempt is at or before now.
+	getDueDeliveries(now time.Time, limit int) ([]*webhookDelivery, error)
+	// replayDeadDeliveries moves every dead-lettered delivery back to pending and returns how many were moved.
+	replayDeadDeliveries(now time.Time) (int64, error)
+}
+
+// watchNotification describes the last payload queued for delivery to a watch's webhook
+type watchNotification struct {
+	entityID   string
+	hash       string // SHA-256 of payload
+	payload    []byte // Customer or Company JSON, kept as the last-known record for delisting
+	changeType WatchChangeType
+	notifiedAt time.Time
 }
 
 type sqliteWebhookRepository struct {
@@ -107,3 +169,169 @@ func (r *sqliteWebhookRepository) recordWebhook(watchID string, attemptedAt time
 	_, err = stmt.Exec(watchID, attemptedAt, status)
 	return err
 }
+
+func (r *sqliteWebhookRepository) getLastNotification(watchID string) (*watchNotification, error) {
+	query := `select entity_id, payload_hash, payload, change_type, notified_at from watch_notifications where watch_id = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var n watchNotification
+	err = stmt.QueryRow(watchID).Scan(&n.entityID, &n.hash, &n.payload, &n.changeType, &n.notifiedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // not found
+		}
+		return nil, fmt.Errorf("getLastNotification: %v", err)
+	}
+	return &n, nil
+}
+
+func (r *sqliteWebhookRepository) recordNotification(watchID string, n *watchNotification) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	columns := []string{"watch_id", "entity_id", "payload_hash", "payload", "change_type", "notified_at"}
+	if err := replaceRow(tx, "watch_notifications", "watch_id", columns, watchID, n.entityID, n.hash, n.payload, n.changeType, n.notifiedAt); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqliteWebhookRepository) getNameWatchHits(watchID string) ([]string, error) {
+	query := `select hit_key from name_watch_hits where watch_id = ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(watchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys, rows.Err()
+}
+
+func (r *sqliteWebhookRepository) replaceNameWatchHits(watchID string, keys []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`delete from name_watch_hits where watch_id = ?;`, watchID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	now := time.Now()
+	for i := range keys {
+		if _, err := tx.Exec(`insert into name_watch_hits (watch_id, hit_key, created_at) values (?, ?, ?);`, watchID, keys[i], now); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *sqliteWebhookRepository) enqueueDelivery(d *webhookDelivery) error {
+	query := `insert into webhook_deliveries (delivery_id, watch_id, webhook, auth_token, body, status, attempts, last_status, last_error, next_attempt_at, created_at, updated_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(d.ID, d.WatchID, d.Webhook, d.authToken, []byte(d.Body), d.Status, d.Attempts, d.LastStatus, d.LastError, d.NextAttemptAt, d.CreatedAt, d.UpdatedAt)
+	return err
+}
+
+func (r *sqliteWebhookRepository) updateDelivery(d *webhookDelivery) error {
+	query := `update webhook_deliveries set status = ?, attempts = ?, last_status = ?, last_error = ?, next_attempt_at = ?, updated_at = ? where delivery_id = ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(d.Status, d.Attempts, d.LastStatus, d.LastError, d.NextAttemptAt, d.UpdatedAt, d.ID)
+	return err
+}
+
+// webhookDeliveryColumns are selected from webhook_deliveries (as d) joined with watch_signing_secrets (as s)
+// and watch_notifiers (as n)
+const webhookDeliveryColumns = `d.delivery_id, d.watch_id, d.webhook, d.auth_token, coalesce(s.secret, ''), coalesce(n.notifier, ''), d.body, d.status, d.attempts, d.last_status, d.last_error, d.next_attempt_at, d.created_at, d.updated_at
+from webhook_deliveries as d left join watch_signing_secrets as s on d.watch_id = s.watch_id
+left join watch_notifiers as n on d.watch_id = n.watch_id`
+
+func (r *sqliteWebhookRepository) getDelivery(deliveryID string) (*webhookDelivery, error) {
+	query := `select ` + webhookDeliveryColumns + ` where d.delivery_id = ? limit 1;`
+	deliveries, err := r.queryDeliveries(query, deliveryID)
+	if err != nil || len(deliveries) == 0 {
+		return nil, err
+	}
+	return deliveries[0], nil
+}
+
+func (r *sqliteWebhookRepository) getDeliveries(status deliveryStatus, limit int) ([]*webhookDelivery, error) {
+	query := `select ` + webhookDeliveryColumns + ` where d.status = ? order by d.updated_at desc limit ?;`
+	return r.queryDeliveries(query, status, limit)
+}
+
+func (r *sqliteWebhookRepository) getDueDeliveries(now time.Time, limit int) ([]*webhookDelivery, error) {
+	query := `select ` + webhookDeliveryColumns + ` where d.status = ? and d.next_attempt_at <= ? order by d.next_attempt_at asc limit ?;`
+	return r.queryDeliveries(query, deliveryPending, now, limit)
+}
+
+func (r *sqliteWebhookRepository) queryDeliveries(query string, args ...interface{}) ([]*webhookDelivery, error) {
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*webhookDelivery
+	for rows.Next() {
+		var d webhookDelivery
+		var body []byte
+		err := rows.Scan(&d.ID, &d.WatchID, &d.Webhook, &d.authToken, &d.signingSecret, &d.Notifier, &body, &d.Status, &d.Attempts, &d.LastStatus, &d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("queryDeliveries: %v", err)
+		}
+		d.Body = body
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *sqliteWebhookRepository) replayDeadDeliveries(now time.Time) (int64, error) {
+	query := `update webhook_deliveries set status = ?, attempts = 0, next_attempt_at = ?, updated_at = ? where status = ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(deliveryPending, now, now, deliveryDead)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
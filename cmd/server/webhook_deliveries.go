@@ -0,0 +1,303 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/moov-io/base"
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+const (
+	webhookDeliveriesPath      = "/webhooks/deliveries"
+	webhookDeliveryReplayPath  = "/webhooks/deliveries/{deliveryID}/replay"
+	webhookDeliveriesReplayAll = "/webhooks/deliveries/replay"
+)
+
+var (
+	// webhookMaxAttempts is how many times a delivery is attempted before it's moved to the dead-letter state
+	webhookMaxAttempts = 10
+
+	// webhookBaseBackoff is the delay after a delivery's first failed attempt. It doubles after each
+	// following failure up to webhookMaxBackoff.
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = 6 * time.Hour
+
+	// webhookRetryInterval is how often the outbox is checked for deliveries due to be retried
+	webhookRetryInterval = 1 * time.Minute
+
+	errNoDeliveryID = errors.New("no deliveryID found")
+)
+
+func init() {
+	webhookMaxAttempts = readWebhookMaxAttempts(os.Getenv("WEBHOOK_MAX_ATTEMPTS"))
+	webhookBaseBackoff = readWebhookDuration(os.Getenv("WEBHOOK_BASE_BACKOFF"), webhookBaseBackoff)
+	webhookMaxBackoff = readWebhookDuration(os.Getenv("WEBHOOK_MAX_BACKOFF"), webhookMaxBackoff)
+	webhookRetryInterval = readWebhookDuration(os.Getenv("WEBHOOK_RETRY_INTERVAL"), webhookRetryInterval)
+}
+
+func readWebhookMaxAttempts(str string) int {
+	if n, _ := strconv.Atoi(str); n > 0 {
+		return n
+	}
+	return webhookMaxAttempts
+}
+
+func readWebhookDuration(str string, def time.Duration) time.Duration {
+	if dur, _ := time.ParseDuration(str); dur > 0 {
+		return dur
+	}
+	return def
+}
+
+// deliveryStatus is the state of a webhook delivery in the outbox
+type deliveryStatus string
+
+const (
+	// deliveryPending deliveries are waiting for their first attempt or a retry
+	deliveryPending deliveryStatus = "pending"
+	// deliveryDelivered deliveries received a 2xx response from their webhook
+	deliveryDelivered deliveryStatus = "delivered"
+	// deliveryDead deliveries failed webhookMaxAttempts times and are only retried when replayed
+	deliveryDead deliveryStatus = "dead"
+)
+
+// webhookDelivery is a watch notification stored in the outbox until its webhook accepts it.
+type webhookDelivery struct {
+	ID            string          `json:"deliveryID"`
+	WatchID       string          `json:"watchID"`
+	Webhook       string          `json:"webhook"`
+	Notifier      string          `json:"notifier,omitempty"`
+	Body          json.RawMessage `json:"body"`
+	Status        deliveryStatus  `json:"status"`
+	Attempts      int             `json:"attempts"`
+	LastStatus    int             `json:"lastStatus"`
+	LastError     string          `json:"lastError,omitempty"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt"`
+
+	authToken     string
+	signingSecret string
+}
+
+// newWebhookDelivery returns a pending delivery of body to the watch's webhook. The first attempt is made
+// by the caller, so the outbox only picks the delivery up after webhookBaseBackoff if that attempt never finished.
+func newWebhookDelivery(w watch, body *bytes.Buffer) *webhookDelivery {
+	now := time.Now()
+	return &webhookDelivery{
+		ID:            base.ID(),
+		WatchID:       w.id,
+		Webhook:       w.webhook,
+		Notifier:      w.notifier,
+		Body:          json.RawMessage(body.Bytes()),
+		Status:        deliveryPending,
+		NextAttemptAt: now.Add(webhookBaseBackoff),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		authToken:     w.authToken,
+		signingSecret: w.signingSecret,
+	}
+}
+
+// webhookBackoff returns how long to wait before retrying a delivery which has failed attempts times.
+// The delay grows exponentially and has jitter applied so retries from an outage don't all land at once.
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		backoff = webhookMaxBackoff
+	}
+	// Wait somewhere between half and all of the backoff
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// attemptWebhookDelivery sends the delivery with its watch's notifier once and saves the outcome. Failed attempts are
+// rescheduled with webhookBackoff until webhookMaxAttempts is reached and the delivery is dead-lettered.
+//
+// Attempts cut short because ctx is done (e.g. we're shutting down) aren't counted and are retried right away
+// by the outbox.
+func attemptWebhookDelivery(ctx context.Context, logger log.Logger, repo webhookRepository, d *webhookDelivery) error {
+	now := time.Now()
+	status, err := notify(ctx, d)
+	if err != nil && ctx.Err() != nil {
+		d.Status, d.LastError = deliveryPending, err.Error()
+		d.NextAttemptAt, d.UpdatedAt = now, now
+		if uerr := repo.updateDelivery(d); uerr != nil {
+			return fmt.Errorf("problem saving delivery %s: %v", d.ID, uerr)
+		}
+		return err
+	}
+	if err := repo.recordWebhook(d.WatchID, now, status); err != nil && logger != nil {
+		logger.Log("webhook", fmt.Sprintf("problem writing watch (%s) webhook status: %v", d.WatchID, err))
+	}
+
+	d.Attempts++
+	d.LastStatus = status
+	d.UpdatedAt = now
+	if err == nil {
+		d.Status, d.LastError = deliveryDelivered, ""
+	} else {
+		d.LastError = err.Error()
+		if d.Attempts >= webhookMaxAttempts {
+			d.Status = deliveryDead
+			if logger != nil {
+				logger.Log("webhook", fmt.Sprintf("delivery %s for watch %s dead-lettered after %d attempts: %v", d.ID, d.WatchID, d.Attempts, err))
+			}
+		} else {
+			d.Status = deliveryPending
+			d.NextAttemptAt = now.Add(webhookBackoff(d.Attempts))
+		}
+	}
+	if uerr := repo.updateDelivery(d); uerr != nil {
+		return fmt.Errorf("problem saving delivery %s: %v", d.ID, uerr)
+	}
+	return err
+}
+
+// spawnWebhookRetries will block and retry pending deliveries from the outbox every interval until stop is closed.
+// Retries in progress are cut short when ctx is done.
+func spawnWebhookRetries(ctx context.Context, stop <-chan struct{}, logger log.Logger, interval time.Duration, repo webhookRepository) {
+	for {
+		select {
+		case <-time.After(interval):
+			retryWebhookDeliveries(ctx, logger, repo)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// retryWebhookDeliveries attempts every pending delivery which is due, stopping early once ctx is done.
+func retryWebhookDeliveries(ctx context.Context, logger log.Logger, repo webhookRepository) {
+	attempted := make(map[string]bool)
+	for {
+		deliveries, err := repo.getDueDeliveries(time.Now(), watchResearchBatchSize)
+		if err != nil {
+			if logger != nil {
+				logger.Log("webhook", fmt.Sprintf("problem reading due deliveries: %v", err))
+			}
+			return
+		}
+		if len(deliveries) == 0 || attempted[deliveries[0].ID] {
+			return // nothing due, or deliveries we couldn't reschedule
+		}
+		for i := range deliveries {
+			if ctx.Err() != nil {
+				return
+			}
+			attempted[deliveries[i].ID] = true
+			if err := attemptWebhookDelivery(ctx, logger, repo, deliveries[i]); err != nil && logger != nil {
+				logger.Log("webhook", fmt.Sprintf("retry of delivery %s for watch %s failed: %v", deliveries[i].ID, deliveries[i].WatchID, err))
+			}
+		}
+	}
+}
+
+// addWebhookDeliveryRoutes registers admin endpoints to inspect and replay outbox deliveries.
+func addWebhookDeliveryRoutes(logger log.Logger, adminServer *adminHTTPServer, repo webhookRepository) {
+	adminServer.AddHandler(webhookDeliveriesPath, listWebhookDeliveries(logger, repo))
+	adminServer.AddHandler(webhookDeliveriesReplayAll, replayDeadWebhookDeliveries(logger, repo))
+	adminServer.AddHandler(webhookDeliveryReplayPath, replayWebhookDelivery(logger, repo))
+}
+
+// listWebhookDeliveries returns the most recently updated deliveries, optionally filtered by ?status=
+// and limited by ?limit=. Dead-lettered deliveries are returned by default.
+func listWebhookDeliveries(logger log.Logger, repo webhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		status := deliveryStatus(r.URL.Query().Get("status"))
+		switch status {
+		case "":
+			status = deliveryDead
+		case deliveryPending, deliveryDelivered, deliveryDead:
+		default:
+			moovhttp.Problem(w, fmt.Errorf("unknown delivery status: %s", status))
+			return
+		}
+		deliveries, err := repo.getDeliveries(status, extractSearchLimit(r))
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(deliveries)
+	}
+}
+
+// replayWebhookDelivery resets a delivery's attempts and immediately tries it again.
+func replayWebhookDelivery(logger log.Logger, repo webhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		deliveryID := mux.Vars(r)["deliveryID"]
+		if deliveryID == "" {
+			moovhttp.Problem(w, errNoDeliveryID)
+			return
+		}
+		d, err := repo.getDelivery(deliveryID)
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if d == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if logger != nil {
+			logger.Log("webhook", fmt.Sprintf("admin: replaying delivery %s for watch %s", d.ID, d.WatchID))
+		}
+		d.Attempts = 0
+		if err := attemptWebhookDelivery(r.Context(), logger, repo, d); err != nil && logger != nil {
+			logger.Log("webhook", fmt.Sprintf("admin: replay of delivery %s failed: %v", d.ID, err))
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(d)
+	}
+}
+
+// replayDeadWebhookDeliveries moves every dead-lettered delivery back to pending so the outbox retries them.
+func replayDeadWebhookDeliveries(logger log.Logger, repo webhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		n, err := repo.replayDeadDeliveries(time.Now())
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if logger != nil {
+			logger.Log("webhook", fmt.Sprintf("admin: replaying %d dead-lettered deliveries", n))
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]int64{"replayed": n})
+	}
+}
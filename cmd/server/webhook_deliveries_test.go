@@ -0,0 +1,401 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+func TestWebhookDeliveries_readConfig(t *testing.T) {
+	if n := readWebhookMaxAttempts(""); n != webhookMaxAttempts {
+		t.Errorf("got %d", n)
+	}
+	if n := readWebhookMaxAttempts("3"); n != 3 {
+		t.Errorf("got %d", n)
+	}
+	if n := readWebhookMaxAttempts("-1"); n != webhookMaxAttempts {
+		t.Errorf("got %d", n)
+	}
+	if d := readWebhookDuration("2m", time.Second); d != 2*time.Minute {
+		t.Errorf("got %v", d)
+	}
+	if d := readWebhookDuration("bogus", time.Second); d != time.Second {
+		t.Errorf("got %v", d)
+	}
+}
+
+func TestWebhookDeliveries_backoff(t *testing.T) {
+	for attempts := 1; attempts <= 30; attempts++ {
+		expected := webhookBaseBackoff << uint(attempts-1)
+		if expected > webhookMaxBackoff || expected <= 0 {
+			expected = webhookMaxBackoff
+		}
+		d := webhookBackoff(attempts)
+		if d < expected/2 || d > expected {
+			t.Errorf("attempts=%d backoff=%v expected between %v and %v", attempts, d, expected/2, expected)
+		}
+	}
+}
+
+func TestWebhookDeliveries_repository(t *testing.T) {
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	repo := &sqliteWebhookRepository{db.db}
+
+	w := watch{id: base.ID(), webhook: "https://localhost/ofac", authToken: "secret"}
+	d := newWebhookDelivery(w, bytes.NewBufferString(`{"id":"306"}`))
+	if err := repo.enqueueDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+
+	// not due until the caller's first attempt had a chance to finish
+	due, err := repo.getDueDeliveries(time.Now(), 10)
+	if err != nil || len(due) != 0 {
+		t.Fatalf("unexpected due deliveries: %#v err=%v", due, err)
+	}
+	due, err = repo.getDueDeliveries(time.Now().Add(webhookBaseBackoff+time.Second), 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("expected one due delivery: %#v err=%v", due, err)
+	}
+	if due[0].ID != d.ID || due[0].authToken != "secret" || string(due[0].Body) != `{"id":"306"}` {
+		t.Errorf("unexpected delivery: %#v", due[0])
+	}
+
+	// dead-letter and replay
+	d.Status = deliveryDead
+	if err := repo.updateDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+	dead, err := repo.getDeliveries(deliveryDead, 10)
+	if err != nil || len(dead) != 1 {
+		t.Fatalf("expected one dead delivery: %#v err=%v", dead, err)
+	}
+	n, err := repo.replayDeadDeliveries(time.Now())
+	if err != nil || n != 1 {
+		t.Fatalf("replayed %d: %v", n, err)
+	}
+	d, err = repo.getDelivery(d.ID)
+	if err != nil || d == nil {
+		t.Fatalf("missing delivery: %v", err)
+	}
+	if d.Status != deliveryPending || d.Attempts != 0 {
+		t.Errorf("unexpected delivery: %#v", d)
+	}
+
+	if d, err := repo.getDelivery(base.ID()); d != nil || err != nil {
+		t.Errorf("expected no delivery: %#v err=%v", d, err)
+	}
+}
+
+func TestWebhookDeliveries_deadLetter(t *testing.T) {
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	repo := &sqliteWebhookRepository{db.db}
+
+	maxAttempts := webhookMaxAttempts
+	webhookMaxAttempts = 2
+	defer func() { webhookMaxAttempts = maxAttempts }()
+
+	// An HTTP webhook is always rejected, so every attempt fails
+	w := watch{id: base.ID(), webhook: "http://localhost/ofac"}
+	d := newWebhookDelivery(w, bytes.NewBufferString(`{}`))
+	if err := repo.enqueueDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := attemptWebhookDelivery(context.Background(), log.NewNopLogger(), repo, d); err == nil {
+		t.Fatal("expected error")
+	}
+	if d.Status != deliveryPending || d.Attempts != 1 || d.LastError == "" || !d.NextAttemptAt.After(time.Now()) {
+		t.Errorf("unexpected delivery after first attempt: %#v", d)
+	}
+	if err := attemptWebhookDelivery(context.Background(), log.NewNopLogger(), repo, d); err == nil {
+		t.Fatal("expected error")
+	}
+	if d.Status != deliveryDead || d.Attempts != 2 {
+		t.Errorf("expected dead delivery: %#v", d)
+	}
+
+	var count int
+	if err := db.db.QueryRow(`select count(*) from webhook_stats where watch_id = ?`, w.id).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 webhook attempts, got %d", count)
+	}
+
+	// dead deliveries aren't retried
+	if due, _ := repo.getDueDeliveries(time.Now().Add(webhookMaxBackoff*2), 10); len(due) != 0 {
+		t.Errorf("unexpected due deliveries: %#v", due)
+	}
+}
+
+func TestWebhookDeliveries_cancelled(t *testing.T) {
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	repo := &sqliteWebhookRepository{db.db}
+
+	w := watch{id: base.ID(), webhook: "https://localhost/ofac"}
+	d := newWebhookDelivery(w, bytes.NewBufferString(`{}`))
+	if err := repo.enqueueDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+
+	// attempts cut short by shutting down aren't counted and are due right away
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := attemptWebhookDelivery(ctx, log.NewNopLogger(), repo, d); err == nil {
+		t.Fatal("expected error")
+	}
+	d, _ = repo.getDelivery(d.ID)
+	if d == nil || d.Status != deliveryPending || d.Attempts != 0 || d.NextAttemptAt.After(time.Now()) {
+		t.Errorf("unexpected delivery: %#v", d)
+	}
+	if due, _ := repo.getDueDeliveries(time.Now(), 10); len(due) != 1 {
+		t.Errorf("unexpected due deliveries: %#v", due)
+	}
+}
+
+func TestWebhookDeliveries_removedWatch(t *testing.T) {
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	repo := &sqliteWebhookRepository{db.db}
+	watchRepo := &sqliteWatchRepository{db.db, log.NewNopLogger()}
+
+	companyID := base.ID()
+	watchID, err := watchRepo.addCompanyWatch(companyID, watchRequest{Webhook: "https://localhost/ofac", AuthToken: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newWebhookDelivery(watch{id: watchID, webhook: "https://localhost/ofac"}, bytes.NewBufferString(`{}`))
+	if err := repo.enqueueDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+
+	// removing the watch for another company keeps its deliveries
+	if err := watchRepo.removeCompanyWatch(base.ID(), watchID); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := repo.getDelivery(d.ID); found == nil {
+		t.Fatal("expected delivery")
+	}
+
+	// undelivered deliveries aren't retried once their watch is removed
+	if err := watchRepo.removeCompanyWatch(companyID, watchID); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := repo.getDelivery(d.ID); found != nil {
+		t.Errorf("unexpected delivery: %#v", found)
+	}
+	if due, _ := repo.getDueDeliveries(time.Now().Add(webhookMaxBackoff), 10); len(due) != 0 {
+		t.Errorf("unexpected due deliveries: %#v", due)
+	}
+}
+
+func TestWebhookDeliveries_retry(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+
+	calls := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	trustTestServer(t, server)
+
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	repo := &sqliteWebhookRepository{db.db}
+
+	baseBackoff := webhookBaseBackoff
+	webhookBaseBackoff = time.Millisecond
+	defer func() { webhookBaseBackoff = baseBackoff }()
+
+	d := newWebhookDelivery(watch{id: base.ID(), webhook: server.URL}, bytes.NewBufferString(`{}`))
+	if err := repo.enqueueDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+	if err := attemptWebhookDelivery(context.Background(), log.NewNopLogger(), repo, d); err == nil {
+		t.Fatal("expected error")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	retryWebhookDeliveries(context.Background(), log.NewNopLogger(), repo)
+
+	d, _ = repo.getDelivery(d.ID)
+	if calls != 2 || d == nil || d.Status != deliveryDelivered || d.Attempts != 2 || d.LastStatus != http.StatusOK {
+		t.Errorf("calls=%d unexpected delivery: %#v", calls, d)
+	}
+}
+
+func TestWebhookDeliveries_updatedWatch(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+
+	old := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer old.Close()
+	trustTestServer(t, old)
+
+	var authToken string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authToken = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	trustTestServer(t, server)
+
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	repo := &sqliteWebhookRepository{db.db}
+	watchRepo := &sqliteWatchRepository{db.db, log.NewNopLogger()}
+
+	baseBackoff := webhookBaseBackoff
+	webhookBaseBackoff = time.Millisecond
+	defer func() { webhookBaseBackoff = baseBackoff }()
+
+	watchID, err := watchRepo.addCompanyWatch(base.ID(), watchRequest{Webhook: old.URL, AuthToken: "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newWebhookDelivery(watch{id: watchID, webhook: old.URL, authToken: "old"}, bytes.NewBufferString(`{}`))
+	if err := repo.enqueueDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+	if err := attemptWebhookDelivery(context.Background(), log.NewNopLogger(), repo, d); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// the pending delivery is retried against the watch's new webhook and auth token
+	webhook, token := server.URL, "new"
+	if err := watchRepo.updateWatch(watchID, watchUpdate{Webhook: &webhook, AuthToken: &token}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	retryWebhookDeliveries(context.Background(), log.NewNopLogger(), repo)
+
+	d, _ = repo.getDelivery(d.ID)
+	if d == nil || d.Status != deliveryDelivered || d.Webhook != server.URL || authToken != "new" {
+		t.Errorf("authToken=%q unexpected delivery: %#v", authToken, d)
+	}
+}
+
+func TestWebhookDeliveries_adminRoutes(t *testing.T) {
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	repo := &sqliteWebhookRepository{db.db}
+
+	d := newWebhookDelivery(watch{id: base.ID(), webhook: "http://localhost/ofac"}, bytes.NewBufferString(`{"id":"306"}`))
+	d.Status = deliveryDead
+	if err := repo.enqueueDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc(webhookDeliveriesPath, listWebhookDeliveries(nil, repo))
+	router.HandleFunc(webhookDeliveriesReplayAll, replayDeadWebhookDeliveries(nil, repo))
+	router.HandleFunc(webhookDeliveryReplayPath, replayWebhookDelivery(nil, repo))
+
+	// list dead-lettered deliveries
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", webhookDeliveriesPath, nil))
+	w.Flush()
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	var deliveries []webhookDelivery
+	if err := json.NewDecoder(w.Body).Decode(&deliveries); err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].ID != d.ID || string(deliveries[0].Body) != `{"id":"306"}` {
+		t.Errorf("unexpected deliveries: %#v", deliveries)
+	}
+
+	// unknown status
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", webhookDeliveriesPath+"?status=other", nil))
+	w.Flush()
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+
+	// replay a single delivery, which fails again
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", fmt.Sprintf("/webhooks/deliveries/%s/replay", d.ID), nil))
+	w.Flush()
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	var replayed webhookDelivery
+	if err := json.NewDecoder(w.Body).Decode(&replayed); err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Status != deliveryPending || replayed.Attempts != 1 || replayed.LastError == "" {
+		t.Errorf("unexpected delivery: %#v", replayed)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", fmt.Sprintf("/webhooks/deliveries/%s/replay", base.ID()), nil))
+	w.Flush()
+	if w.Code != http.StatusNotFound {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+
+	// replay all dead deliveries
+	d.Status = deliveryDead
+	if err := repo.updateDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", webhookDeliveriesReplayAll, nil))
+	w.Flush()
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	if dead, _ := repo.getDeliveries(deliveryDead, 10); len(dead) != 0 {
+		t.Errorf("unexpected dead deliveries: %#v", dead)
+	}
+}
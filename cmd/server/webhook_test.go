@@ -6,6 +6,8 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -41,6 +43,24 @@ var (
 	}
 )
 
+// trustTestServer overrides webhookHTTPClient to add the test TLS certificate of server
+func trustTestServer(t *testing.T, server *httptest.Server) {
+	t.Helper()
+
+	tr, ok := webhookHTTPClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("%T %#v", webhookHTTPClient.Transport, webhookHTTPClient.Transport)
+	}
+	ctr, ok := server.Client().Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("unknown server.Client().Transport type: %T", server.Client().Transport)
+	}
+	if tr.TLSClientConfig == nil {
+		tr.TLSClientConfig = &tls.Config{}
+	}
+	tr.TLSClientConfig.RootCAs = ctr.TLSClientConfig.RootCAs
+}
+
 // TestWebhook_retry ensures the webhookHTTPClient never follows a redirect.
 // This is done to prevent infinite (or costly) redirect cycles which can degrade performance.
 func TestWebhook_retry(t *testing.T) {
@@ -110,29 +130,68 @@ func TestWebhook_call(t *testing.T) {
 
 	server := httptest.NewTLSServer(http.HandlerFunc(customerWebhook))
 	defer server.Close()
-
-	// override to add test TLS certificate
-	if tr, ok := webhookHTTPClient.Transport.(*http.Transport); ok {
-		if ctr, ok := server.Client().Transport.(*http.Transport); ok {
-			tr.TLSClientConfig.RootCAs = ctr.TLSClientConfig.RootCAs
-		} else {
-			t.Errorf("unknown server.Client().Transport type: %T", server.Client().Transport)
-		}
-	} else {
-		t.Fatalf("%T %#v", webhookHTTPClient.Transport, webhookHTTPClient.Transport)
-	}
+	trustTestServer(t, server)
 
 	custRepo := createTestCustomerRepository(t)
 	defer custRepo.close()
 
 	// execute webhook with arbitrary Customer
-	body, err := getCustomerBody(customerSearcher, "watchID", "306", 1.0, custRepo)
-	if body == nil {
-		t.Fatalf("nil body: %v", err)
+	customer, err := getWatchedCustomer(customerSearcher, "306", 1.0, custRepo)
+	if customer == nil {
+		t.Fatalf("nil customer: %v", err)
+	}
+	body, err := encodeWatchBody("watchID", customer, WatchNewMatch, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newWebhookDelivery(watch{id: base.ID(), webhook: server.URL, authToken: "authToken"}, body)
+	if _, err := callWebhook(context.Background(), d); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhook_sign(t *testing.T) {
+	sig := signWebhook("secret", "1563466800", []byte(`{"id":"306"}`))
+	if sig != "sha256=89f6700e9825af52a4ec2de97840aec6725483425f03088aff06e8f78b1142d8" {
+		t.Errorf("unexpected signature: %s", sig)
+	}
+	if other := signWebhook("secret", "1563466801", []byte(`{"id":"306"}`)); other == sig {
+		t.Error("timestamp isn't signed")
+	}
+}
+
+func TestWebhook_signedCall(t *testing.T) {
+	if testing.Short() {
+		return
 	}
-	if _, err := callWebhook(base.ID(), body, server.URL, "authToken"); err != nil {
+
+	var headers http.Header
+	var body []byte
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	trustTestServer(t, server)
+
+	d := newWebhookDelivery(watch{id: base.ID(), webhook: server.URL, signingSecret: "secret"}, bytes.NewBufferString(`{"id":"306"}`))
+	if _, err := callWebhook(context.Background(), d); err != nil {
 		t.Fatal(err)
 	}
+	if v := headers.Get(webhookDeliveryIDHeader); v != d.ID {
+		t.Errorf("%s=%q", webhookDeliveryIDHeader, v)
+	}
+	timestamp := headers.Get(webhookTimestampHeader)
+	if timestamp == "" {
+		t.Fatalf("missing %s", webhookTimestampHeader)
+	}
+	if v := headers.Get(webhookSignatureHeader); v != signWebhook("secret", timestamp, body) {
+		t.Errorf("%s=%q", webhookSignatureHeader, v)
+	}
+	if v := headers.Get("Authorization"); v != "" {
+		t.Errorf("unexpected Authorization: %q", v)
+	}
 }
 
 func TestWebhook_record(t *testing.T) {
@@ -149,3 +208,35 @@ func TestWebhook_record(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestWebhook_notifications(t *testing.T) {
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+
+	repo := sqliteWebhookRepository{db.db}
+	defer repo.close()
+
+	watchID := base.ID()
+	last, err := repo.getLastNotification(watchID)
+	if err != nil || last != nil {
+		t.Fatalf("expected no notification: %#v err=%v", last, err)
+	}
+
+	// record and read back
+	if err := repo.recordNotification(watchID, &watchNotification{entityID: "306", hash: "abc", changeType: WatchNewMatch, notifiedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.recordNotification(watchID, &watchNotification{entityID: "306", hash: "def", changeType: WatchUpdated, notifiedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	last, err = repo.getLastNotification(watchID)
+	if err != nil || last == nil {
+		t.Fatalf("expected notification: err=%v", err)
+	}
+	if last.entityID != "306" || last.hash != "def" || last.changeType != WatchUpdated {
+		t.Errorf("unexpected notification: %#v", last)
+	}
+}
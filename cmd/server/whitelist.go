@@ -0,0 +1,449 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base"
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+var (
+	errNoWhitelistID     = errors.New("no whitelistID found")
+	errNoWhitelistReason = errors.New("whitelist entries need a reason")
+	errWhitelistSubject  = errors.New("whitelist entries need either a subjectID or name")
+)
+
+// WhitelistEntry marks a record (by list and EntityID) as a false positive for one of our subjects,
+// or for every search of a name. Whitelisted records are left out of search results, subject
+// screening and name watch notifications.
+//
+// An entry is invalidated when its record's content changes (or the record is removed) in a
+// later download, so the record is reviewed again.
+type WhitelistEntry struct {
+	ID        string `json:"whitelistID"`
+	SubjectID string `json:"subjectID,omitempty"`
+	Name      string `json:"name,omitempty"` // normalized with precompute
+	List      string `json:"list"`
+	EntityID  string `json:"entityID"`
+
+	Reviewer string `json:"reviewer"`
+	Reason   string `json:"reason"`
+
+	// RecordHash is the hash of the record when it was whitelisted
+	RecordHash string `json:"-"`
+
+	CreatedAt     time.Time  `json:"createdAt"`
+	InvalidatedAt *time.Time `json:"invalidatedAt,omitempty"`
+}
+
+func (e *WhitelistEntry) key() string {
+	return e.List + ":" + e.EntityID
+}
+
+// whitelist holds the valid whitelist entries by list and EntityID. A nil whitelist is empty.
+type whitelist struct {
+	entries map[string][]*WhitelistEntry
+}
+
+func newWhitelist(entries []*WhitelistEntry) *whitelist {
+	wl := &whitelist{entries: make(map[string][]*WhitelistEntry)}
+	for _, e := range entries {
+		if e.InvalidatedAt == nil {
+			wl.entries[e.key()] = append(wl.entries[e.key()], e)
+		}
+	}
+	return wl
+}
+
+// find returns the entry whitelisting a record for subjectID or name, but only if the record's hash
+// hasn't changed since it was whitelisted.
+func (wl *whitelist) find(subjectID, name, list, entityID, hash string) *WhitelistEntry {
+	if wl == nil {
+		return nil
+	}
+	name = precompute(name)
+	for _, e := range wl.entries[list+":"+entityID] {
+		if e.RecordHash != hash {
+			continue
+		}
+		if (e.SubjectID != "" && e.SubjectID == subjectID) || (e.Name != "" && e.Name == name) {
+			return e
+		}
+	}
+	return nil
+}
+
+// loadWhitelist returns the valid whitelist entries, which are read once and kept in memory until
+// reloadWhitelist. Nothing is whitelisted if they can't be read, so every hit is still reported.
+func (s *searcher) loadWhitelist() *whitelist {
+	if s.whitelistRepo == nil {
+		return nil
+	}
+	s.whitelistMu.Lock()
+	defer s.whitelistMu.Unlock()
+
+	if s.wl == nil {
+		s.wl = s.readWhitelist()
+	}
+	return s.wl
+}
+
+// reloadWhitelist reads the valid whitelist entries again, which is called after they're changed.
+func (s *searcher) reloadWhitelist() {
+	if s.whitelistRepo == nil {
+		return
+	}
+	s.whitelistMu.Lock()
+	defer s.whitelistMu.Unlock()
+
+	s.wl = s.readWhitelist()
+}
+
+// readWhitelist returns the valid entries of whitelistRepo, or nil if they can't be read so the next
+// loadWhitelist tries again.
+func (s *searcher) readWhitelist() *whitelist {
+	entries, err := s.whitelistRepo.getWhitelist(false)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Log("whitelist", fmt.Sprintf("problem reading whitelist: %v", err))
+		}
+		return nil
+	}
+	return newWhitelist(entries)
+}
+
+// findRecord returns the record on list identified by entityID (see Case), or nil if it isn't found.
+func (s *searcher) findRecord(list, entityID string) interface{} {
+	if list == sdnList {
+		if sdn := s.FindSDN(entityID); sdn != nil {
+			return sdn
+		}
+		return nil
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	switch list {
+	case altNameList:
+		for i := range s.Alts {
+			if s.Alts[i].AlternateIdentity.AlternateID == entityID {
+				return s.Alts[i].AlternateIdentity
+			}
+		}
+	case dplList:
+		for i := range s.DPs {
+			if s.DPs[i].id == entityID {
+				return s.DPs[i].DeniedPerson
+			}
+		}
+	case ssiList:
+		for i := range s.SSIs {
+			if s.SSIs[i].SectoralSanction.EntityID == entityID {
+				return s.SSIs[i].SectoralSanction
+			}
+		}
+	case elList:
+		for i := range s.ELs {
+			if s.ELs[i].id == entityID {
+				return s.ELs[i].Entity
+			}
+		}
+	}
+	return nil
+}
+
+// invalidateWhitelist invalidates every entry whose record changed or was removed, which is called
+// after each data refresh. It returns how many entries were invalidated.
+func (s *searcher) invalidateWhitelist(now time.Time) (int, error) {
+	if s.whitelistRepo == nil {
+		return 0, nil
+	}
+	entries, err := s.whitelistRepo.getWhitelist(false)
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for _, e := range entries {
+		record := s.findRecord(e.List, e.EntityID)
+		if record == nil || recordHash(record) != e.RecordHash {
+			ids = append(ids, e.ID)
+		}
+	}
+	if err := s.whitelistRepo.invalidateWhitelistEntries(ids, now); err != nil {
+		return 0, err
+	}
+	if len(ids) > 0 {
+		s.reloadWhitelist()
+	}
+	return len(ids), nil
+}
+
+// suppressWhitelisted removes results whitelisted for name and annotates the response with
+// the entries which removed them.
+func (resp *searchResponse) suppressWhitelisted(wl *whitelist, name string) {
+	if wl == nil {
+		return
+	}
+	whitelisted := func(list, entityID string, record interface{}) bool {
+		if e := wl.find("", name, list, entityID, recordHash(record)); e != nil {
+			resp.Whitelisted = append(resp.Whitelisted, e)
+			return true
+		}
+		return false
+	}
+
+	var sdns []SDN
+	for i := range resp.SDNs {
+		if !whitelisted(sdnList, resp.SDNs[i].EntityID, resp.SDNs[i].SDN) {
+			sdns = append(sdns, resp.SDNs[i])
+		}
+	}
+	var alts []Alt
+	for i := range resp.AltNames {
+		if !whitelisted(altNameList, resp.AltNames[i].AlternateIdentity.AlternateID, resp.AltNames[i].AlternateIdentity) {
+			alts = append(alts, resp.AltNames[i])
+		}
+	}
+	var dps []DP
+	for i := range resp.DeniedPersons {
+		if !whitelisted(dplList, resp.DeniedPersons[i].id, resp.DeniedPersons[i].DeniedPerson) {
+			dps = append(dps, resp.DeniedPersons[i])
+		}
+	}
+	var ssis []SSI
+	for i := range resp.SectoralSanctions {
+		if !whitelisted(ssiList, resp.SectoralSanctions[i].SectoralSanction.EntityID, resp.SectoralSanctions[i].SectoralSanction) {
+			ssis = append(ssis, resp.SectoralSanctions[i])
+		}
+	}
+	var els []EL
+	for i := range resp.BISEntities {
+		if !whitelisted(elList, resp.BISEntities[i].id, resp.BISEntities[i].Entity) {
+			els = append(els, resp.BISEntities[i])
+		}
+	}
+	if len(resp.Whitelisted) > 0 {
+		resp.SDNs, resp.AltNames, resp.DeniedPersons, resp.SectoralSanctions, resp.BISEntities = sdns, alts, dps, ssis, els
+	}
+}
+
+// addWhitelistTable creates the whitelist_entries table for databaseType
+func addWhitelistTable(databaseType string) []string {
+	timestamp := map[string]string{sqliteDatabase: "datetime", postgresDatabase: "timestamptz", mysqlDatabase: "datetime(6)"}[databaseType]
+
+	return []string{
+		fmt.Sprintf(`create table if not exists whitelist_entries(whitelist_id varchar(64) primary key, subject_id varchar(64), name text, list varchar(16), entity_id varchar(64), record_hash varchar(64), reviewer text, reason text, created_at %s, invalidated_at %s);`, timestamp, timestamp),
+		`create index idx_whitelist_entries_entity_id on whitelist_entries (entity_id);`,
+	}
+}
+
+func dropWhitelistTable() []string {
+	return []string{`drop table if exists whitelist_entries;`}
+}
+
+// whitelistRepository holds our false positive whitelist.
+type whitelistRepository interface {
+	addWhitelistEntry(entry *WhitelistEntry) error
+	removeWhitelistEntry(whitelistID string) (bool, error)
+
+	// getWhitelist returns every valid entry, along with invalidated entries if includeInvalidated is true.
+	getWhitelist(includeInvalidated bool) ([]*WhitelistEntry, error)
+
+	invalidateWhitelistEntries(whitelistIDs []string, now time.Time) error
+
+	close() error
+}
+
+type sqliteWhitelistRepository struct {
+	db *sql.DB
+}
+
+func (r *sqliteWhitelistRepository) close() error {
+	return r.db.Close()
+}
+
+const whitelistColumns = `whitelist_id, subject_id, name, list, entity_id, record_hash, reviewer, reason, created_at, invalidated_at`
+
+func (r *sqliteWhitelistRepository) addWhitelistEntry(e *WhitelistEntry) error {
+	query := `insert into whitelist_entries (` + whitelistColumns + `) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, e.ID, e.SubjectID, e.Name, e.List, e.EntityID, e.RecordHash, e.Reviewer, e.Reason, e.CreatedAt, e.InvalidatedAt)
+	return err
+}
+
+func (r *sqliteWhitelistRepository) removeWhitelistEntry(whitelistID string) (bool, error) {
+	res, err := r.db.Exec(`delete from whitelist_entries where whitelist_id = ?;`, whitelistID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (r *sqliteWhitelistRepository) getWhitelist(includeInvalidated bool) ([]*WhitelistEntry, error) {
+	query := `select ` + whitelistColumns + ` from whitelist_entries where invalidated_at is null order by created_at, whitelist_id;`
+	if includeInvalidated {
+		query = `select ` + whitelistColumns + ` from whitelist_entries order by created_at, whitelist_id;`
+	}
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*WhitelistEntry
+	for rows.Next() {
+		var e WhitelistEntry
+		var subjectID, name sql.NullString
+		err := rows.Scan(&e.ID, &subjectID, &name, &e.List, &e.EntityID, &e.RecordHash, &e.Reviewer, &e.Reason, &e.CreatedAt, &e.InvalidatedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.SubjectID, e.Name = subjectID.String, name.String
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *sqliteWhitelistRepository) invalidateWhitelistEntries(whitelistIDs []string, now time.Time) error {
+	for _, id := range whitelistIDs {
+		if _, err := r.db.Exec(`update whitelist_entries set invalidated_at = ? where whitelist_id = ? and invalidated_at is null;`, now, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addWhitelistRoutes(logger log.Logger, r *mux.Router, searcher *searcher, repo whitelistRepository) {
+	r.Methods("GET").Path("/whitelist").HandlerFunc(getWhitelist(logger, repo))
+	r.Methods("POST").Path("/whitelist").HandlerFunc(addWhitelistEntry(logger, searcher, repo))
+	r.Methods("DELETE").Path("/whitelist/{whitelistID}").HandlerFunc(removeWhitelistEntry(logger, searcher, repo))
+}
+
+func getWhitelist(logger log.Logger, repo whitelistRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		entries, err := repo.getWhitelist(strings.EqualFold(r.URL.Query().Get("includeInvalidated"), "true"))
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if entries == nil {
+			entries = []*WhitelistEntry{}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(entries)
+	}
+}
+
+type whitelistRequest struct {
+	SubjectID string `json:"subjectID"`
+	Name      string `json:"name"`
+	List      string `json:"list"`
+	EntityID  string `json:"entityID"`
+	Reason    string `json:"reason"`
+}
+
+func (req whitelistRequest) validate() error {
+	if (req.SubjectID == "") == (strings.TrimSpace(req.Name) == "") {
+		return errWhitelistSubject
+	}
+	if !nameWatchList(req.List) {
+		return fmt.Errorf("unknown list %q, expected one of: %s", req.List, strings.Join(nameWatchLists, ", "))
+	}
+	if req.EntityID == "" {
+		return errors.New("whitelist entries need an entityID")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return errNoWhitelistReason
+	}
+	return nil
+}
+
+func addWhitelistEntry(logger log.Logger, searcher *searcher, repo whitelistRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		userID := moovhttp.GetUserId(r)
+		if userID == "" {
+			moovhttp.Problem(w, errNoUserID)
+			return
+		}
+		var req whitelistRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		req.List = strings.ToLower(strings.TrimSpace(req.List))
+		if err := req.validate(); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		record := searcher.findRecord(req.List, req.EntityID)
+		if record == nil {
+			moovhttp.Problem(w, fmt.Errorf("%s record %s not found", req.List, req.EntityID))
+			return
+		}
+		entry := &WhitelistEntry{
+			ID:         base.ID(),
+			SubjectID:  req.SubjectID,
+			List:       req.List,
+			EntityID:   req.EntityID,
+			Reviewer:   userID,
+			Reason:     req.Reason,
+			RecordHash: recordHash(record),
+			CreatedAt:  time.Now(),
+		}
+		if req.Name != "" {
+			entry.Name = precompute(req.Name)
+		}
+		if err := repo.addWhitelistEntry(entry); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		searcher.reloadWhitelist()
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(entry)
+	}
+}
+
+func removeWhitelistEntry(logger log.Logger, searcher *searcher, repo whitelistRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w = wrapResponseWriter(logger, w, r)
+
+		whitelistID, ok := mux.Vars(r)["whitelistID"]
+		if !ok || whitelistID == "" {
+			moovhttp.Problem(w, errNoWhitelistID)
+			return
+		}
+		removed, err := repo.removeWhitelistEntry(whitelistID)
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if !removed {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		searcher.reloadWhitelist()
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cardonator/ofac"
+	"github.com/gorilla/mux"
+)
+
+func createTestWhitelistRepository(t *testing.T) *sqliteWhitelistRepository {
+	t.Helper()
+
+	db, err := createTestSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &sqliteWhitelistRepository{db.db}
+}
+
+// whitelistSearcher returns a searcher holding one SDN with remarks, so tests can change the record.
+func whitelistSearcher(repo whitelistRepository, remarks string) *searcher {
+	return &searcher{
+		SDNs:          precomputeSDNs([]*ofac.SDN{{EntityID: "306", SDNName: "BANCO NACIONAL DE CUBA", Remarks: remarks}}),
+		whitelistRepo: repo,
+	}
+}
+
+func addTestWhitelistEntry(t *testing.T, router *mux.Router, body string) *WhitelistEntry {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", "/whitelist", strings.NewReader(body))
+	req.Header.Set("x-user-id", "jane")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+	}
+	var entry WhitelistEntry
+	if err := json.NewDecoder(w.Body).Decode(&entry); err != nil {
+		t.Fatal(err)
+	}
+	return &entry
+}
+
+func TestWhitelist__find(t *testing.T) {
+	var empty *whitelist
+	if e := empty.find("cust-1", "", sdnList, "306", "abc"); e != nil {
+		t.Errorf("unexpected entry: %#v", e)
+	}
+
+	now := time.Now()
+	wl := newWhitelist([]*WhitelistEntry{
+		{ID: "1", SubjectID: "cust-1", List: sdnList, EntityID: "306", RecordHash: "abc"},
+		{ID: "2", Name: precompute("Banco Nacional"), List: sdnList, EntityID: "306", RecordHash: "abc"},
+		{ID: "3", SubjectID: "cust-2", List: sdnList, EntityID: "306", RecordHash: "abc", InvalidatedAt: &now},
+	})
+	if e := wl.find("cust-1", "", sdnList, "306", "abc"); e == nil || e.ID != "1" {
+		t.Errorf("unexpected entry: %#v", e)
+	}
+	if e := wl.find("", "BANCO NACIONAL", sdnList, "306", "abc"); e == nil || e.ID != "2" {
+		t.Errorf("unexpected entry: %#v", e)
+	}
+
+	// the record changed, a different record or list, or an invalidated entry
+	if e := wl.find("cust-1", "", sdnList, "306", "def"); e != nil {
+		t.Errorf("unexpected entry: %#v", e)
+	}
+	if e := wl.find("cust-1", "", altNameList, "306", "abc"); e != nil {
+		t.Errorf("unexpected entry: %#v", e)
+	}
+	if e := wl.find("cust-2", "", sdnList, "306", "abc"); e != nil {
+		t.Errorf("unexpected entry: %#v", e)
+	}
+}
+
+func TestWhitelist__routes(t *testing.T) {
+	repo := createTestWhitelistRepository(t)
+	defer repo.close()
+
+	router := mux.NewRouter()
+	addWhitelistRoutes(nil, router, whitelistSearcher(repo, ""), repo)
+
+	entry := addTestWhitelistEntry(t, router, `{"subjectID": "cust-1", "list": "SDN", "entityID": "306", "reason": "different DOB"}`)
+	if entry.ID == "" || entry.List != sdnList || entry.Reviewer != "jane" {
+		t.Errorf("unexpected entry: %#v", entry)
+	}
+
+	bad := []string{
+		`{"list": "sdn", "entityID": "306", "reason": "no subject"}`,
+		`{"subjectID": "cust-1", "name": "Banco", "list": "sdn", "entityID": "306", "reason": "both"}`,
+		`{"subjectID": "cust-1", "list": "ofac", "entityID": "306", "reason": "unknown list"}`,
+		`{"subjectID": "cust-1", "list": "sdn", "entityID": "306"}`,
+		`{"subjectID": "cust-1", "list": "sdn", "entityID": "999", "reason": "missing record"}`,
+	}
+	for i := range bad {
+		req := httptest.NewRequest("POST", "/whitelist", strings.NewReader(bad[i]))
+		req.Header.Set("x-user-id", "jane")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: bogus status code: %d", bad[i], w.Code)
+		}
+	}
+	if w := serveSubjects(router, "POST", "/whitelist", `{"subjectID": "cust-1", "list": "sdn", "entityID": "306", "reason": "no user"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+
+	w := serveSubjects(router, "GET", "/whitelist", "")
+	var entries []*WhitelistEntry
+	if err := json.NewDecoder(w.Body).Decode(&entries); err != nil || len(entries) != 1 || entries[0].ID != entry.ID {
+		t.Errorf("entries=%#v err=%v", entries, err)
+	}
+
+	if w := serveSubjects(router, "DELETE", "/whitelist/"+entry.ID, ""); w.Code != http.StatusOK {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+	if w := serveSubjects(router, "DELETE", "/whitelist/"+entry.ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+}
+
+func TestWhitelist__deniedPersons(t *testing.T) {
+	repo := createTestWhitelistRepository(t)
+	defer repo.close()
+
+	// denied persons sharing a name are whitelisted separately
+	s := &searcher{
+		DPs: precomputeDPs([]*ofac.DPL{
+			{Name: "JOHN SMITH", City: "MIAMI", EffectiveDate: "01/01/2019"},
+			{Name: "JOHN SMITH", City: "DALLAS", EffectiveDate: "06/01/2019"},
+		}),
+		whitelistRepo: repo,
+	}
+	router := mux.NewRouter()
+	addWhitelistRoutes(nil, router, s, repo)
+
+	miami := s.DPs[0].id
+	if record, ok := s.findRecord(dplList, miami).(*ofac.DPL); !ok || record.City != "MIAMI" {
+		t.Fatalf("unexpected record: %#v", record)
+	}
+	addTestWhitelistEntry(t, router, `{"subjectID": "cust-1", "list": "dpl", "entityID": "`+miami+`", "reason": "different city"}`)
+
+	cases := s.findSubjectHits(&Subject{ID: "cust-1", Name: "John Smith"}, 0.90, s.loadWhitelist(), time.Now())
+	if len(cases) != 1 || cases[0].EntityID != s.DPs[1].id {
+		t.Errorf("unexpected cases: %#v", cases)
+	}
+}
+
+func TestWhitelist__suppression(t *testing.T) {
+	repo := createTestWhitelistRepository(t)
+	defer repo.close()
+
+	s := whitelistSearcher(repo, "a.k.a. 'BNC'.")
+	router := mux.NewRouter()
+	addSearchRoutes(nil, router, s)
+	addWhitelistRoutes(nil, router, s, repo)
+
+	search := func() searchResponse {
+		t.Helper()
+		w := serveSubjects(router, "GET", "/search?name=Banco+Nacional+de+Cuba", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("bogus status code: %d: %s", w.Code, w.Body.String())
+		}
+		var resp searchResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	if resp := search(); len(resp.SDNs) != 1 || len(resp.Whitelisted) != 0 {
+		t.Fatalf("unexpected response: %#v", resp)
+	}
+
+	nameEntry := addTestWhitelistEntry(t, router, `{"name": "Banco Nacional de Cuba", "list": "sdn", "entityID": "306", "reason": "our bank"}`)
+	addTestWhitelistEntry(t, router, `{"subjectID": "cust-1", "list": "sdn", "entityID": "306", "reason": "different DOB"}`)
+
+	// search results and name watches leave out the record
+	if resp := search(); len(resp.SDNs) != 0 || len(resp.Whitelisted) != 1 || resp.Whitelisted[0].ID != nameEntry.ID {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+	if hits := s.findNameWatchHits(watch{companyName: "Banco Nacional de Cuba", minMatch: 0.90}); len(hits.SDNs) != 0 {
+		t.Errorf("unexpected hits: %#v", hits)
+	}
+
+	// so does screening the subject
+	if cases := s.findSubjectHits(&Subject{ID: "cust-1", Name: "Banco Nacional de Cuba"}, 0.90, s.loadWhitelist(), time.Now()); len(cases) != 0 {
+		t.Errorf("unexpected cases: %#v", cases)
+	}
+	if cases := s.findSubjectHits(&Subject{ID: "cust-2", Name: "Banco Nacional Cuba"}, 0.90, s.loadWhitelist(), time.Now()); len(cases) != 1 {
+		t.Errorf("unexpected cases: %#v", cases)
+	}
+
+	// an unchanged record keeps the whitelist
+	if n, err := s.invalidateWhitelist(time.Now()); n != 0 || err != nil {
+		t.Errorf("n=%d err=%v", n, err)
+	}
+
+	// the record changed in a later download
+	s = whitelistSearcher(repo, "a.k.a. 'BNC'; Linked To: CUBA.")
+	router = mux.NewRouter()
+	addSearchRoutes(nil, router, s)
+	if resp := search(); len(resp.SDNs) != 1 || len(resp.Whitelisted) != 0 {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+	if n, err := s.invalidateWhitelist(time.Now()); n != 2 || err != nil {
+		t.Errorf("n=%d err=%v", n, err)
+	}
+	if wl := s.loadWhitelist(); len(wl.entries) != 0 {
+		t.Errorf("invalidated entries are still loaded: %#v", wl.entries)
+	}
+	entries, err := repo.getWhitelist(true)
+	if err != nil || len(entries) != 2 || entries[0].InvalidatedAt == nil {
+		t.Errorf("entries=%#v err=%v", entries, err)
+	}
+	if entries, err := repo.getWhitelist(false); err != nil || len(entries) != 0 {
+		t.Errorf("entries=%#v err=%v", entries, err)
+	}
+}
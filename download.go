@@ -5,13 +5,22 @@
 package ofac
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/moov-io/base"
 )
 
 var (
@@ -47,6 +56,81 @@ func init() {
 // See: https://www.treasury.gov/resource-center/sanctions/SDN-List/Pages/sdn_data.aspx
 type Downloader struct {
 	HTTP *http.Client
+
+	// MaxAttempts is how many times each file is requested before giving up on it. Zero means 3.
+	MaxAttempts int
+
+	// Backoff is how long to wait before the first retry of a file, which doubles after each attempt. Zero means 1s.
+	Backoff time.Duration
+
+	// Previous is the manifest of an earlier download. Conditional requests (with the ETag and Last-Modified
+	// of each file) are made for files still in Previous.Dir and unchanged files are copied from there.
+	Previous *Manifest
+}
+
+// Manifest describes the files downloaded into Dir, sorted by name.
+type Manifest struct {
+	Dir   string          `json:"dir"`
+	Files []*ManifestFile `json:"files"`
+}
+
+// Unchanged returns true when every file is the same as in the previous download.
+func (m *Manifest) Unchanged() bool {
+	for i := range m.Files {
+		if !m.Files[i].Unchanged {
+			return false
+		}
+	}
+	return len(m.Files) > 0
+}
+
+// File returns the manifest entry for the file called name, or nil if it's not in the manifest.
+func (m *Manifest) File(name string) *ManifestFile {
+	for i := range m.Files {
+		if m.Files[i].Name == name {
+			return m.Files[i]
+		}
+	}
+	return nil
+}
+
+// ManifestFile is a single downloaded file
+type ManifestFile struct {
+	// Name is the file's name inside Manifest.Dir
+	Name string `json:"name"`
+	// URL is where the file was downloaded from
+	URL string `json:"url"`
+	// Size is the file's length in bytes
+	Size int64 `json:"size"`
+	// SHA256 is the hex encoded SHA-256 checksum of the file
+	SHA256 string `json:"sha256"`
+	// Attempts is how many requests were made to download the file
+	Attempts int `json:"attempts"`
+
+	// ETag and LastModified are the response headers of the file, sent back in conditional requests
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+
+	// Unchanged is true when the file has the same checksum as in Downloader.Previous, either because
+	// the server responded with 304 Not Modified or the same contents were downloaded again.
+	Unchanged bool `json:"unchanged"`
+}
+
+// DownloadError is returned (inside a base.ErrorList) for each file which couldn't be downloaded.
+type DownloadError struct {
+	Name     string
+	URL      string
+	Attempts int
+	Err      error
+}
+
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("%s: %v (attempts=%d url=%s)", e.Name, e.Err, e.Attempts, e.URL)
+}
+
+// Unwrap returns the error from the last attempt
+func (e *DownloadError) Unwrap() error {
+	return e.Err
 }
 
 // GetFiles will download all OFAC related files and store them in a temporary directory
@@ -54,13 +138,32 @@ type Downloader struct {
 //
 // Callers are expected to cleanup the temp directory.
 func (dl *Downloader) GetFiles() (string, error) {
+	manifest, err := dl.GetFilesContext(context.Background())
+	if err != nil {
+		return "", err
+	}
+	return manifest.Dir, nil
+}
+
+// GetFilesContext downloads all OFAC related files into a temporary directory and returns a Manifest of them.
+//
+// Each file is requested up to MaxAttempts times, backing off between attempts, until it's returned with a
+// 2xx status code, a non-HTML content type and a body as long as its Content-Length. Responses with any other
+// 4xx status code (except 429) aren't retried. When any file fails a base.ErrorList holding a *DownloadError
+// for each failed file is returned and the directory is removed. Downloads are cancelled when ctx is done.
+//
+// When Previous is set files which haven't changed are copied from Previous.Dir (if they're still there) instead
+// of being downloaded again and are marked as Unchanged, see Manifest.Unchanged.
+//
+// Callers are expected to cleanup the temp directory.
+func (dl *Downloader) GetFilesContext(ctx context.Context) (*Manifest, error) {
 	if dl.HTTP == nil {
 		dl.HTTP = http.DefaultClient
 	}
 
 	dir, err := ioutil.TempDir("", "sanctions-lists-downloader")
 	if err != nil {
-		return "", fmt.Errorf("OFAC: unable to make temp dir: %v", err)
+		return nil, fmt.Errorf("OFAC: unable to make temp dir: %v", err)
 	}
 
 	// create a single list containing all filenames and source URLs
@@ -73,36 +176,205 @@ func (dl *Downloader) GetFiles() (string, error) {
 		namesAndSources[fname] = fmt.Sprintf(dplURLTemplate, fname)
 	}
 
+	var mu sync.Mutex
+	manifest := &Manifest{Dir: dir}
+	var failed []*DownloadError
+
 	wg := sync.WaitGroup{}
 	wg.Add(len(namesAndSources))
 	for name, source := range namesAndSources {
 		go func(wg *sync.WaitGroup, filename, downloadURL string) {
 			defer wg.Done()
 
-			resp, err := dl.HTTP.Get(downloadURL)
-			if err != nil {
-				return
-			}
-			defer resp.Body.Close()
+			file, err := dl.downloadFile(ctx, dir, filename, downloadURL)
 
-			// Copy resp.Body into a file in our temp dir
-			fd, err := os.Create(filepath.Join(dir, filename))
+			mu.Lock()
+			defer mu.Unlock()
 			if err != nil {
-				return
+				failed = append(failed, err)
+			} else {
+				manifest.Files = append(manifest.Files, file)
 			}
-			defer fd.Close()
-
-			io.Copy(fd, resp.Body) // copy contents
 		}(&wg, name, source)
 	}
 
 	wg.Wait()
 
-	// count files and error if the count isn't what we expected
-	fds, err := ioutil.ReadDir(dir)
-	if err != nil || len(fds) != len(namesAndSources) {
-		return "", fmt.Errorf("DOWNLOADER: problem downloading (found=%d, expected=%d): err=%v", len(fds), len(namesAndSources), err)
+	if len(failed) > 0 {
+		os.RemoveAll(dir)
+
+		sort.Slice(failed, func(i, j int) bool { return failed[i].Name < failed[j].Name })
+		var errs base.ErrorList
+		for i := range failed {
+			errs.Add(failed[i])
+		}
+		return nil, errs
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Name < manifest.Files[j].Name })
+	return manifest, nil
+}
+
+// downloadFile saves downloadURL as filename in dir, retrying until dl.MaxAttempts is reached.
+func (dl *Downloader) downloadFile(ctx context.Context, dir, filename, downloadURL string) (*ManifestFile, *DownloadError) {
+	maxAttempts, backoff := dl.MaxAttempts, dl.Backoff
+	if maxAttempts < 1 {
+		maxAttempts = 3
 	}
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	prev := dl.previousFile(filename)
 
-	return dir, nil
+	fail := &DownloadError{Name: filename, URL: downloadURL}
+	for {
+		fail.Attempts++
+		file, retry, err := dl.attemptFile(ctx, filepath.Join(dir, filename), downloadURL, prev)
+		if err == nil {
+			file.Name, file.URL, file.Attempts = filename, downloadURL, fail.Attempts
+			if dl.Previous != nil {
+				if recorded := dl.Previous.File(filename); recorded != nil && recorded.SHA256 == file.SHA256 {
+					file.Unchanged = true
+				}
+			}
+			return file, nil
+		}
+		fail.Err = err
+		if !retry || fail.Attempts >= maxAttempts {
+			return nil, fail
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			fail.Err = ctx.Err()
+			return nil, fail
+		}
+	}
+}
+
+// previousFile returns the file called name from dl.Previous if it's still on disk with the same size and checksum.
+func (dl *Downloader) previousFile(name string) *ManifestFile {
+	if dl.Previous == nil || dl.Previous.Dir == "" {
+		return nil
+	}
+	prev := dl.Previous.File(name)
+	if prev == nil {
+		return nil
+	}
+	size, sum, err := checksumFile(filepath.Join(dl.Previous.Dir, name))
+	if err != nil || size != prev.Size || sum != prev.SHA256 {
+		return nil
+	}
+	return prev
+}
+
+func checksumFile(path string) (int64, string, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer fd.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, fd)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// attemptFile makes one request for downloadURL and writes its body to path. It returns if the request
+// should be retried when there's an error.
+//
+// When prev is set the request is conditional and prev's copy is used if the server responds with 304 Not Modified.
+func (dl *Downloader) attemptFile(ctx context.Context, path, downloadURL string, prev *ManifestFile) (*ManifestFile, bool, error) {
+	req, err := http.NewRequest("GET", downloadURL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if prev != nil {
+		if prev.ETag != "" {
+			req.Header.Set("If-None-Match", prev.ETag)
+		}
+		if prev.LastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.LastModified)
+		}
+	}
+	resp, err := dl.HTTP.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && prev != nil {
+		if err := copyFile(filepath.Join(dl.Previous.Dir, prev.Name), path); err != nil {
+			return nil, false, fmt.Errorf("problem copying unchanged file: %v", err)
+		}
+		return &ManifestFile{
+			Size:         prev.Size,
+			SHA256:       prev.SHA256,
+			ETag:         prev.ETag,
+			LastModified: prev.LastModified,
+			Unchanged:    true,
+		}, false, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, retry, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	// Error pages (and captive portals) respond with HTML instead of the list
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		if mediaType, _, _ := mime.ParseMediaType(ct); mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+			return nil, true, fmt.Errorf("unexpected content type %s", ct)
+		}
+	}
+
+	// Copy resp.Body into a file in our temp dir
+	fd, err := os.Create(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer fd.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(fd, h), resp.Body)
+	if err != nil {
+		return nil, ctx.Err() == nil, fmt.Errorf("problem reading body: %v", err)
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return nil, true, fmt.Errorf("truncated body, read %d of %d bytes", n, resp.ContentLength)
+	}
+	if n == 0 {
+		return nil, true, errors.New("empty body")
+	}
+	if err := fd.Sync(); err != nil {
+		return nil, false, err
+	}
+	file := &ManifestFile{
+		Size:         n,
+		SHA256:       hex.EncodeToString(h.Sum(nil)),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	return file, false, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
 }
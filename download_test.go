@@ -5,9 +5,20 @@
 package ofac
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/moov-io/base"
 )
 
 func TestDownloader(t *testing.T) {
@@ -38,3 +49,175 @@ func TestDownloader(t *testing.T) {
 		}
 	}
 }
+
+// serveTestFiles points every download URL at handler until the returned function is called
+func serveTestFiles(t *testing.T, handler http.Handler) func() {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	ofacTemplate, dplTemplate, csl := ofacURLTemplate, dplURLTemplate, cslURL
+	ofacURLTemplate = server.URL + "/%s"
+	dplURLTemplate = server.URL + "/%s"
+	cslURL = server.URL + "/csl.csv"
+	return func() {
+		server.Close()
+		ofacURLTemplate, dplURLTemplate, cslURL = ofacTemplate, dplTemplate, csl
+	}
+}
+
+func TestDownloader__manifest(t *testing.T) {
+	defer serveTestFiles(t, http.FileServer(http.Dir(filepath.Join("test", "testdata"))))()
+
+	dl := Downloader{}
+	manifest, err := dl.GetFilesContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(manifest.Dir)
+
+	if len(manifest.Files) != 6 || manifest.Files[0].Name != "add.csv" {
+		t.Fatalf("unexpected manifest: %#v", manifest.Files)
+	}
+	for _, file := range manifest.Files {
+		bs, err := ioutil.ReadFile(filepath.Join("test", "testdata", file.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(bs)
+		if file.Size != int64(len(bs)) || file.SHA256 != hex.EncodeToString(sum[:]) || file.Attempts != 1 {
+			t.Errorf("unexpected file: %#v", file)
+		}
+	}
+	if f := manifest.File("sdn.csv"); f == nil || !strings.HasSuffix(f.URL, "/sdn.csv") {
+		t.Errorf("unexpected file: %#v", f)
+	}
+	if f := manifest.File("other.csv"); f != nil {
+		t.Errorf("unexpected file: %#v", f)
+	}
+}
+
+func TestDownloader__retries(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	files := http.FileServer(http.Dir(filepath.Join("test", "testdata")))
+	defer serveTestFiles(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		n := requests[r.URL.Path]
+		mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/sdn.csv" && n == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/alt.csv" && n == 1:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html>Maintenance</html>"))
+		case r.URL.Path == "/dpl.txt":
+			http.NotFound(w, r)
+		case r.URL.Path == "/add.csv":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			files.ServeHTTP(w, r)
+		}
+	}))()
+
+	dl := Downloader{MaxAttempts: 3, Backoff: time.Millisecond}
+	manifest, err := dl.GetFilesContext(context.Background())
+	if manifest != nil || err == nil {
+		t.Fatalf("manifest=%#v err=%v", manifest, err)
+	}
+
+	// only the files which never downloaded are reported
+	errs, ok := err.(base.ErrorList)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	add, dpl := errs[0].(*DownloadError), errs[1].(*DownloadError)
+	if add.Name != "add.csv" || add.Attempts != 3 || !strings.Contains(add.Error(), "status code 502") {
+		t.Errorf("unexpected error: %v", add)
+	}
+	if dpl.Name != "dpl.txt" || dpl.Attempts != 1 || !strings.Contains(dpl.Error(), "status code 404") {
+		t.Errorf("unexpected error: %v", dpl)
+	}
+	if requests["/sdn.csv"] != 2 || requests["/alt.csv"] != 2 {
+		t.Errorf("unexpected requests: %v", requests)
+	}
+}
+
+func TestDownloader__cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dl := Downloader{}
+	if manifest, err := dl.GetFilesContext(ctx); err == nil {
+		os.RemoveAll(manifest.Dir)
+		t.Fatal("expected error")
+	}
+}
+
+func TestDownloader__conditional(t *testing.T) {
+	var mu sync.Mutex
+	var conditional, full int
+	files := http.FileServer(http.Dir(filepath.Join("test", "testdata")))
+	defer serveTestFiles(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if r.Header.Get("If-None-Match") != "" && r.Header.Get("If-Modified-Since") != "" {
+			conditional++
+		} else {
+			full++
+		}
+		mu.Unlock()
+
+		w.Header().Set("ETag", `"`+r.URL.Path+`"`)
+		if r.Header.Get("If-None-Match") == `"`+r.URL.Path+`"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))()
+
+	dl := Downloader{}
+	first, err := dl.GetFilesContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(first.Dir)
+	if first.Unchanged() || full != 6 {
+		t.Errorf("unchanged=%v full=%d", first.Unchanged(), full)
+	}
+	if f := first.File("sdn.csv"); f.ETag != `"/sdn.csv"` || f.LastModified == "" {
+		t.Errorf("unexpected file: %#v", f)
+	}
+
+	// unchanged files are copied from the previous download
+	dl = Downloader{Previous: first}
+	second, err := dl.GetFilesContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(second.Dir)
+	if !second.Unchanged() || conditional != 6 {
+		t.Errorf("unchanged=%v conditional=%d", second.Unchanged(), conditional)
+	}
+	for _, f := range second.Files {
+		if size, sum, err := checksumFile(filepath.Join(second.Dir, f.Name)); err != nil || size != f.Size || sum != first.File(f.Name).SHA256 {
+			t.Errorf("%s: size=%d sum=%s err=%v", f.Name, size, sum, err)
+		}
+	}
+
+	// without the previous files everything is downloaded again, but matching checksums are still unchanged
+	os.RemoveAll(first.Dir)
+	third, err := dl.GetFilesContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(third.Dir)
+	if !third.Unchanged() || full != 12 {
+		t.Errorf("unchanged=%v full=%d", third.Unchanged(), full)
+	}
+
+	// one changed file changes the manifest
+	if m := (&Manifest{Files: []*ManifestFile{{Unchanged: true}, {}}}); m.Unchanged() {
+		t.Error("expected a changed manifest")
+	}
+}
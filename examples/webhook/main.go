@@ -22,6 +22,9 @@ import (
 
 var (
 	httpAddr = flag.String("http.addr", ":10101", "HTTP listen address")
+
+	flagSigningSecret      = flag.String("webhook.secret", os.Getenv("WEBHOOK_SIGNING_SECRET"), "Signing secret of the OFAC watch, deliveries aren't verified if empty")
+	flagSignatureTolerance = flag.Duration("webhook.tolerance", 5*time.Minute, "How old (or far in the future) a signed delivery can be")
 )
 
 func main() {
@@ -44,7 +47,13 @@ func main() {
 	// Setup HTTP handler
 	handler := mux.NewRouter()
 	addPingRoute(handler)
-	addWebhookRoute(logger, handler)
+	var verifier *webhookVerifier
+	if *flagSigningSecret != "" {
+		verifier = newWebhookVerifier(*flagSigningSecret, *flagSignatureTolerance)
+	} else {
+		logger.Log("startup", "WARNING: no signing secret, webhook deliveries won't be verified")
+	}
+	addWebhookRoute(logger, handler, verifier)
 
 	// Create main HTTP server
 	serve := &http.Server{
@@ -0,0 +1,94 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	errMissingSignature = errors.New("missing X-Signature, X-Timestamp or X-Delivery-ID header")
+	errBadSignature     = errors.New("webhook signature mismatch")
+	errStaleWebhook     = errors.New("webhook timestamp is outside the allowed tolerance")
+	errReplayedWebhook  = errors.New("webhook delivery was already received")
+)
+
+// webhookVerifier checks the signature OFAC sends with each webhook delivery and rejects stale
+// or replayed deliveries.
+//
+// OFAC signs each delivery with the secret returned when the watch was created. X-Signature is
+// "sha256=" followed by the hex encoded HMAC-SHA256 of the X-Timestamp value, a period and the body.
+type webhookVerifier struct {
+	secret []byte
+
+	// tolerance is how far X-Timestamp can be from our clock before the delivery is rejected.
+	tolerance time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time // delivery ID -> timestamp
+}
+
+func newWebhookVerifier(secret string, tolerance time.Duration) *webhookVerifier {
+	return &webhookVerifier{
+		secret:    []byte(secret),
+		tolerance: tolerance,
+		seen:      make(map[string]time.Time),
+	}
+}
+
+// verify returns an error if the request isn't signed with our secret, was sent outside of the
+// tolerance or has a delivery ID we've already accepted.
+//
+// OFAC retries deliveries (with the same delivery ID) when we don't respond with a 2xx status, so
+// a delivery ID is only remembered once its signature and timestamp are valid.
+func (v *webhookVerifier) verify(r *http.Request, body []byte) error {
+	signature, timestamp := r.Header.Get("X-Signature"), r.Header.Get("X-Timestamp")
+	deliveryID := r.Header.Get("X-Delivery-ID")
+	if signature == "" || timestamp == "" || deliveryID == "" {
+		return errMissingSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid X-Timestamp %q: %v", timestamp, err)
+	}
+	sentAt := time.Unix(unix, 0)
+	if diff := time.Since(sentAt); diff > v.tolerance || diff < -v.tolerance {
+		return errStaleWebhook
+	}
+
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return errBadSignature
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, exists := v.seen[deliveryID]; exists {
+		return errReplayedWebhook
+	}
+	v.seen[deliveryID] = sentAt
+
+	// Forget deliveries which would be rejected as stale anyway
+	for id, t := range v.seen {
+		if time.Since(t) > 2*v.tolerance {
+			delete(v.seen, id)
+		}
+	}
+	return nil
+}
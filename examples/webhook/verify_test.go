@@ -0,0 +1,107 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+func signedRequest(t *testing.T, secret string, deliveryID string, sentAt time.Time, body []byte) *http.Request {
+	t.Helper()
+
+	timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	req := httptest.NewRequest("POST", "/ofac", bytes.NewReader(body))
+	req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Delivery-ID", deliveryID)
+	return req
+}
+
+func TestVerifier(t *testing.T) {
+	v := newWebhookVerifier("secret", 5*time.Minute)
+	body := []byte(`{"id":"306"}`)
+
+	if err := v.verify(signedRequest(t, "secret", "delivery1", time.Now(), body), body); err != nil {
+		t.Fatal(err)
+	}
+
+	// replayed delivery
+	if err := v.verify(signedRequest(t, "secret", "delivery1", time.Now(), body), body); err != errReplayedWebhook {
+		t.Errorf("expected replay error: %v", err)
+	}
+
+	// stale delivery
+	if err := v.verify(signedRequest(t, "secret", "delivery2", time.Now().Add(-10*time.Minute), body), body); err != errStaleWebhook {
+		t.Errorf("expected stale error: %v", err)
+	}
+
+	// wrong secret or modified body
+	if err := v.verify(signedRequest(t, "other", "delivery3", time.Now(), body), body); err != errBadSignature {
+		t.Errorf("expected signature error: %v", err)
+	}
+	if err := v.verify(signedRequest(t, "secret", "delivery4", time.Now(), body), []byte(`{"id":"307"}`)); err != errBadSignature {
+		t.Errorf("expected signature error: %v", err)
+	}
+
+	// the rejected delivery can still be received
+	if err := v.verify(signedRequest(t, "secret", "delivery3", time.Now(), body), body); err != nil {
+		t.Error(err)
+	}
+
+	// unsigned
+	req := httptest.NewRequest("POST", "/ofac", bytes.NewReader(body))
+	if err := v.verify(req, body); err != errMissingSignature {
+		t.Errorf("expected missing signature error: %v", err)
+	}
+}
+
+func TestWebhookRoute__signed(t *testing.T) {
+	router := mux.NewRouter()
+	addWebhookRoute(log.NewNopLogger(), router, newWebhookVerifier("secret", 5*time.Minute))
+
+	body, err := json.Marshal(exampleCustomer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, signedRequest(t, "secret", "delivery1", time.Now(), body))
+	w.Flush()
+	if w.Code != http.StatusOK {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+
+	// replayed
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, signedRequest(t, "secret", "delivery1", time.Now(), body))
+	w.Flush()
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+
+	// unsigned
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/ofac", bytes.NewReader(body)))
+	w.Flush()
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("bogus status code: %d", w.Code)
+	}
+}
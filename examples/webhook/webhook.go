@@ -24,6 +24,8 @@ type Customer struct {
 	Addresses []*ofac.Address           `json:"addresses"`
 	Alts      []*ofac.AlternateIdentity `json:"alts"`
 	Match     float64                   `json:"match,omitempty"`
+
+	ChangeType string `json:"changeType"`
 }
 
 type Company struct {
@@ -32,9 +34,13 @@ type Company struct {
 	Addresses []*ofac.Address           `json:"addresses"`
 	Alts      []*ofac.AlternateIdentity `json:"alts"`
 	Match     float64                   `json:"match,omitempty"`
+
+	ChangeType string `json:"changeType"`
 }
 
-func addWebhookRoute(logger log.Logger, r *mux.Router) {
+// addWebhookRoute registers our handler for OFAC webhooks. When verifier is non-nil every
+// delivery's signature is checked before it's read.
+func addWebhookRoute(logger log.Logger, r *mux.Router, verifier *webhookVerifier) {
 	r.Methods("POST").Path("/ofac").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		bs, err := ioutil.ReadAll(io.LimitReader(r.Body, 5*1024*1024))
 		if err != nil {
@@ -43,16 +49,28 @@ func addWebhookRoute(logger log.Logger, r *mux.Router) {
 			w.Header().Set("Content-Type", "application/json; charset=utf-8")
 			w.WriteHeader(http.StatusBadRequest)
 			w.Write([]byte(fmt.Sprintf(`{"error": "%s"}`, err)))
+			return
+		}
+		if verifier != nil {
+			if err := verifier.verify(r, bs); err != nil {
+				logger.Log("webhook", fmt.Sprintf("rejected delivery %s: %v", r.Header.Get("X-Delivery-ID"), err))
+
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(fmt.Sprintf(`{"error": "%s"}`, err)))
+				return
+			}
 		}
 
 		if cust := readCustomer(bytes.NewReader(bs)); cust != nil {
-			logger.Log("webhook", fmt.Sprintf("got webhook for Customer %s (%s) match=%.2f", cust.ID, cust.SDN.SDNName, cust.Match))
+			logger.Log("webhook", fmt.Sprintf("got %s webhook for Customer %s (%s) match=%.2f", cust.ChangeType, cust.ID, sdnName(cust.SDN), cust.Match))
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 		if company := readCompany(bytes.NewReader(bs)); company != nil {
-			logger.Log("webhook", fmt.Sprintf("got webhook for Company %s (%s) match=%.2f", company.ID, company.SDN.SDNName, company.Match))
+			logger.Log("webhook", fmt.Sprintf("got %s webhook for Company %s (%s) match=%.2f", company.ChangeType, company.ID, sdnName(company.SDN), company.Match))
 			w.WriteHeader(http.StatusOK)
+			return
 		}
 
 		logger.Log("webhook", "malformed webhook request")
@@ -62,6 +80,14 @@ func addWebhookRoute(logger log.Logger, r *mux.Router) {
 	})
 }
 
+// sdnName returns the SDN's name, delisted notifications don't include the SDN record.
+func sdnName(sdn *ofac.SDN) string {
+	if sdn == nil {
+		return ""
+	}
+	return sdn.SDNName
+}
+
 func readCustomer(r io.Reader) *Customer {
 	var cust Customer
 	if err := json.NewDecoder(r).Decode(&cust); err != nil || cust.ID == "" {
@@ -59,7 +59,7 @@ func TestWebhookRoute(t *testing.T) {
 	}
 
 	router := mux.NewRouter()
-	addWebhookRoute(logger, router)
+	addWebhookRoute(logger, router, nil)
 
 	req := httptest.NewRequest("POST", "/ofac", &body)
 	router.ServeHTTP(w, req)
@@ -74,7 +74,7 @@ func TestWebhookRoute__bad(t *testing.T) {
 	logger := log.NewNopLogger()
 
 	router := mux.NewRouter()
-	addWebhookRoute(logger, router)
+	addWebhookRoute(logger, router, nil)
 
 	// no body
 	w := httptest.NewRecorder()
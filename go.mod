@@ -1,19 +1,23 @@
 module github.com/cardonator/ofac
 
 require (
+	github.com/Shopify/sarama v1.26.4
 	github.com/VividCortex/gohistogram v1.0.0 // indirect
 	github.com/antihax/optional v0.0.0-20180407024304-ca021399b1a6
 	github.com/go-kit/kit v0.8.0
+	github.com/go-sql-driver/mysql v1.5.0
 	github.com/gorilla/mux v1.7.2
 	github.com/hashicorp/golang-lru v0.5.1 // indirect
+	github.com/lib/pq v1.3.0
 	github.com/mattn/go-sqlite3 v1.10.0
 	github.com/moov-io/base v0.9.1-0.20190430163914-76a8e2cca72d
+	github.com/nats-io/nats-server/v2 v2.1.7
+	github.com/nats-io/nats.go v1.10.0
 	github.com/prometheus/client_golang v1.0.0
 	github.com/rickar/cal v1.0.1 // indirect
 	github.com/xrash/smetrics v0.0.0-20170218160415-a3153f7040e9
 	go4.org v0.0.0-20191010144846-132d2879e1e9
 	golang.org/x/oauth2 v0.0.0-20190604053449-0f29369cfe45
-	golang.org/x/sys v0.0.0-20190215142949-d0b11bdaac8a // indirect
 	golang.org/x/text v0.3.2
 )
 
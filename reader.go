@@ -5,10 +5,8 @@
 package ofac
 
 import (
-	"encoding/csv"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -117,11 +115,25 @@ type Reader struct {
 	// BISEntities returns an array of Bureau of Industry and Security Entities
 	BISEntities []*EL
 
+	// Strict makes Read return the first row or column which can't be parsed as a *base.ParseError. Otherwise
+	// bad rows are skipped (or kept, when only a column is bad) and their errors are returned by Errors.
+	Strict bool `json:"-"`
+
 	// errors holds each error encountered when attempting to parse the file
 	errors base.ErrorList
 }
 
-// Read will consume the file at r.FileName and attempt to parse it was a CSV OFAC file.
+// Errors returns each problem found by Read, including the rows skipped when Strict is false.
+func (r *Reader) Errors() base.ErrorList {
+	return r.errors
+}
+
+func (r *Reader) readOptions() *ReadOptions {
+	return &ReadOptions{Strict: r.Strict, Warn: r.errors.Add}
+}
+
+// Read will consume the file at r.FileName and attempt to parse it was a CSV OFAC file. Records are appended to
+// those already read, so one Reader can read each file of a download.
 func (r *Reader) Read() error {
 	ext := filepath.Ext(r.FileName)
 
@@ -173,140 +185,55 @@ func (r *Reader) csvFile() error {
 }
 
 func (r *Reader) csvAddressFile() error {
-	// Open CSV file
 	f, err := os.Open(r.FileName)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	// Read File into a Variable
-	reader := csv.NewReader(f)
-	for {
-		record, err := reader.Read()
-		if err != nil && err == csv.ErrFieldCount {
-			continue
-		}
-		if err == io.EOF { // TODO(Adam): add max line count break here also
-			break
-		}
-		if len(record) != 6 {
-			continue
-		}
-
-		record = replaceNull(record)
-		addr := &Address{
-			EntityID:                    record[0],
-			AddressID:                   record[1],
-			Address:                     record[2],
-			CityStateProvincePostalCode: record[3],
-			Country:                     record[4],
-			AddressRemarks:              record[5],
-		}
+	return ReadAddresses(f, r.readOptions(), func(addr *Address) error {
 		r.Addresses = append(r.Addresses, addr)
-	}
-	return nil
+		return nil
+	})
 }
 
 func (r *Reader) csvAlternateIdentityFile() error {
-	// Open CSV file
 	f, err := os.Open(r.FileName)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	// Read File into a Variable
-	reader := csv.NewReader(f)
-	for {
-		record, err := reader.Read()
-		if err != nil && err == csv.ErrFieldCount {
-			continue
-		}
-		if err == io.EOF { // TODO(adam)
-			break
-		}
-		if len(record) != 5 {
-			continue
-		}
-		record = replaceNull(record)
-		alt := &AlternateIdentity{
-			EntityID:         record[0],
-			AlternateID:      record[1],
-			AlternateType:    record[2],
-			AlternateName:    record[3],
-			AlternateRemarks: record[4],
-		}
+	return ReadAlternateIdentities(f, r.readOptions(), func(alt *AlternateIdentity) error {
 		r.AlternateIdentities = append(r.AlternateIdentities, alt)
-	}
-	return nil
+		return nil
+	})
 }
 
 func (r *Reader) csvSDNFile() error {
-	// Open CSV file
 	f, err := os.Open(r.FileName)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	// Read File into a Variable
-	reader := csv.NewReader(f)
-	for {
-		record, err := reader.Read()
-		if err != nil && err == csv.ErrFieldCount {
-			continue
-		}
-		if err == io.EOF { // TODO(Adam): add max line count break here also
-			break
-		}
-		if len(record) != 12 {
-			continue
-		}
-		record = replaceNull(record)
-		sdn := &SDN{
-			EntityID:               record[0],
-			SDNName:                record[1],
-			SDNType:                record[2],
-			Program:                record[3],
-			Title:                  record[4],
-			CallSign:               record[5],
-			VesselType:             record[6],
-			Tonnage:                record[7],
-			GrossRegisteredTonnage: record[8],
-			VesselFlag:             record[9],
-			VesselOwner:            record[10],
-			Remarks:                record[11],
-		}
+	return ReadSDNs(f, r.readOptions(), func(sdn *SDN) error {
 		r.SDNs = append(r.SDNs, sdn)
-	}
-	return nil
+		return nil
+	})
 }
 
 func (r *Reader) csvSDNCommentsFile() error {
-	// Open CSV file
 	f, err := os.Open(r.FileName)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	// Read File into a Variable
-	lines, err := csv.NewReader(f).ReadAll()
-	if err != nil {
-		return err
-	}
-
-	// Loop through lines & turn into object
-	for _, csvLine := range lines {
-		csvLine := replaceNull(csvLine)
-		sdnComments := &SDNComments{
-			EntityID:        csvLine[0],
-			RemarksExtended: csvLine[1],
-		}
-		r.SDNComments = append(r.SDNComments, sdnComments)
-	}
-	return nil
+	return ReadSDNComments(f, r.readOptions(), func(comments *SDNComments) error {
+		r.SDNComments = append(r.SDNComments, comments)
+		return nil
+	})
 }
 
 func (r *Reader) csvConsolidatedScreeningList() error {
@@ -316,23 +243,15 @@ func (r *Reader) csvConsolidatedScreeningList() error {
 	}
 	defer f.Close()
 
-	lines, err := csv.NewReader(f).ReadAll()
-	if err != nil {
-		return err
+	ssi := func(ssi *SSI) error {
+		r.SectoralSanctions = append(r.SectoralSanctions, ssi)
+		return nil
 	}
-
-	for _, csvLine := range lines {
-		csvLine := replaceNull(csvLine)
-		switch csvLine[cslSource] {
-		case ssiListName:
-			r.SectoralSanctions = append(r.SectoralSanctions, unmarshalSSI(csvLine))
-		case bisEntityListName:
-			r.BISEntities = append(r.BISEntities, unmarshalEL(csvLine))
-		default:
-			continue
-		}
+	el := func(el *EL) error {
+		r.BISEntities = append(r.BISEntities, el)
+		return nil
 	}
-	return nil
+	return ReadConsolidatedScreeningList(f, r.readOptions(), ssi, el)
 }
 
 func unmarshalSSI(row []string) *SSI {
@@ -390,40 +309,16 @@ func (r *Reader) txtFile() error {
 }
 
 func (r *Reader) txtDeniedPersonsFile() error {
-	// open txt file
 	f, err := os.Open(r.FileName)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	// create a new csv.Reader and set the delim char to txtDelim char
-	reader := csv.NewReader(f)
-	reader.Comma = txtDelim
-	// Read File into a Variable
-	lines, err := reader.ReadAll()
-	if err != nil {
-		return err
-	}
-
-	for _, txtLine := range lines {
-		deniedPerson := &DPL{
-			Name:           txtLine[0],
-			StreetAddress:  txtLine[1],
-			City:           txtLine[2],
-			State:          txtLine[3],
-			Country:        txtLine[4],
-			PostalCode:     txtLine[5],
-			EffectiveDate:  txtLine[6],
-			ExpirationDate: txtLine[7],
-			StandardOrder:  txtLine[8],
-			LastUpdate:     txtLine[9],
-			Action:         txtLine[10],
-			FRCitation:     txtLine[11],
-		}
-		r.DeniedPersons = append(r.DeniedPersons, deniedPerson)
-	}
-	return nil
+	return ReadDeniedPersons(f, r.readOptions(), func(dp *DPL) error {
+		r.DeniedPersons = append(r.DeniedPersons, dp)
+		return nil
+	})
 }
 
 // replaceNull replaces a CSV field that contain -0- with "".  Null values for all four formats consist of "-0-"
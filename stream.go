@@ -0,0 +1,363 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/moov-io/base"
+)
+
+// ReadOptions control how ReadSDNs and the other Read functions handle rows which can't be parsed. A nil
+// *ReadOptions skips bad rows without warnings.
+type ReadOptions struct {
+	// Strict stops reading at the first bad row or column, which is returned as a *base.ParseError.
+	Strict bool
+
+	// Warn is called with a *base.ParseError for each problem when Strict is false. Rows with the wrong number
+	// of columns (or invalid CSV) and a header naming the wrong columns are skipped, while rows with a bad
+	// column (a *ColumnError) are still read. It's optional.
+	Warn func(err error)
+}
+
+// ColumnError is a problem with one column of a row. It's the Err of the row's *base.ParseError.
+type ColumnError struct {
+	// Column is the position of the column in its row, the first column is 1
+	Column int
+	// Name is the column's name in the list's documentation or header
+	Name string
+	Err  error
+}
+
+func (e *ColumnError) Error() string {
+	return fmt.Sprintf("column %d (%s): %v", e.Column, e.Name, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ColumnError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errMissingValue = errors.New("missing value")
+	errNotNumeric   = errors.New("expected a number")
+)
+
+// rowFormat describes the rows of one list file
+type rowFormat struct {
+	record   string   // record type named in a base.ParseError
+	comma    rune     // column delimiter
+	columns  []string // name of each column
+	header   bool     // the first row names the columns and is skipped
+	required []int    // columns which can't be empty (0-indexed)
+	numeric  []int    // columns which have to be numbers (0-indexed)
+}
+
+var (
+	addressFormat = rowFormat{
+		record:   "Address",
+		comma:    ',',
+		columns:  []string{"ent_num", "Add_num", "Address", "City/State/Province/Postal Code", "Country", "Add_remarks"},
+		required: []int{0, 1},
+		numeric:  []int{0, 1},
+	}
+	alternateIdentityFormat = rowFormat{
+		record:   "AlternateIdentity",
+		comma:    ',',
+		columns:  []string{"ent_num", "alt_num", "alt_type", "alt_name", "alt_remarks"},
+		required: []int{0, 1, 3},
+		numeric:  []int{0, 1},
+	}
+	sdnFormat = rowFormat{
+		record:   "SDN",
+		comma:    ',',
+		columns:  []string{"ent_num", "SDN_Name", "SDN_Type", "Program", "Title", "Call_Sign", "Vess_type", "Tonnage", "GRT", "Vess_flag", "Vess_owner", "Remarks"},
+		required: []int{0, 1},
+		numeric:  []int{0},
+	}
+	sdnCommentsFormat = rowFormat{
+		record:   "SDNComments",
+		comma:    ',',
+		columns:  []string{"ent_num", "Remarks_Extended"},
+		required: []int{0},
+		numeric:  []int{0},
+	}
+	deniedPersonsFormat = rowFormat{
+		record:   "DPL",
+		comma:    txtDelim,
+		columns:  []string{"Name", "Street_Address", "City", "State", "Country", "Postal_Code", "Effective_Date", "Expiration_Date", "Standard_Order", "Last_Update", "Action", "FR_Citation"},
+		header:   true,
+		required: []int{0},
+	}
+	cslFormat = rowFormat{
+		record: "CSL",
+		comma:  ',',
+		columns: []string{
+			"source", "entity_number", "type", "programs", "name", "title", "addresses", "federal_register_notice", "start_date", "end_date",
+			"standard_order", "license_requirement", "license_policy", "call_sign", "vessel_type", "gross_tonnage", "gross_registered_tonnage",
+			"vessel_flag", "vessel_owner", "remarks", "source_list_url", "alt_names", "citizenships", "dates_of_birth", "nationalities",
+			"places_of_birth", "source_information_url", "ids",
+		},
+		header:   true,
+		required: []int{cslSource, cslName},
+	}
+)
+
+// ReadAddresses reads OFAC's add.csv from r, calling fn with each address. Reading stops at the first error
+// returned by fn.
+func ReadAddresses(r io.Reader, opts *ReadOptions, fn func(*Address) error) error {
+	return readRows(r, opts, addressFormat, func(record []string) error {
+		return fn(&Address{
+			EntityID:                    record[0],
+			AddressID:                   record[1],
+			Address:                     record[2],
+			CityStateProvincePostalCode: record[3],
+			Country:                     record[4],
+			AddressRemarks:              record[5],
+		})
+	})
+}
+
+// ReadAlternateIdentities reads OFAC's alt.csv from r, calling fn with each alternate identity. Reading stops
+// at the first error returned by fn.
+func ReadAlternateIdentities(r io.Reader, opts *ReadOptions, fn func(*AlternateIdentity) error) error {
+	return readRows(r, opts, alternateIdentityFormat, func(record []string) error {
+		return fn(&AlternateIdentity{
+			EntityID:         record[0],
+			AlternateID:      record[1],
+			AlternateType:    record[2],
+			AlternateName:    record[3],
+			AlternateRemarks: record[4],
+		})
+	})
+}
+
+// ReadSDNs reads OFAC's sdn.csv from r, calling fn with each Specially Designated National. Reading stops at
+// the first error returned by fn.
+func ReadSDNs(r io.Reader, opts *ReadOptions, fn func(*SDN) error) error {
+	return readRows(r, opts, sdnFormat, func(record []string) error {
+		return fn(&SDN{
+			EntityID:               record[0],
+			SDNName:                record[1],
+			SDNType:                record[2],
+			Program:                record[3],
+			Title:                  record[4],
+			CallSign:               record[5],
+			VesselType:             record[6],
+			Tonnage:                record[7],
+			GrossRegisteredTonnage: record[8],
+			VesselFlag:             record[9],
+			VesselOwner:            record[10],
+			Remarks:                record[11],
+		})
+	})
+}
+
+// ReadSDNComments reads OFAC's sdn_comments.csv from r, calling fn with each SDN's extended remarks. Reading
+// stops at the first error returned by fn.
+func ReadSDNComments(r io.Reader, opts *ReadOptions, fn func(*SDNComments) error) error {
+	return readRows(r, opts, sdnCommentsFormat, func(record []string) error {
+		return fn(&SDNComments{
+			EntityID:        record[0],
+			RemarksExtended: record[1],
+		})
+	})
+}
+
+// ReadDeniedPersons reads the BIS Denied Persons List (dpl.txt) from r, calling fn with each denied person.
+// Reading stops at the first error returned by fn.
+func ReadDeniedPersons(r io.Reader, opts *ReadOptions, fn func(*DPL) error) error {
+	return readRows(r, opts, deniedPersonsFormat, func(record []string) error {
+		return fn(&DPL{
+			Name:           record[0],
+			StreetAddress:  record[1],
+			City:           record[2],
+			State:          record[3],
+			Country:        record[4],
+			PostalCode:     record[5],
+			EffectiveDate:  record[6],
+			ExpirationDate: record[7],
+			StandardOrder:  record[8],
+			LastUpdate:     record[9],
+			Action:         record[10],
+			FRCitation:     record[11],
+		})
+	})
+}
+
+// ReadConsolidatedScreeningList reads the Consolidated Screening List (csl.csv) from r, calling ssi with each
+// Sectoral Sanctions Identification and el with each BIS Entity. Either can be nil to skip that list, and
+// records from other lists are skipped. Reading stops at the first error returned by ssi or el.
+func ReadConsolidatedScreeningList(r io.Reader, opts *ReadOptions, ssi func(*SSI) error, el func(*EL) error) error {
+	return readRows(r, opts, cslFormat, func(record []string) error {
+		switch {
+		case record[cslSource] == ssiListName && ssi != nil:
+			return ssi(unmarshalSSI(record))
+		case record[cslSource] == bisEntityListName && el != nil:
+			return el(unmarshalEL(record))
+		}
+		return nil
+	})
+}
+
+// readRows calls fn with each row of r after replacing its null values. Bad rows and columns are handled
+// according to opts.
+func readRows(r io.Reader, opts *ReadOptions, format rowFormat, fn func(record []string) error) error {
+	if opts == nil {
+		opts = &ReadOptions{}
+	}
+	problem := func(line int, err error) error {
+		err = &base.ParseError{Line: line, Record: format.record, Err: err}
+		if opts.Strict {
+			return err
+		}
+		if opts.Warn != nil {
+			opts.Warn(err)
+		}
+		return nil
+	}
+
+	rows := &rowScanner{r: bufio.NewReader(r), comma: format.comma}
+	first := true
+	for {
+		record, line, err := rows.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return err
+			}
+			if err := problem(line, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if first && format.header {
+			first = false
+			if strings.EqualFold(strings.TrimSpace(record[0]), format.columns[0]) {
+				if !format.isHeader(record) {
+					if err := problem(line, fmt.Errorf("unexpected header %q", strings.Join(record, string(format.comma)))); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+		}
+		first = false
+
+		if len(record) != len(format.columns) {
+			if err := problem(line, fmt.Errorf("wrong number of fields: expected %d, found %d", len(format.columns), len(record))); err != nil {
+				return err
+			}
+			continue
+		}
+		record = replaceNull(record)
+		for _, err := range format.checkColumns(record) {
+			if err := problem(line, err); err != nil {
+				return err
+			}
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// isHeader returns true when record names each of our columns
+func (f rowFormat) isHeader(record []string) bool {
+	if len(record) != len(f.columns) {
+		return false
+	}
+	for i := range record {
+		if !strings.EqualFold(strings.TrimSpace(record[i]), f.columns[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkColumns returns a *ColumnError for each required column which is empty and each numeric column which
+// isn't a number
+func (f rowFormat) checkColumns(record []string) []error {
+	var out []error
+	for _, i := range f.required {
+		if record[i] == "" {
+			out = append(out, &ColumnError{Column: i + 1, Name: f.columns[i], Err: errMissingValue})
+		}
+	}
+	for _, i := range f.numeric {
+		if record[i] == "" {
+			continue // reported as missing
+		}
+		if _, err := strconv.ParseInt(record[i], 10, 64); err != nil {
+			out = append(out, &ColumnError{Column: i + 1, Name: f.columns[i], Err: errNotNumeric})
+		}
+	}
+	return out
+}
+
+// rowScanner reads one CSV record at a time while counting lines, so errors can name the line a row starts on.
+// A record spans lines when a quoted field contains a newline.
+type rowScanner struct {
+	r     *bufio.Reader
+	comma rune
+	line  int // lines read so far
+}
+
+// next returns the fields of the next row and the line it starts on. Invalid CSV is returned as a
+// *csv.ParseError. Blank lines, and the EOF (0x1A) character OFAC's files end with, are skipped.
+func (s *rowScanner) next() ([]string, int, error) {
+	for {
+		start := s.line + 1
+
+		var buf strings.Builder
+		quotes := 0
+		for {
+			line, err := s.r.ReadString('\n')
+			if line != "" {
+				s.line++
+				buf.WriteString(line)
+				quotes += strings.Count(line, `"`)
+			}
+			if err == io.EOF {
+				if buf.Len() == 0 {
+					return nil, 0, io.EOF
+				}
+				break
+			}
+			if err != nil {
+				return nil, start, err
+			}
+			if quotes%2 == 0 {
+				break // the row doesn't end inside a quoted field
+			}
+		}
+
+		text := buf.String()
+		if strings.Trim(text, "\x1a \r\n") == "" {
+			continue
+		}
+		reader := csv.NewReader(strings.NewReader(text))
+		reader.Comma = s.comma
+		reader.FieldsPerRecord = -1
+		record, err := reader.Read()
+		if err != nil {
+			if perr, ok := err.(*csv.ParseError); ok {
+				perr.StartLine += start - 1 // count lines from the start of r, not the row
+				perr.Line += start - 1
+				return nil, perr.Line, perr
+			}
+			return nil, start, err
+		}
+		return record, start, nil
+	}
+}
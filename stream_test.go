@@ -0,0 +1,165 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/base"
+)
+
+func TestReadSDNs(t *testing.T) {
+	f, err := os.Open("test/testdata/sdn.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var sdns []*SDN
+	err = ReadSDNs(f, &ReadOptions{Strict: true}, func(sdn *SDN) error {
+		sdns = append(sdns, sdn)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sdns) != 7379 {
+		t.Errorf("got %d SDNs", len(sdns))
+	}
+	if sdn := sdns[0]; sdn.EntityID != "36" || sdn.SDNName != "AEROCARIBBEAN AIRLINES" || sdn.SDNType != "" || sdn.Program != "CUBA" {
+		t.Errorf("sdn=%#v", sdn)
+	}
+
+	// reading stops at the callback's error
+	f.Seek(0, 0)
+	stop, n := errors.New("stop"), 0
+	err = ReadSDNs(f, nil, func(sdn *SDN) error {
+		if n++; n == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || n != 3 {
+		t.Errorf("n=%d err=%v", n, err)
+	}
+}
+
+func TestReadStrict(t *testing.T) {
+	input := strings.Join([]string{
+		`36,"AEROCARIBBEAN AIRLINES",-0- ,"CUBA",-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- `,
+		`306,"BANCO NACIONAL DE CUBA",-0- ,"CUBA",-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,"a.k.a. 'BNC';`,
+		`Havana."`,
+		`abc,"BOUTIQUE LA MAISON",-0- ,"CUBA",-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- `,
+		`475,"CASA DE CUBA",-0- ,"CUBA"`,
+	}, "\n")
+
+	// the bad column is on line 4, after a row spanning two lines
+	err := ReadSDNs(strings.NewReader(input), &ReadOptions{Strict: true}, func(*SDN) error { return nil })
+	perr, ok := err.(*base.ParseError)
+	if !ok || perr.Line != 4 || perr.Record != "SDN" {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	var cerr *ColumnError
+	if !errors.As(err, &cerr) || cerr.Column != 1 || cerr.Name != "ent_num" || cerr.Err != errNotNumeric {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// lenient reads skip the short row, but keep the row with a bad column
+	var warnings base.ErrorList
+	var names []string
+	err = ReadSDNs(strings.NewReader(input), &ReadOptions{Warn: warnings.Add}, func(sdn *SDN) error {
+		names = append(names, sdn.SDNName)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 3 || names[2] != "BOUTIQUE LA MAISON" {
+		t.Errorf("names=%v", names)
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("warnings=%v", warnings)
+	}
+	if perr := warnings[1].(*base.ParseError); perr.Line != 5 || !strings.Contains(perr.Err.Error(), "wrong number of fields: expected 12, found 4") {
+		t.Errorf("unexpected warning: %v", perr)
+	}
+}
+
+func TestReadStrict__invalidCSV(t *testing.T) {
+	input := "12300,\"Z S.A.S.\"\n15599,\"MI FEL\" S. DE R.L.\n\x1a\n"
+
+	err := ReadSDNComments(strings.NewReader(input), &ReadOptions{Strict: true}, func(*SDNComments) error { return nil })
+	if perr, ok := err.(*base.ParseError); !ok || perr.Line != 2 || perr.Record != "SDNComments" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	// OFAC's EOF character isn't a row
+	err = ReadSDNComments(strings.NewReader("12300,\"Z S.A.S.\"\n\x1a\n"), &ReadOptions{Strict: true}, func(*SDNComments) error { return nil })
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReadDeniedPersons__header(t *testing.T) {
+	f, err := os.Open("test/testdata/dpl.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var dps []*DPL
+	err = ReadDeniedPersons(f, &ReadOptions{Strict: true}, func(dp *DPL) error {
+		dps = append(dps, dp)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dps) != 546 || dps[0].Name == "Name" {
+		t.Errorf("got %d denied persons, first=%#v", len(dps), dps[0])
+	}
+
+	// a header naming other columns is a problem
+	err = ReadDeniedPersons(strings.NewReader("Name\tCity\n"), &ReadOptions{Strict: true}, func(*DPL) error { return nil })
+	if perr, ok := err.(*base.ParseError); !ok || perr.Line != 1 || perr.Record != "DPL" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+}
+
+func TestReadConsolidatedScreeningList(t *testing.T) {
+	f, err := os.Open("test/testdata/csl.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var els []*EL
+	err = ReadConsolidatedScreeningList(f, &ReadOptions{Strict: true}, nil, func(el *EL) error {
+		els = append(els, el)
+		return nil
+	})
+	if err != nil || len(els) != 7 {
+		t.Errorf("got %d ELs: %v", len(els), err)
+	}
+}
+
+func TestReader__strict(t *testing.T) {
+	r := &Reader{FileName: "test/testdata/invalidFiles/sdn_comments.csv"}
+	if err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.SDNComments) != 3 || len(r.Errors()) != 1 {
+		t.Errorf("comments=%d errors=%v", len(r.SDNComments), r.Errors())
+	}
+
+	r = &Reader{FileName: "test/testdata/invalidFiles/sdn_comments.csv", Strict: true}
+	err := r.Read()
+	if perr, ok := err.(*base.ParseError); !ok || perr.Line != 4 {
+		t.Errorf("unexpected error: %#v", err)
+	}
+}